@@ -6,11 +6,13 @@ import (
 	"Taurus/internal/app"
 	"Taurus/internal/controller"
 	"Taurus/internal/hooks"
+	"Taurus/pkg/httpx"
 	"Taurus/pkg/logx"
 	"Taurus/pkg/middleware"
+	"Taurus/pkg/openapi"
+	"Taurus/pkg/ratelimit"
 	"Taurus/pkg/router"
 	"Taurus/pkg/telemetry"
-	"Taurus/pkg/util"
 	"net/http"
 	"time"
 )
@@ -18,7 +20,11 @@ import (
 func main() {
 
 	t := telemetry.GetTracer("http-server")
-	rateLimiter := util.NewCompositeRateLimiter(100, 1000, 1*time.Second)
+	// 每个 IP 每秒 100 次, 每分钟 1000 次
+	rateLimiter, _ := ratelimit.NewLimiter(nil,
+		ratelimit.Tier{Limit: 100, Window: time.Second},
+		ratelimit.Tier{Limit: 1000, Window: time.Minute},
+	)
 
 	// 测试trace_simple中间件
 	router.AddRouter(router.Router{
@@ -49,6 +55,11 @@ func main() {
 					hooks.HostMiddleware,
 					middleware.ValidationMiddleware(&controller.ValidateRequest{}), // 验证请求是否符合ValidateRequest结构体
 				},
+				Doc: &router.RouteDoc{
+					Summary: "测试参数验证",
+					Tags:    []string{"validate"},
+					Request: &controller.ValidateRequest{},
+				},
 			},
 		},
 	})
@@ -68,7 +79,7 @@ func main() {
 		Handler: http.HandlerFunc(internal.Core.MidCtrl.TestMid),
 		Middleware: []router.MiddlewareFunc{
 			middleware.TraceMiddleware(t),                                  // 追踪
-			middleware.RateLimitMiddleware(rateLimiter),                    // 限流
+			middleware.RateLimitMiddleware(rateLimiter, nil),               // 限流
 			middleware.ErrorHandlerMiddleware,                              // 错误处理
 			hooks.HostMiddleware,                                           // 主机限制
 			middleware.ApiKeyAuthMiddleware,                                // api key认证
@@ -78,27 +89,77 @@ func main() {
 		},
 	})
 
+	// 测试请求方法和路径参数, 其他请求方法返回405
+	router.AddRouter(router.Router{
+		Path:    "/users/{id}",
+		Methods: []string{http.MethodGet, http.MethodDelete},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(r.Method + " user " + router.Param(r, "id")))
+		}),
+		Doc: &router.RouteDoc{
+			Summary: "测试请求方法和路径参数",
+			Tags:    []string{"demo"},
+		},
+	})
+
 	// 测试获取consul注册的服务
 	router.AddRouter(router.Router{
 		Path:    "/consul/services",
 		Handler: http.HandlerFunc(internal.Core.ConsulCtrl.TestConsul),
 	})
 
-	// 设置健康检查
+	// 路由列表, 包含分组前缀、请求方法、中间件和注册状态
+	router.AddRouterGroup(router.RouteGroup{
+		Prefix: "/admin",
+		Middleware: []router.MiddlewareFunc{
+			middleware.ErrorHandlerMiddleware,
+			hooks.HostMiddleware,
+			middleware.ApiKeyAuthMiddleware,
+		},
+		Routes: []router.Router{
+			{
+				Path:    "/routes",
+				Method:  http.MethodGet,
+				Handler: http.HandlerFunc(router.RoutesHandler),
+			},
+		},
+	})
+
+	// OpenAPI 文档, 由路由和 RouteDoc 生成
 	router.AddRouter(router.Router{
-		Path: "/health",
+		Path:    "/openapi.json",
+		Method:  http.MethodGet,
+		Handler: openapi.Handler(openapi.Info{Title: config.Core.AppName, Version: config.Core.Version}),
+	})
+
+	// OpenAPI 文档页面
+	router.AddRouter(router.Router{
+		Path: "/docs",
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ok"))
+			http.Redirect(w, r, "/static/openapi/index.html", http.StatusFound)
 		}),
 	})
 
+	// 设置健康检查
+	health := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	router.AddRouter(router.Router{
+		Path:    "/health",
+		Handler: health,
+	})
+
 	// 设置静态文件地址
 	router.AddRouter(router.Router{
 		Path: "/static/",
 		// 浏览器访问 http://localhost:8080/static/css/style.css 首先会去掉/static/ 剩下css/style.css, 然后去相对于应用根目录 ./static去找css/style.css文件返回
-		Handler:    http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))),
-		Middleware: []router.MiddlewareFunc{},
+		// server.static_precompressed 开启时优先返回预压缩的 .br/.gz 文件, 其他文本文件按 Accept-Encoding 实时压缩
+		Handler: http.StripPrefix("/static/", httpx.FileServer(http.Dir("./static"), config.Core.Server.StaticPrecompressed)),
+		Middleware: []router.MiddlewareFunc{
+			middleware.CompressMiddleware(middleware.CompressConfig{}),
+		},
 	})
 
 	// 重定向到静态文件
@@ -119,5 +180,10 @@ func main() {
 		},
 	})
 
+	// 注册handler, 供配置文件 config/router 中的路由按名称引用
+	router.RegisterHandler("health", health)
+	router.RegisterHandler("consul.services", http.HandlerFunc(internal.Core.ConsulCtrl.TestConsul))
+	router.RegisterHandler("validate", http.HandlerFunc(internal.Core.ValidateCtrl.TestValidateMiddleware))
+
 	app.Default()
 }
@@ -19,6 +19,127 @@ type Config struct {
 	GRPCEnable      bool `json:"grpc_enable" yaml:"grpc_enable" toml:"grpc_enable"`                // 是否启用grpc
 	TracingEnable   bool `json:"tracing_enable" yaml:"tracing_enable" toml:"tracing_enable"`       // 是否启用tracing
 	TCPEnable       bool `json:"tcp_enable" yaml:"tcp_enable" toml:"tcp_enable"`                   // 是否启用tcp
+	RouterStrict    bool `json:"router_strict" yaml:"router_strict" toml:"router_strict"`          // 是否严格检查路由, 重复或冲突的路由会导致启动失败
+	ApiKeyEnable    bool `json:"api_key_enable" yaml:"api_key_enable" toml:"api_key_enable"`       // 是否启用 API key 存储和管理接口
+	JwtEnable       bool `json:"jwt_enable" yaml:"jwt_enable" toml:"jwt_enable"`                   // 是否启用 JWT 签发和校验
+	AuthzEnable     bool `json:"authz_enable" yaml:"authz_enable" toml:"authz_enable"`             // 是否启用授权策略
+	MeteringEnable  bool `json:"metering_enable" yaml:"metering_enable" toml:"metering_enable"`    // 是否启用使用量计量和配额
+
+	// HTTP 服务器, 时间为空时使用默认值, 0 表示不限制
+	Server struct {
+		ReadHeaderTimeout   string `json:"read_header_timeout" yaml:"read_header_timeout" toml:"read_header_timeout"`    // 读取请求头的超时, 默认 10s
+		ReadTimeout         string `json:"read_timeout" yaml:"read_timeout" toml:"read_timeout"`                         // 读取整个请求的超时, 包括请求体, 默认 0
+		WriteTimeout        string `json:"write_timeout" yaml:"write_timeout" toml:"write_timeout"`                      // 写响应的超时, 默认 0; 设置后 SSE/websocket 等长连接会被断开
+		IdleTimeout         string `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`                         // keep-alive 连接的空闲超时, 默认 1m
+		MaxHeaderBytes      int    `json:"max_header_bytes" yaml:"max_header_bytes" toml:"max_header_bytes"`             // 请求头的最大字节数, 默认 1MB
+		MaxMultipartMemory  int64  `json:"max_multipart_memory" yaml:"max_multipart_memory" toml:"max_multipart_memory"` // 解析 multipart/form-data 时保存在内存中的最大字节数, 默认 10MB
+		ErrorFormat         string `json:"error_format" yaml:"error_format" toml:"error_format"`                         // 错误响应格式, 可选值: legacy (httpx.Response), problem (application/problem+json), 默认 legacy
+		ProblemTypeBase     string `json:"problem_type_base" yaml:"problem_type_base" toml:"problem_type_base"`          // problem 的 type 前缀, type 为 <前缀><消息 key>, 为空时是 about:blank
+		StaticPrecompressed bool   `json:"static_precompressed" yaml:"static_precompressed" toml:"static_precompressed"` // 静态文件优先返回预压缩的 .br/.zst/.gz 文件, 默认 false
+	} `json:"server" yaml:"server" toml:"server"`
+
+	// 声明式路由, handler 和 middleware 按名称引用, 见 router.RegisterHandler 和 router.RegisterMiddleware
+	Router struct {
+		Routes []RouteConfig          `json:"routes" yaml:"routes" toml:"routes"` // 路由
+		Groups []RouteGroupConfig     `json:"groups" yaml:"groups" toml:"groups"` // 路由分组
+		Splits map[string]SplitConfig `json:"splits" yaml:"splits" toml:"splits"` // 流量拆分, 上游通过 split 引用名称
+		Cors   map[string]CorsConfig  `json:"cors" yaml:"cors" toml:"cors"`       // 跨域策略, cors 中间件通过 policy 参数引用名称
+	} `json:"router" yaml:"router" toml:"router"`
+
+	ApiKey struct {
+		Store         string `json:"store" yaml:"store" toml:"store"`                            // 存储, 可选值: memory, redis, db, 默认 memory
+		DB            string `json:"db" yaml:"db" toml:"db"`                                     // store 为 db 时使用的数据库名称
+		RedisHash     string `json:"redis_hash" yaml:"redis_hash" toml:"redis_hash"`             // store 为 redis 时使用的 hash, 默认 apikeys
+		Hash          string `json:"hash" yaml:"hash" toml:"hash"`                               // 密钥哈希算法, 可选值: sha256, bcrypt, 默认 sha256
+		CacheTTL      string `json:"cache_ttl" yaml:"cache_ttl" toml:"cache_ttl"`                // 校验结果缓存时间, 默认 10s, -1s 表示不缓存
+		TouchInterval string `json:"touch_interval" yaml:"touch_interval" toml:"touch_interval"` // 最后使用时间的最小更新间隔, 默认 1m
+		AdminPrefix   string `json:"admin_prefix" yaml:"admin_prefix" toml:"admin_prefix"`       // 管理接口前缀, 默认 /admin/apikeys
+		AdminScope    string `json:"admin_scope" yaml:"admin_scope" toml:"admin_scope"`          // 访问管理接口需要的 scope, 默认 apikey:admin
+	} `json:"api_key" yaml:"api_key" toml:"api_key"`
+
+	Jwt struct {
+		Algorithm        string   `json:"algorithm" yaml:"algorithm" toml:"algorithm"`                            // 签名算法, 可选值: RS256, ES256, EdDSA, 默认从 key_file 推断
+		KeyFile          string   `json:"key_file" yaml:"key_file" toml:"key_file"`                               // PEM 私钥文件, 签发 token 时必须配置
+		DevKey           bool     `json:"dev_key" yaml:"dev_key" toml:"dev_key"`                                  // 开发模式, key_file 为空时生成临时 key, 重启后 token 全部失效
+		KeyID            string   `json:"key_id" yaml:"key_id" toml:"key_id"`                                     // key id, 默认为公钥的 JWK thumbprint
+		PreviousKeyFiles []string `json:"previous_key_files" yaml:"previous_key_files" toml:"previous_key_files"` // 轮换前的私钥文件, 只用于校验和发布公钥
+		VerifyOnly       bool     `json:"verify_only" yaml:"verify_only" toml:"verify_only"`                      // 只校验 token, 不签发, 例如只使用远程 JWKS 的资源服务
+		Issuer           string   `json:"issuer" yaml:"issuer" toml:"issuer"`                                     // 签发方 iss
+		AcceptIssuers    []string `json:"accept_issuers" yaml:"accept_issuers" toml:"accept_issuers"`             // 额外接受的签发方, 例如远程 JWKS 的身份提供方
+		Audience         []string `json:"audience" yaml:"audience" toml:"audience"`                               // 受众 aud, 为空表示不校验
+		Leeway           string   `json:"leeway" yaml:"leeway" toml:"leeway"`                                     // 允许的时钟偏差, 默认 30s
+		AccessTTL        string   `json:"access_ttl" yaml:"access_ttl" toml:"access_ttl"`                         // access token 有效期, 默认 15m
+		RefreshTTL       string   `json:"refresh_ttl" yaml:"refresh_ttl" toml:"refresh_ttl"`                      // refresh token 有效期, 默认 168h
+		RemoteJWKS       []string `json:"remote_jwks" yaml:"remote_jwks" toml:"remote_jwks"`                      // 远程 JWKS 地址
+		RemoteJWKSTTL    string   `json:"remote_jwks_ttl" yaml:"remote_jwks_ttl" toml:"remote_jwks_ttl"`          // 远程 JWKS 缓存时间, 默认 10m
+		Revocation       string   `json:"revocation" yaml:"revocation" toml:"revocation"`                         // 吊销列表存储, 可选值: memory, redis, 默认 memory
+		JWKSPath         string   `json:"jwks_path" yaml:"jwks_path" toml:"jwks_path"`                            // JWKS 路径, 默认 /.well-known/jwks.json
+		RefreshPath      string   `json:"refresh_path" yaml:"refresh_path" toml:"refresh_path"`                   // 刷新 token 路径, 默认 /auth/refresh
+		RevokePath       string   `json:"revoke_path" yaml:"revoke_path" toml:"revoke_path"`                      // 吊销 token 路径, 默认 /auth/revoke
+	} `json:"jwt" yaml:"jwt" toml:"jwt"`
+
+	Authz struct {
+		Source         string                     `json:"source" yaml:"source" toml:"source"`                            // 策略来源, 可选值: config, db, both, 默认 config
+		DB             string                     `json:"db" yaml:"db" toml:"db"`                                        // source 包含 db 时使用的数据库名称, 表 authz_roles 和 authz_grants
+		ReloadInterval string                     `json:"reload_interval" yaml:"reload_interval" toml:"reload_interval"` // 数据库策略的重新加载间隔, 为空表示不重新加载
+		CacheTTL       string                     `json:"cache_ttl" yaml:"cache_ttl" toml:"cache_ttl"`                   // 决策缓存时间, 默认 1m, -1s 表示不缓存
+		CacheSize      int                        `json:"cache_size" yaml:"cache_size" toml:"cache_size"`                // 缓存的最大决策数, 默认 10000
+		RolesClaim     string                     `json:"roles_claim" yaml:"roles_claim" toml:"roles_claim"`             // JWT 中角色的 claim, 默认 roles
+		AuditLogger    string                     `json:"audit_logger" yaml:"audit_logger" toml:"audit_logger"`          // 审计日志使用的日志名称, 默认 default
+		AuditAllowed   bool                       `json:"audit_allowed" yaml:"audit_allowed" toml:"audit_allowed"`       // 是否记录允许的请求, 默认只记录拒绝的请求
+		Roles          map[string]AuthzRoleConfig `json:"roles" yaml:"roles" toml:"roles"`                               // 角色和权限
+		GRPC           map[string]string          `json:"grpc" yaml:"grpc" toml:"grpc"`                                  // gRPC 完整方法名到权限的映射, 支持 /pkg.Service/*
+	} `json:"authz" yaml:"authz" toml:"authz"`
+
+	RealIP struct {
+		TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies" toml:"trusted_proxies"` // 受信任的代理, 支持 CIDR、IP、loopback、private, 为空时只使用连接的对端地址
+		Headers        []string `json:"headers" yaml:"headers" toml:"headers"`                         // 从受信任代理读取的请求头, 按顺序使用第一个存在的, 默认 [Forwarded, X-Forwarded-For, X-Real-IP]
+	} `json:"real_ip" yaml:"real_ip" toml:"real_ip"`
+
+	IPFilter struct {
+		Lists map[string]IPFilterRuleConfig `json:"lists" yaml:"lists" toml:"lists"` // 命名的 IP 列表, default 由 HTTP 和 gRPC 的 host 中间件使用, 路由分组通过 ip_filter 中间件的 list 参数引用
+		GRPC  string                        `json:"grpc" yaml:"grpc" toml:"grpc"`    // gRPC 拦截器检查的列表, 为空时不检查
+		TCP   string                        `json:"tcp" yaml:"tcp" toml:"tcp"`       // TCP 服务器接受连接时检查的列表, 为空时不检查
+	} `json:"ip_filter" yaml:"ip_filter" toml:"ip_filter"`
+
+	RateLimit struct {
+		Store  string `json:"store" yaml:"store" toml:"store"`    // 限流配额存储, 可选值: local, redis, 默认 local; redis 不可用时临时退回 local
+		Prefix string `json:"prefix" yaml:"prefix" toml:"prefix"` // store 为 redis 时 key 的前缀, 默认 ratelimit:
+		GRPC   struct {
+			Keys  []string              `json:"keys" yaml:"keys" toml:"keys"`    // 限流 key 的组成: ip, api_key, user, method, metadata:<name>, 默认 [method, ip]
+			Tiers []RateLimitTierConfig `json:"tiers" yaml:"tiers" toml:"tiers"` // 限流层级, 为空时不限流
+		} `json:"grpc" yaml:"grpc" toml:"grpc"`
+		TCP struct {
+			Tiers []RateLimitTierConfig `json:"tiers" yaml:"tiers" toml:"tiers"` // 按客户端 IP 共享的消息限流层级, 为空时使用 tcp.rate_limiter 的单连接限流
+		} `json:"tcp" yaml:"tcp" toml:"tcp"`
+	} `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+
+	Idempotency struct {
+		Store   string `json:"store" yaml:"store" toml:"store"`          // 记录存储, 可选值: memory, redis, 默认 memory; 多个副本时使用 redis
+		Prefix  string `json:"prefix" yaml:"prefix" toml:"prefix"`       // store 为 redis 时 key 的前缀, 默认 idempotency:
+		TTL     string `json:"ttl" yaml:"ttl" toml:"ttl"`                // 响应的保存时间, 默认 24h
+		LockTTL string `json:"lock_ttl" yaml:"lock_ttl" toml:"lock_ttl"` // 处理中的请求保留 key 的时间, 超过后允许重试, 默认 1m
+	} `json:"idempotency" yaml:"idempotency" toml:"idempotency"`
+
+	HTTPCache struct {
+		Store    string `json:"store" yaml:"store" toml:"store"`          // 响应存储, 可选值: memory, redis, 默认 memory; 多个副本共享缓存时使用 redis
+		Prefix   string `json:"prefix" yaml:"prefix" toml:"prefix"`       // store 为 redis 时 key 的前缀, 默认 httpcache
+		Capacity int    `json:"capacity" yaml:"capacity" toml:"capacity"` // store 为 memory 时缓存的最大响应数, 默认 1000
+	} `json:"http_cache" yaml:"http_cache" toml:"http_cache"`
+
+	Metering struct {
+		Counter       string                        `json:"counter" yaml:"counter" toml:"counter"`                      // 计数存储, 可选值: memory, redis, 默认 memory
+		Prefix        string                        `json:"prefix" yaml:"prefix" toml:"prefix"`                         // counter 为 redis 时 key 的 hash tag, 默认 metering
+		DB            string                        `json:"db" yaml:"db" toml:"db"`                                     // 使用量写入的数据库名称
+		FlushInterval string                        `json:"flush_interval" yaml:"flush_interval" toml:"flush_interval"` // 写入数据库的间隔, 默认 1m
+		DefaultPlan   string                        `json:"default_plan" yaml:"default_plan" toml:"default_plan"`       // 未指定套餐的调用方使用的套餐, 为空时只计量不限制
+		PlanClaim     string                        `json:"plan_claim" yaml:"plan_claim" toml:"plan_claim"`             // JWT 中套餐的 claim, 默认 plan
+		TenantClaim   string                        `json:"tenant_claim" yaml:"tenant_claim" toml:"tenant_claim"`       // JWT 中租户的 claim, 默认 tenant
+		Plans         map[string]MeteringPlanConfig `json:"plans" yaml:"plans" toml:"plans"`                            // 套餐
+		Consumers     map[string]string             `json:"consumers" yaml:"consumers" toml:"consumers"`                // 调用方到套餐的映射, 例如 {"key:8f3a": basic}
+		AdminPrefix   string                        `json:"admin_prefix" yaml:"admin_prefix" toml:"admin_prefix"`       // 管理接口前缀, 默认 /admin/usage
+		AdminScope    string                        `json:"admin_scope" yaml:"admin_scope" toml:"admin_scope"`          // 访问管理接口需要的 scope, 默认 usage:admin
+	} `json:"metering" yaml:"metering" toml:"metering"`
 
 	Tcp struct {
 		Address        string `json:"address" yaml:"address" toml:"address"`                            // tcp地址
@@ -145,6 +266,130 @@ type Config struct {
 	} `json:"telemetry" yaml:"telemetry" toml:"telemetry"`
 }
 
+type MiddlewareConfig struct {
+	Name   string                 `json:"name" yaml:"name" toml:"name"`       // 中间件名称
+	Params map[string]interface{} `json:"params" yaml:"params" toml:"params"` // 中间件参数
+}
+
+type RouteConfig struct {
+	Path       string             `json:"path" yaml:"path" toml:"path"`                   // 路径, 支持 {id} 路径参数
+	Methods    []string           `json:"methods" yaml:"methods" toml:"methods"`          // 请求方法, 为空时允许所有方法
+	Handler    string             `json:"handler" yaml:"handler" toml:"handler"`          // handler名称
+	Upstream   *UpstreamConfig    `json:"upstream" yaml:"upstream" toml:"upstream"`       // 上游代理, 设置后忽略handler
+	Middleware []MiddlewareConfig `json:"middleware" yaml:"middleware" toml:"middleware"` // 中间件, 按顺序执行
+}
+
+type UpstreamConfig struct {
+	Targets               []string          `json:"targets" yaml:"targets" toml:"targets"`                                                 // 静态地址, 例如 http://127.0.0.1:8081, h2c:// 表示不加密的HTTP/2
+	Service               string            `json:"service" yaml:"service" toml:"service"`                                                 // consul服务名称, targets为空时使用
+	Tags                  []string          `json:"tags" yaml:"tags" toml:"tags"`                                                          // consul服务标签, 只选择带有所有标签的实例
+	Split                 string            `json:"split" yaml:"split" toml:"split"`                                                       // 流量拆分名称, 设置后忽略targets和service
+	Scheme                string            `json:"scheme" yaml:"scheme" toml:"scheme"`                                                    // consul服务的协议, 默认http
+	StripPrefix           string            `json:"strip_prefix" yaml:"strip_prefix" toml:"strip_prefix"`                                  // 转发前去掉的路径前缀
+	AddPrefix             string            `json:"add_prefix" yaml:"add_prefix" toml:"add_prefix"`                                        // 转发前添加的路径前缀
+	PreserveHost          bool              `json:"preserve_host" yaml:"preserve_host" toml:"preserve_host"`                               // 是否保留客户端的Host
+	SetHeaders            map[string]string `json:"set_headers" yaml:"set_headers" toml:"set_headers"`                                     // 设置的请求头
+	RemoveHeaders         []string          `json:"remove_headers" yaml:"remove_headers" toml:"remove_headers"`                            // 删除的请求头
+	SetResponseHeaders    map[string]string `json:"set_response_headers" yaml:"set_response_headers" toml:"set_response_headers"`          // 设置的响应头
+	RemoveResponseHeaders []string          `json:"remove_response_headers" yaml:"remove_response_headers" toml:"remove_response_headers"` // 删除的响应头
+	DialTimeout           string            `json:"dial_timeout" yaml:"dial_timeout" toml:"dial_timeout"`                                  // 连接超时, 例如 5s
+	ResponseHeaderTimeout string            `json:"response_header_timeout" yaml:"response_header_timeout" toml:"response_header_timeout"` // 等待响应头超时
+	Timeout               string            `json:"timeout" yaml:"timeout" toml:"timeout"`                                                 // 整个请求的超时, 不包括websocket
+	Retries               int               `json:"retries" yaml:"retries" toml:"retries"`                                                 // 幂等请求的重试次数
+	RetryBackoff          string            `json:"retry_backoff" yaml:"retry_backoff" toml:"retry_backoff"`                               // 重试间隔
+	Breaker               *BreakerConfig    `json:"breaker" yaml:"breaker" toml:"breaker"`                                                 // 上游熔断, 持续失败时直接返回503
+	Outlier               *BreakerConfig    `json:"outlier" yaml:"outlier" toml:"outlier"`                                                 // 实例剔除, 持续失败的实例暂时不再转发
+	Mirror                *struct {
+		Targets       []string `json:"targets" yaml:"targets" toml:"targets"`                      // 影子上游的静态地址
+		Service       string   `json:"service" yaml:"service" toml:"service"`                      // 影子上游的consul服务名称
+		Tags          []string `json:"tags" yaml:"tags" toml:"tags"`                               // 影子上游的consul服务标签
+		Percent       float64  `json:"percent" yaml:"percent" toml:"percent"`                      // 镜像的请求百分比 0 - 100
+		MaxBodySize   int64    `json:"max_body_size" yaml:"max_body_size" toml:"max_body_size"`    // 超过该大小的请求体不镜像, 默认1MB
+		Timeout       string   `json:"timeout" yaml:"timeout" toml:"timeout"`                      // 影子请求超时, 默认5s
+		MaxConcurrent int      `json:"max_concurrent" yaml:"max_concurrent" toml:"max_concurrent"` // 同时进行的影子请求数, 超过则丢弃, 默认100
+		Logger        string   `json:"logger" yaml:"logger" toml:"logger"`                         // 记录差异的日志名称, 默认default
+	} `json:"mirror" yaml:"mirror" toml:"mirror"` // 流量镜像, 不影响主请求
+}
+
+// CorsConfig 跨域策略
+type CorsConfig struct {
+	AllowedOrigins      []string `json:"allowed_origins" yaml:"allowed_origins" toml:"allowed_origins"`                   // 允许的来源, 支持 *, https://*.example.com 和 regex:正则
+	AllowedMethods      []string `json:"allowed_methods" yaml:"allowed_methods" toml:"allowed_methods"`                   // 允许的方法
+	AllowedHeaders      []string `json:"allowed_headers" yaml:"allowed_headers" toml:"allowed_headers"`                   // 允许的请求头, * 表示所有
+	ExposedHeaders      []string `json:"exposed_headers" yaml:"exposed_headers" toml:"exposed_headers"`                   // 浏览器可以读取的响应头
+	AllowCredentials    bool     `json:"allow_credentials" yaml:"allow_credentials" toml:"allow_credentials"`             // 是否允许携带凭证
+	MaxAge              string   `json:"max_age" yaml:"max_age" toml:"max_age"`                                           // 预检结果缓存时间, 例如 10m
+	AllowPrivateNetwork bool     `json:"allow_private_network" yaml:"allow_private_network" toml:"allow_private_network"` // 是否允许访问内网地址
+}
+
+// AuthzRoleConfig 角色的权限
+type AuthzRoleConfig struct {
+	Inherits    []string `json:"inherits" yaml:"inherits" toml:"inherits"`          // 继承的角色
+	Permissions []string `json:"permissions" yaml:"permissions" toml:"permissions"` // 无条件授予的权限, 支持 * 和 orders:*
+	Grants      []struct {
+		Permission string            `json:"permission" yaml:"permission" toml:"permission"` // 权限
+		When       map[string]string `json:"when" yaml:"when" toml:"when"`                   // 条件, 例如 {resource.owner: subject.id}
+	} `json:"grants" yaml:"grants" toml:"grants"` // 带条件的权限
+}
+
+// IPFilterRuleConfig IP 列表, 支持 IP 和 CIDR(包括 IPv6), 拒绝优先, allow 为空时允许所有未被拒绝的地址
+type IPFilterRuleConfig struct {
+	Allow []string `json:"allow" yaml:"allow" toml:"allow"` // 允许的 IP 或 CIDR
+	Deny  []string `json:"deny" yaml:"deny" toml:"deny"`    // 拒绝的 IP 或 CIDR
+}
+
+// RateLimitTierConfig 限流层级, 每个 window 允许 limit 次请求
+type RateLimitTierConfig struct {
+	Name   string `json:"name" yaml:"name" toml:"name"`       // 层级名称, 默认 <limit>/<window>
+	Limit  int    `json:"limit" yaml:"limit" toml:"limit"`    // 每个窗口允许的请求数
+	Window string `json:"window" yaml:"window" toml:"window"` // 窗口大小, 例如 1s, 1m, 1h
+	Burst  int    `json:"burst" yaml:"burst" toml:"burst"`    // 突发请求数, 默认等于 limit
+}
+
+// MeteringPlanConfig 套餐的配额, 0 表示不限制
+type MeteringPlanConfig struct {
+	Daily   int64 `json:"daily" yaml:"daily" toml:"daily"`       // 每日请求数
+	Monthly int64 `json:"monthly" yaml:"monthly" toml:"monthly"` // 每月请求数
+}
+
+// BreakerConfig 熔断配置, 零值使用默认值
+type BreakerConfig struct {
+	Window              string  `json:"window" yaml:"window" toml:"window"`                                           // 统计窗口, 默认10s
+	MinCalls            int     `json:"min_calls" yaml:"min_calls" toml:"min_calls"`                                  // 窗口内达到该调用数才计算失败率, 默认20
+	FailureRate         float64 `json:"failure_rate" yaml:"failure_rate" toml:"failure_rate"`                         // 熔断的失败率 0 - 1, 默认0.5
+	ConsecutiveFailures int     `json:"consecutive_failures" yaml:"consecutive_failures" toml:"consecutive_failures"` // 熔断的连续失败次数, 默认5, -1 表示不按连续失败熔断
+	SlowCall            string  `json:"slow_call" yaml:"slow_call" toml:"slow_call"`                                  // 慢调用阈值, 为空表示不统计慢调用
+	SlowCallRate        float64 `json:"slow_call_rate" yaml:"slow_call_rate" toml:"slow_call_rate"`                   // 熔断的慢调用率 0 - 1, 默认0.8
+	OpenTimeout         string  `json:"open_timeout" yaml:"open_timeout" toml:"open_timeout"`                         // 熔断持续时间, 之后进入半开, 默认30s
+	HalfOpenCalls       int     `json:"half_open_calls" yaml:"half_open_calls" toml:"half_open_calls"`                // 半开时的试探调用数, 全部成功后恢复, 默认3
+}
+
+type SplitConfig struct {
+	Pools []struct {
+		Name    string   `json:"name" yaml:"name" toml:"name"`          // 池名称
+		Weight  int      `json:"weight" yaml:"weight" toml:"weight"`    // 权重, 0 表示只能通过规则选中
+		Targets []string `json:"targets" yaml:"targets" toml:"targets"` // 静态地址
+		Service string   `json:"service" yaml:"service" toml:"service"` // consul服务名称
+		Scheme  string   `json:"scheme" yaml:"scheme" toml:"scheme"`    // consul服务的协议, 默认http
+		Tags    []string `json:"tags" yaml:"tags" toml:"tags"`          // consul服务标签, 例如 [v2]
+	} `json:"pools" yaml:"pools" toml:"pools"`
+	Rules []struct {
+		Pool   string            `json:"pool" yaml:"pool" toml:"pool"`       // 匹配后选择的池
+		Header map[string]string `json:"header" yaml:"header" toml:"header"` // 请求头, 值为 * 表示存在即可
+		Cookie map[string]string `json:"cookie" yaml:"cookie" toml:"cookie"` // cookie
+		Query  map[string]string `json:"query" yaml:"query" toml:"query"`    // 查询参数
+		Claim  map[string]string `json:"claim" yaml:"claim" toml:"claim"`    // JWT claims
+	} `json:"rules" yaml:"rules" toml:"rules"` // 按顺序匹配, 第一个匹配的规则生效
+	Sticky string `json:"sticky" yaml:"sticky" toml:"sticky"` // 按权重分配时的粘性键, 例如 header:X-User-ID, cookie:uid, claim:uid
+}
+
+type RouteGroupConfig struct {
+	Prefix     string             `json:"prefix" yaml:"prefix" toml:"prefix"`             // 路由前缀
+	Middleware []MiddlewareConfig `json:"middleware" yaml:"middleware" toml:"middleware"` // 中间件, 在路由中间件之前执行
+	Routes     []RouteConfig      `json:"routes" yaml:"routes" toml:"routes"`             // 路由
+	Groups     []RouteGroupConfig `json:"groups" yaml:"groups" toml:"groups"`             // 子分组
+}
+
 type ConsulServer struct {
 	Address   string `json:"address" yaml:"address" toml:"address"` // consul服务端地址
 	Port      int    `json:"port" yaml:"port" toml:"port"`          // consul服务端端口
@@ -21,7 +21,9 @@ package app
 import (
 	"Taurus/config"
 	"Taurus/pkg/mcp"
+	"Taurus/pkg/middleware"
 	"Taurus/pkg/router"
+	"Taurus/pkg/util"
 	"context"
 	"errors"
 	"flag"
@@ -30,6 +32,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -51,22 +54,68 @@ var (
 )
 
 // Default initializes and starts the HTTP server with default settings
+// If the command is routes, it prints all routes instead of starting the server
 func Default() {
+	if flag.Arg(0) == "routes" {
+		PrintRoutes()
+		for _, cleanup := range Cleanup {
+			if cleanup != nil {
+				cleanup()
+			}
+		}
+		return
+	}
 	Start(config.Core.AppHost, config.Core.AppPort)
 }
 
+// PrintRoutes prints all routes of router.DefaultManager as a table
+func PrintRoutes() {
+	lines := make([][]interface{}, 0)
+	for _, info := range router.DefaultManager.Routes() {
+		lines = append(lines, []interface{}{info.Method, info.Path, info.Prefix, strings.Join(info.Middleware, " -> "), info.Handler, info.Status})
+	}
+	util.RenderTable([]string{"Method", "Path", "Prefix", "Middleware", "Handler", "Status"}, lines)
+}
+
+// newServer creates the http server with the timeouts and limits of config.Core.Server
+func newServer(addr string, handler http.Handler) *http.Server {
+	conf := config.Core.Server
+	duration := func(name, value string, def time.Duration) time.Duration {
+		if value == "" {
+			return def
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("%sInvalid server %s: %v %s\n", Red, name, err, Reset)
+		}
+		return d
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: duration("read_header_timeout", conf.ReadHeaderTimeout, 10*time.Second),
+		ReadTimeout:       duration("read_timeout", conf.ReadTimeout, 0),
+		WriteTimeout:      duration("write_timeout", conf.WriteTimeout, 0),
+		IdleTimeout:       duration("idle_timeout", conf.IdleTimeout, time.Minute),
+		MaxHeaderBytes:    conf.MaxHeaderBytes, // 0 使用 http.DefaultMaxHeaderBytes
+	}
+}
+
 // Start initializes and starts the HTTP server with graceful shutdown
 func Start(host string, port int) {
 	// Load routes
 	r := router.LoadRoutes()
 
-	addr := fmt.Sprintf("%s:%d", host, port)
-	srv := &http.Server{
-		Addr:        addr,
-		Handler:     r,
-		IdleTimeout: 1 * time.Minute,
+	// In strict mode, duplicate or conflicting routes are startup errors
+	if config.Core.RouterStrict {
+		if err := router.DefaultManager.Check(); err != nil {
+			log.Fatalf("%sRoutes check failed: %v %s\n", Red, err, Reset)
+		}
 	}
 
+	addr := fmt.Sprintf("%s:%d", host, port)
+	srv := newServer(addr, middleware.RealIPMiddleware(r)) // 所有中间件共享解析后的客户端 IP
+
 	// use errChan to receive http server startup error
 	errChan := make(chan error, 1)
 
@@ -170,10 +219,12 @@ func init() {
 	// custom usage
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "\n%s\n", Cyan+"==================== Usage ===================="+Reset)
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage of %s: [options] [command]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s-e, --env <file>%s      Specify the environment file (default \".env.local\")\n", Green, Reset)
 		fmt.Fprintf(os.Stderr, "  %s-c, --config <path>%s   Specify the configuration file or directory (default \"config\")\n", Green, Reset)
 		fmt.Fprintf(os.Stderr, "  %s-h, --help%s            Show this help message\n", Green, Reset)
+		fmt.Fprintf(os.Stderr, "Commands:\n")
+		fmt.Fprintf(os.Stderr, "  %sroutes%s                Print all registered routes and exit\n", Green, Reset)
 		fmt.Fprintf(os.Stderr, "%s\n", Cyan+"==============================================="+Reset)
 	}
 
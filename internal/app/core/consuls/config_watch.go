@@ -2,7 +2,12 @@ package consuls
 
 import (
 	"Taurus/pkg/consul"
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/router"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 )
 
 // 实现configwatcher接口
@@ -12,7 +17,76 @@ type DefaultConfigWatcher struct {
 // 处理配置变更
 func (w *DefaultConfigWatcher) OnChange(c *consul.ConsulClient, serviceName string, key string, value []byte) error {
 	log.Printf("配置变更: %s, %s", key, string(value))
+
+	// 路由开关, key: services/{serviceName}/config/routes, value: {"disabled": ["GET /users/{id}", "/demo"]}
+	if strings.HasSuffix(key, "/config/routes") {
+		return onRoutesChange(value)
+	}
+
+	// 流量拆分, key: services/{serviceName}/config/traffic, value: {"user-canary": {"pools": [...], "rules": [...]}}
+	if strings.HasSuffix(key, "/config/traffic") {
+		return onTrafficChange(value)
+	}
+
+	// IP 列表, key: services/{serviceName}/config/ip_filter, value: {"default": {"allow": ["10.0.0.0/8"], "deny": ["10.0.0.7"]}}
+	if strings.HasSuffix(key, "/config/ip_filter") {
+		return onIPFilterChange(value)
+	}
+
 	// 更新配置
 	// TODO 解析，修改当前内存的配置即可
 	return nil
 }
+
+// routesConfig 路由开关配置
+type routesConfig struct {
+	Disabled []string `json:"disabled"` // 禁用的路由, 格式: "METHOD /path" 或者 "/path"
+}
+
+// onRoutesChange 更新禁用的路由, 路由表原子替换, 不需要重启服务
+func onRoutesChange(value []byte) error {
+	var cfg routesConfig
+	if len(value) > 0 {
+		if err := json.Unmarshal(value, &cfg); err != nil {
+			return err
+		}
+	}
+	router.DefaultManager.SetDisabled(cfg.Disabled...)
+	log.Printf("禁用的路由已更新: %v", cfg.Disabled)
+	return nil
+}
+
+// onTrafficChange 更新流量拆分规则, 未出现在配置中的拆分保持不变
+func onTrafficChange(value []byte) error {
+	var cfg map[string]router.SplitConfig
+	if err := json.Unmarshal(value, &cfg); err != nil {
+		return err
+	}
+	for name, split := range cfg {
+		if err := router.SetTrafficSplit(name, split); err != nil {
+			return err
+		}
+		log.Printf("流量拆分已更新: %s", name)
+	}
+	return nil
+}
+
+// onIPFilterChange 替换 IP 列表, 未出现在配置中的列表保持不变; 任何一个列表无效时都不更新
+func onIPFilterChange(value []byte) error {
+	var cfg map[string]ipfilter.Rule
+	if err := json.Unmarshal(value, &cfg); err != nil {
+		return err
+	}
+	for name, rule := range cfg {
+		if _, err := ipfilter.New(rule); err != nil {
+			return fmt.Errorf("ip filter list %s: %w", name, err)
+		}
+	}
+	for name, rule := range cfg {
+		if err := ipfilter.Set(name, rule); err != nil {
+			return err
+		}
+		log.Printf("IP 列表已更新: %s", name)
+	}
+	return nil
+}
@@ -23,13 +23,25 @@ import (
 	"Taurus/internal"
 	"Taurus/internal/app/core/consuls"
 	http_hooks "Taurus/internal/hooks"
+	"Taurus/pkg/apikey"
+	"Taurus/pkg/authz"
+	"Taurus/pkg/breaker"
 	"Taurus/pkg/consul"
 	"Taurus/pkg/cron"
 	"Taurus/pkg/db"
 	"Taurus/pkg/grpc/server"
+	"Taurus/pkg/grpc/server/interceptor"
+	"Taurus/pkg/httpcache"
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/idempotency"
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/jwtx"
 	"Taurus/pkg/logx"
 	"Taurus/pkg/mcp"
+	"Taurus/pkg/metering"
 	"Taurus/pkg/middleware"
+	"Taurus/pkg/ratelimit"
+	"Taurus/pkg/realip"
 	"Taurus/pkg/redisx"
 	"Taurus/pkg/router"
 	"Taurus/pkg/tcp"
@@ -37,6 +49,7 @@ import (
 	"Taurus/pkg/telemetry"
 	"Taurus/pkg/templates"
 	"Taurus/pkg/wsocket"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -46,6 +59,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hashicorp/consul/api"
 	"google.golang.org/grpc/keepalive"
+	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	// 引入crons包下的所有的定时任务, 需要用到init初始化
@@ -393,6 +407,539 @@ func InitializeTelemetry() {
 	}
 }
 
+// InitializeRouter loads the declarative routes from the configuration, names are resolved when the routes are loaded
+func InitializeRouter() {
+	for name, split := range config.Core.Router.Splits {
+		if err := router.SetTrafficSplit(name, buildSplitConfig(split)); err != nil {
+			log.Fatalf("Failed to initialize traffic split: %v", err)
+		}
+	}
+	for name, cors := range config.Core.Router.Cors {
+		var maxAge time.Duration
+		if cors.MaxAge != "" {
+			var err error
+			if maxAge, err = time.ParseDuration(cors.MaxAge); err != nil {
+				log.Fatalf("Invalid max_age of cors policy %s: %v", name, err)
+			}
+		}
+		err := middleware.SetCorsPolicy(name, middleware.CorsPolicy{
+			AllowedOrigins:      cors.AllowedOrigins,
+			AllowedMethods:      cors.AllowedMethods,
+			AllowedHeaders:      cors.AllowedHeaders,
+			ExposedHeaders:      cors.ExposedHeaders,
+			AllowCredentials:    cors.AllowCredentials,
+			MaxAge:              maxAge,
+			AllowPrivateNetwork: cors.AllowPrivateNetwork,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize cors policy: %v", err)
+		}
+	}
+	if len(config.Core.Router.Routes) == 0 && len(config.Core.Router.Groups) == 0 {
+		return
+	}
+	routes, groups := buildRouteConfig(config.Core.Router.Routes, config.Core.Router.Groups)
+	router.LoadConfig(routes, groups)
+	log.Println("\033[1;32m🔗 -> Router configuration loaded successfully\033[0m")
+}
+
+// InitializeHTTP initialize the request and error settings of httpx shared by all handlers
+func InitializeHTTP() {
+	conf := config.Core.Server
+	if conf.MaxMultipartMemory > 0 {
+		httpx.MaxMultipartMemory = conf.MaxMultipartMemory
+	}
+	switch format := httpx.ErrorFormat(conf.ErrorFormat); format {
+	case "":
+	case httpx.ErrorFormatLegacy, httpx.ErrorFormatProblem:
+		httpx.DefaultErrorFormat = format
+	default:
+		log.Fatalf("Unsupported server error_format: %s", conf.ErrorFormat)
+	}
+	httpx.ProblemTypeBase = conf.ProblemTypeBase
+	log.Println("\033[1;32m🔗 -> Http initialized successfully\033[0m")
+}
+
+// InitializeRealIP initialize the trusted proxies used to resolve the client ip
+func InitializeRealIP() {
+	conf := config.Core.RealIP
+	resolver, err := realip.NewResolver(conf.TrustedProxies, conf.Headers...)
+	if err != nil {
+		log.Fatalf("Failed to initialize real ip: %v", err)
+	}
+	realip.Default = resolver
+	log.Println("\033[1;32m🔗 -> Real ip initialized successfully\033[0m")
+}
+
+// InitializeIPFilter initialize the named ip allow/deny lists, the lists can be replaced at runtime through consul
+func InitializeIPFilter() {
+	conf := config.Core.IPFilter
+	for name, rule := range conf.Lists {
+		if err := ipfilter.Set(name, ipfilter.Rule{Allow: rule.Allow, Deny: rule.Deny}); err != nil {
+			log.Fatalf("Failed to initialize ip filter: %v", err)
+		}
+	}
+	if _, ok := conf.Lists[ipfilter.DefaultList]; !ok {
+		log.Printf("%s🔗 -> Ip filter list %s is not configured, the host middleware denies all requests %s\n", Yellow, ipfilter.DefaultList, Reset)
+	}
+	if conf.GRPC != "" {
+		server.RegisterInterceptor(interceptor.IPFilterServerInterceptor(conf.GRPC))
+		server.RegisterStreamInterceptor(interceptor.IPFilterStreamServerInterceptor(conf.GRPC))
+	}
+	log.Println("\033[1;32m🔗 -> Ip filter initialized successfully\033[0m")
+}
+
+// InitializeApiKey initialize api key store and admin api
+func InitializeApiKey() {
+	if !config.Core.ApiKeyEnable {
+		return
+	}
+	conf := config.Core.ApiKey
+
+	var store apikey.Store
+	switch conf.Store {
+	case "", "memory":
+		store = apikey.NewMemoryStore()
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("Api key store redis requires redis_enable")
+		}
+		store = apikey.NewRedisStore(redisx.Redis, conf.RedisHash)
+	case "db":
+		gdb, ok := db.DbList()[conf.DB]
+		if !ok {
+			log.Fatalf("Api key store database %s not found", conf.DB)
+		}
+		var err error
+		if store, err = apikey.NewDBStore(gdb); err != nil {
+			log.Fatalf("Failed to initialize api key store: %v", err)
+		}
+	default:
+		log.Fatalf("Unsupported api key store: %s", conf.Store)
+	}
+
+	cacheTTL, err := parseOptionalDuration(conf.CacheTTL)
+	if err != nil {
+		log.Fatalf("Invalid cache_ttl of api key: %v", err)
+	}
+	touchInterval, err := parseOptionalDuration(conf.TouchInterval)
+	if err != nil {
+		log.Fatalf("Invalid touch_interval of api key: %v", err)
+	}
+	manager := apikey.NewManager(store, apikey.Config{Hash: conf.Hash, CacheTTL: cacheTTL, TouchInterval: touchInterval})
+	apikey.Default = manager
+
+	prefix := conf.AdminPrefix
+	if prefix == "" {
+		prefix = "/admin/apikeys"
+	}
+	scope := conf.AdminScope
+	if scope == "" {
+		scope = "apikey:admin"
+	}
+	router.AddRouterGroup(router.RouteGroup{
+		Prefix:     prefix,
+		Middleware: []router.MiddlewareFunc{middleware.ApiKeyScopeMiddleware(scope)},
+		Routes: []router.Router{
+			{Path: "", Method: http.MethodGet, Handler: http.HandlerFunc(manager.HandleList)},
+			{Path: "", Method: http.MethodPost, Handler: http.HandlerFunc(manager.HandleIssue)},
+			{Path: "/{id}/rotate", Method: http.MethodPost, Handler: http.HandlerFunc(manager.HandleRotate)},
+			{Path: "/{id}", Method: http.MethodDelete, Handler: http.HandlerFunc(manager.HandleRevoke)},
+		},
+	})
+	log.Println("\033[1;32m🔗 -> Api key initialized successfully\033[0m")
+}
+
+// InitializeJwt initialize jwt service, jwks, refresh and revoke api
+func InitializeJwt() {
+	if !config.Core.JwtEnable {
+		return
+	}
+	conf := config.Core.Jwt
+
+	var signer *jwtx.SigningKey
+	var err error
+	switch {
+	case conf.VerifyOnly:
+	case conf.KeyFile != "":
+		if signer, err = jwtx.LoadSigningKey(conf.KeyFile, conf.Algorithm, conf.KeyID); err != nil {
+			log.Fatalf("Failed to load jwt signing key: %v", err)
+		}
+	case conf.DevKey:
+		algorithm := conf.Algorithm
+		if algorithm == "" {
+			algorithm = jwtx.EdDSA
+		}
+		if signer, err = jwtx.GenerateSigningKey(algorithm); err != nil {
+			log.Fatalf("Failed to generate jwt signing key: %v", err)
+		}
+		log.Printf("Warning: jwt dev_key is enabled, a temporary %s key %s is generated\n", algorithm, signer.ID)
+	default:
+		log.Fatalf("Jwt key_file is required to issue tokens, set verify_only for a verify only service or dev_key for development")
+	}
+
+	var trusted []jwtx.JWK
+	for _, file := range conf.PreviousKeyFiles {
+		previous, err := jwtx.LoadSigningKey(file, "", "")
+		if err != nil {
+			log.Fatalf("Failed to load previous jwt key: %v", err)
+		}
+		jwk, err := previous.JWK()
+		if err != nil {
+			log.Fatalf("Failed to load previous jwt key: %v", err)
+		}
+		trusted = append(trusted, jwk)
+	}
+
+	remoteTTL, err := parseOptionalDuration(conf.RemoteJWKSTTL)
+	if err != nil {
+		log.Fatalf("Invalid remote_jwks_ttl of jwt: %v", err)
+	}
+	var remotes []*jwtx.RemoteJWKS
+	for _, url := range conf.RemoteJWKS {
+		remotes = append(remotes, jwtx.NewRemoteJWKS(url, remoteTTL))
+	}
+
+	var revocations jwtx.RevocationStore
+	switch conf.Revocation {
+	case "", "memory":
+		revocations = jwtx.NewMemoryRevocations()
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("Jwt revocation redis requires redis_enable")
+		}
+		revocations = jwtx.NewRedisRevocations(redisx.Redis, "")
+	default:
+		log.Fatalf("Unsupported jwt revocation: %s", conf.Revocation)
+	}
+
+	leeway, err := parseOptionalDuration(conf.Leeway)
+	if err != nil {
+		log.Fatalf("Invalid leeway of jwt: %v", err)
+	}
+	accessTTL, err := parseOptionalDuration(conf.AccessTTL)
+	if err != nil {
+		log.Fatalf("Invalid access_ttl of jwt: %v", err)
+	}
+	refreshTTL, err := parseOptionalDuration(conf.RefreshTTL)
+	if err != nil {
+		log.Fatalf("Invalid refresh_ttl of jwt: %v", err)
+	}
+
+	service, err := jwtx.NewService(signer, jwtx.Config{
+		Issuer:        conf.Issuer,
+		AcceptIssuers: conf.AcceptIssuers,
+		Audience:      conf.Audience,
+		Leeway:        leeway,
+		AccessTTL:     accessTTL,
+		RefreshTTL:    refreshTTL,
+		TrustedKeys:   trusted,
+		Remotes:       remotes,
+		Revocations:   revocations,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize jwt: %v", err)
+	}
+	jwtx.Default = service
+
+	jwksPath := conf.JWKSPath
+	if jwksPath == "" {
+		jwksPath = "/.well-known/jwks.json"
+	}
+	router.AddRouter(router.Router{Path: jwksPath, Method: http.MethodGet, Handler: http.HandlerFunc(service.HandleJWKS)})
+	if !conf.VerifyOnly {
+		refreshPath, revokePath := conf.RefreshPath, conf.RevokePath
+		if refreshPath == "" {
+			refreshPath = "/auth/refresh"
+		}
+		if revokePath == "" {
+			revokePath = "/auth/revoke"
+		}
+		router.AddRouter(router.Router{Path: refreshPath, Method: http.MethodPost, Handler: http.HandlerFunc(service.HandleRefresh)})
+		router.AddRouter(router.Router{Path: revokePath, Method: http.MethodPost, Handler: http.HandlerFunc(service.HandleRevoke)})
+	}
+	log.Println("\033[1;32m🔗 -> Jwt initialized successfully\033[0m")
+}
+
+// InitializeAuthz initialize authorization policy engine
+func InitializeAuthz() {
+	if !config.Core.AuthzEnable {
+		return
+	}
+	conf := config.Core.Authz
+
+	configPolicy := authz.Policy{Roles: make(map[string]authz.Role, len(conf.Roles))}
+	for name, role := range conf.Roles {
+		grants := make([]authz.Grant, 0, len(role.Grants))
+		for _, grant := range role.Grants {
+			grants = append(grants, authz.Grant{Permission: grant.Permission, When: grant.When})
+		}
+		configPolicy.Roles[name] = authz.Role{Inherits: role.Inherits, Permissions: role.Permissions, Grants: grants}
+	}
+
+	var gdb *gorm.DB
+	switch conf.Source {
+	case "", "config":
+	case "db", "both":
+		var ok bool
+		if gdb, ok = db.DbList()[conf.DB]; !ok {
+			log.Fatalf("Authz policy database %s not found", conf.DB)
+		}
+		// 只在启动时迁移, 定时重新加载只读取策略
+		if err := authz.MigratePolicy(gdb); err != nil {
+			log.Fatalf("Failed to migrate authz policy tables: %v", err)
+		}
+	default:
+		log.Fatalf("Unsupported authz source: %s", conf.Source)
+	}
+	loadPolicy := func(ctx context.Context) (authz.Policy, error) {
+		if gdb == nil {
+			return configPolicy, nil
+		}
+		dbPolicy, err := authz.LoadPolicy(ctx, gdb)
+		if err != nil {
+			return authz.Policy{}, err
+		}
+		if conf.Source == "both" {
+			return configPolicy.Merge(dbPolicy), nil
+		}
+		return dbPolicy, nil
+	}
+
+	cacheTTL, err := parseOptionalDuration(conf.CacheTTL)
+	if err != nil {
+		log.Fatalf("Invalid cache_ttl of authz: %v", err)
+	}
+	reloadInterval, err := parseOptionalDuration(conf.ReloadInterval)
+	if err != nil {
+		log.Fatalf("Invalid reload_interval of authz: %v", err)
+	}
+	if conf.RolesClaim != "" {
+		authz.RolesClaim = conf.RolesClaim
+	}
+
+	policy, err := loadPolicy(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to load authz policy: %v", err)
+	}
+	engine, err := authz.NewEngine(policy, authz.Options{
+		CacheTTL:     cacheTTL,
+		CacheSize:    conf.CacheSize,
+		AuditLogger:  conf.AuditLogger,
+		AuditAllowed: conf.AuditAllowed,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize authz: %v", err)
+	}
+	authz.Default = engine
+
+	// 数据库策略定时重新加载, 加载失败时继续使用旧策略
+	if gdb != nil && reloadInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			ticker := time.NewTicker(reloadInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					policy, err := loadPolicy(ctx)
+					if err == nil {
+						err = engine.SetPolicy(policy)
+					}
+					if err != nil {
+						log.Printf("Failed to reload authz policy: %v\n", err)
+					}
+				}
+			}
+		}()
+		Cleanup = append(Cleanup, cancel)
+	}
+
+	if len(conf.GRPC) > 0 {
+		server.RegisterInterceptor(interceptor.AuthzServerInterceptor(conf.GRPC))
+		server.RegisterStreamInterceptor(interceptor.AuthzStreamServerInterceptor(conf.GRPC))
+	}
+	log.Println("\033[1;32m🔗 -> Authz initialized successfully\033[0m")
+}
+
+// InitializeMetering initialize usage metering, quotas and the usage admin api
+func InitializeMetering() {
+	if !config.Core.MeteringEnable {
+		return
+	}
+	conf := config.Core.Metering
+
+	var counter metering.Counter
+	switch conf.Counter {
+	case "", "memory":
+		counter = metering.NewMemoryCounter()
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("Metering counter redis requires redis_enable")
+		}
+		counter = metering.NewRedisCounter(redisx.Redis, conf.Prefix)
+	default:
+		log.Fatalf("Unsupported metering counter: %s", conf.Counter)
+	}
+
+	plans := make(map[string]metering.Plan, len(conf.Plans))
+	for name, plan := range conf.Plans {
+		plans[name] = metering.Plan{Daily: plan.Daily, Monthly: plan.Monthly}
+	}
+	meter, err := metering.NewMeter(counter, metering.Config{
+		Plans:       plans,
+		Consumers:   conf.Consumers,
+		DefaultPlan: conf.DefaultPlan,
+		PlanClaim:   conf.PlanClaim,
+		TenantClaim: conf.TenantClaim,
+		DB:          conf.DB,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize metering: %v", err)
+	}
+	if err := meter.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate metering tables: %v", err)
+	}
+	metering.Default = meter
+
+	flushInterval, err := parseOptionalDuration(conf.FlushInterval)
+	if err != nil {
+		log.Fatalf("Invalid flush_interval of metering: %v", err)
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Minute
+	}
+	// 退出时最后写入一次, 避免丢失内存中的计数; 必须在关闭 redis 和数据库之前执行, 所以放在 Cleanup 的最前面
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		meter.Run(ctx, flushInterval)
+		close(done)
+	}()
+	Cleanup = append([]func(){func() {
+		cancel()
+		<-done
+	}}, Cleanup...)
+
+	prefix := conf.AdminPrefix
+	if prefix == "" {
+		prefix = "/admin/usage"
+	}
+	scope := conf.AdminScope
+	if scope == "" {
+		scope = "usage:admin"
+	}
+	router.AddRouterGroup(router.RouteGroup{
+		Prefix:     prefix,
+		Middleware: []router.MiddlewareFunc{middleware.ApiKeyScopeMiddleware(scope)},
+		Routes: []router.Router{
+			{Path: "", Method: http.MethodGet, Handler: http.HandlerFunc(meter.HandleReport)},
+			{Path: "/export", Method: http.MethodGet, Handler: http.HandlerFunc(meter.HandleExport)},
+			{Path: "/consumers/{consumer}", Method: http.MethodGet, Handler: http.HandlerFunc(meter.HandleConsumer)},
+		},
+	})
+	log.Println("\033[1;32m🔗 -> Metering initialized successfully\033[0m")
+}
+
+// InitializeRateLimit initialize the store of rate limiters and the gRPC rate limit interceptors
+func InitializeRateLimit() {
+	conf := config.Core.RateLimit
+	switch conf.Store {
+	case "", "local":
+		ratelimit.Default = ratelimit.NewLocalStore()
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("Rate limit store redis requires redis_enable")
+		}
+		ratelimit.Default = ratelimit.NewRedisStore(redisx.Redis, conf.Prefix)
+	default:
+		log.Fatalf("Unsupported rate limit store: %s", conf.Store)
+	}
+
+	if len(conf.GRPC.Tiers) > 0 {
+		limiter := newRateLimiter(conf.GRPC.Tiers, "grpc")
+		server.RegisterInterceptor(interceptor.RateLimitServerInterceptor(limiter, conf.GRPC.Keys...))
+		server.RegisterStreamInterceptor(interceptor.RateLimitStreamServerInterceptor(limiter, conf.GRPC.Keys...))
+	}
+	log.Println("\033[1;32m🔗 -> Rate limit initialized successfully\033[0m")
+}
+
+// InitializeIdempotency initialize the store of the idempotency middleware
+func InitializeIdempotency() {
+	conf := config.Core.Idempotency
+	switch conf.Store {
+	case "", "memory":
+		idempotency.Default = idempotency.NewMemoryStore()
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("Idempotency store redis requires redis_enable")
+		}
+		idempotency.Default = idempotency.NewRedisStore(redisx.Redis, conf.Prefix)
+	default:
+		log.Fatalf("Unsupported idempotency store: %s", conf.Store)
+	}
+
+	ttl, err := parseOptionalDuration(conf.TTL)
+	if err != nil {
+		log.Fatalf("Invalid ttl of idempotency: %v", err)
+	}
+	if ttl > 0 {
+		idempotency.DefaultTTL = ttl
+	}
+	lockTTL, err := parseOptionalDuration(conf.LockTTL)
+	if err != nil {
+		log.Fatalf("Invalid lock_ttl of idempotency: %v", err)
+	}
+	if lockTTL > 0 {
+		idempotency.DefaultLockTTL = lockTTL
+	}
+	log.Println("\033[1;32m🔗 -> Idempotency initialized successfully\033[0m")
+}
+
+// InitializeHTTPCache initialize the store of the cache middleware
+func InitializeHTTPCache() {
+	conf := config.Core.HTTPCache
+	switch conf.Store {
+	case "", "memory":
+		httpcache.Default = httpcache.NewMemoryStore(conf.Capacity)
+	case "redis":
+		if redisx.Redis == nil {
+			log.Fatalf("HTTP cache store redis requires redis_enable")
+		}
+		httpcache.Default = httpcache.NewRedisStore(redisx.Redis, conf.Prefix)
+	default:
+		log.Fatalf("Unsupported http cache store: %s", conf.Store)
+	}
+	log.Println("\033[1;32m🔗 -> HTTP cache initialized successfully\033[0m")
+}
+
+// newRateLimiter creates a limiter of the configured tiers with ratelimit.Default
+func newRateLimiter(tiers []config.RateLimitTierConfig, name string) *ratelimit.Limiter {
+	converted := make([]ratelimit.Tier, 0, len(tiers))
+	for _, tier := range tiers {
+		window, err := time.ParseDuration(tier.Window)
+		if err != nil {
+			log.Fatalf("Invalid window of %s rate limit tier: %v", name, err)
+		}
+		converted = append(converted, ratelimit.Tier{Name: tier.Name, Limit: tier.Limit, Window: window, Burst: tier.Burst})
+	}
+	limiter, err := ratelimit.NewLimiter(nil, converted...)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s rate limiter: %v", name, err)
+	}
+	return limiter
+}
+
+// parseOptionalDuration parses the duration, empty means zero
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
 // InitializeTCP initialize tcp
 func InitializeTCP() {
 	if config.Core.TCPEnable {
@@ -405,13 +952,20 @@ func InitializeTCP() {
 			log.Fatalf("创建协议失败: %v", err)
 		}
 
-		server, cleanup, err := tcp.NewServer(config.Core.Tcp.Address, p, tcp.GetHandler(config.Core.Tcp.Handler),
-			tcp.WithMaxConnections(int32(config.Core.Tcp.MaxConnections)),                         // 最大连接数
-			tcp.WithConnectionBufferSize(config.Core.Tcp.BufferSize),                              // 缓冲区大小
-			tcp.WithConnectionMaxMessageSize(config.Core.Tcp.MaxMessageSize),                      // 最大消息大小
-			tcp.WithConnectionIdleTimeout(time.Duration(config.Core.Tcp.IdleTimeout)*time.Minute), // 空闲超时时间
-			tcp.WithConnectionRateLimiter(config.Core.Tcp.RateLimiter),                            // 消息频率限制器
-		)
+		opts := []tcp.ServerOption{
+			tcp.WithMaxConnections(int32(config.Core.Tcp.MaxConnections)),                           // 最大连接数
+			tcp.WithConnectionBufferSize(config.Core.Tcp.BufferSize),                                // 缓冲区大小
+			tcp.WithConnectionMaxMessageSize(config.Core.Tcp.MaxMessageSize),                        // 最大消息大小
+			tcp.WithConnectionIdleTimeout(time.Duration(config.Core.Tcp.IdleTimeout) * time.Minute), // 空闲超时时间
+			tcp.WithConnectionRateLimiter(config.Core.Tcp.RateLimiter),                              // 消息频率限制器
+		}
+		if list := config.Core.IPFilter.TCP; list != "" {
+			opts = append(opts, tcp.WithIPFilter(list)) // 接受连接时拒绝列表中不允许的客户端 IP
+		}
+		if tiers := config.Core.RateLimit.TCP.Tiers; len(tiers) > 0 {
+			opts = append(opts, tcp.WithSharedRateLimiter(newRateLimiter(tiers, "tcp"))) // 按客户端 IP 共享的消息频率限制器
+		}
+		server, cleanup, err := tcp.NewServer(config.Core.Tcp.Address, p, tcp.GetHandler(config.Core.Tcp.Handler), opts...)
 
 		if err != nil {
 			log.Fatalf("Failed to initialize tcp server: %v", err)
@@ -469,6 +1023,129 @@ func parseLevel(level string) logx.LogLevel {
 	}
 }
 
+// buildRouteConfig 将配置文件中的路由和分组转换为 router 的配置, 分组递归转换
+func buildRouteConfig(routeConfigs []config.RouteConfig, groupConfigs []config.RouteGroupConfig) ([]router.RouteConfig, []router.GroupConfig) {
+	routes := make([]router.RouteConfig, 0, len(routeConfigs))
+	for _, rc := range routeConfigs {
+		routes = append(routes, router.RouteConfig{
+			Path:       rc.Path,
+			Methods:    rc.Methods,
+			Handler:    rc.Handler,
+			Upstream:   buildUpstream(rc.Path, rc.Upstream),
+			Middleware: buildMiddlewareConfig(rc.Middleware),
+		})
+	}
+	groups := make([]router.GroupConfig, 0, len(groupConfigs))
+	for _, gc := range groupConfigs {
+		groupRoutes, subGroups := buildRouteConfig(gc.Routes, gc.Groups)
+		groups = append(groups, router.GroupConfig{
+			Prefix:     gc.Prefix,
+			Middleware: buildMiddlewareConfig(gc.Middleware),
+			Routes:     groupRoutes,
+			Groups:     subGroups,
+		})
+	}
+	return routes, groups
+}
+
+func buildUpstream(path string, upstreamConfig *config.UpstreamConfig) *router.Upstream {
+	if upstreamConfig == nil {
+		return nil
+	}
+	duration := func(name string, value string) time.Duration {
+		if value == "" {
+			return 0
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid %s of upstream %s: %v", name, path, err)
+		}
+		return d
+	}
+	var mirror *router.Mirror
+	if mc := upstreamConfig.Mirror; mc != nil {
+		mirror = &router.Mirror{
+			Targets:       mc.Targets,
+			Service:       mc.Service,
+			Tags:          mc.Tags,
+			Percent:       mc.Percent,
+			MaxBodySize:   mc.MaxBodySize,
+			Timeout:       duration("mirror.timeout", mc.Timeout),
+			MaxConcurrent: mc.MaxConcurrent,
+			Logger:        mc.Logger,
+		}
+	}
+	breakerConfig := func(name string, bc *config.BreakerConfig) *breaker.Config {
+		if bc == nil {
+			return nil
+		}
+		return &breaker.Config{
+			Window:              duration(name+".window", bc.Window),
+			MinCalls:            bc.MinCalls,
+			FailureRate:         bc.FailureRate,
+			ConsecutiveFailures: bc.ConsecutiveFailures,
+			SlowCall:            duration(name+".slow_call", bc.SlowCall),
+			SlowCallRate:        bc.SlowCallRate,
+			OpenTimeout:         duration(name+".open_timeout", bc.OpenTimeout),
+			HalfOpenCalls:       bc.HalfOpenCalls,
+		}
+	}
+	return &router.Upstream{
+		Targets:               upstreamConfig.Targets,
+		Service:               upstreamConfig.Service,
+		Tags:                  upstreamConfig.Tags,
+		Split:                 upstreamConfig.Split,
+		Scheme:                upstreamConfig.Scheme,
+		StripPrefix:           upstreamConfig.StripPrefix,
+		AddPrefix:             upstreamConfig.AddPrefix,
+		PreserveHost:          upstreamConfig.PreserveHost,
+		SetHeaders:            upstreamConfig.SetHeaders,
+		RemoveHeaders:         upstreamConfig.RemoveHeaders,
+		SetResponseHeaders:    upstreamConfig.SetResponseHeaders,
+		RemoveResponseHeaders: upstreamConfig.RemoveResponseHeaders,
+		DialTimeout:           duration("dial_timeout", upstreamConfig.DialTimeout),
+		ResponseHeaderTimeout: duration("response_header_timeout", upstreamConfig.ResponseHeaderTimeout),
+		Timeout:               duration("timeout", upstreamConfig.Timeout),
+		Retries:               upstreamConfig.Retries,
+		RetryBackoff:          duration("retry_backoff", upstreamConfig.RetryBackoff),
+		Breaker:               breakerConfig("breaker", upstreamConfig.Breaker),
+		Outlier:               breakerConfig("outlier", upstreamConfig.Outlier),
+		Mirror:                mirror,
+	}
+}
+
+func buildSplitConfig(splitConfig config.SplitConfig) router.SplitConfig {
+	split := router.SplitConfig{Sticky: splitConfig.Sticky}
+	for _, pool := range splitConfig.Pools {
+		split.Pools = append(split.Pools, router.PoolConfig{
+			Name:    pool.Name,
+			Weight:  pool.Weight,
+			Targets: pool.Targets,
+			Service: pool.Service,
+			Scheme:  pool.Scheme,
+			Tags:    pool.Tags,
+		})
+	}
+	for _, rule := range splitConfig.Rules {
+		split.Rules = append(split.Rules, router.SplitRule{
+			Pool:   rule.Pool,
+			Header: rule.Header,
+			Cookie: rule.Cookie,
+			Query:  rule.Query,
+			Claim:  rule.Claim,
+		})
+	}
+	return split
+}
+
+func buildMiddlewareConfig(middlewareConfigs []config.MiddlewareConfig) []router.MiddlewareConfig {
+	middleware := make([]router.MiddlewareConfig, 0, len(middlewareConfigs))
+	for _, mc := range middlewareConfigs {
+		middleware = append(middleware, router.MiddlewareConfig{Name: mc.Name, Params: mc.Params})
+	}
+	return middleware
+}
+
 // BuildConfig 构建Consul配置, 注意参数配置的完整性
 func buildConsulConfig(server config.ConsulServer, service config.ConsulService) (*consul.ServerConfig, *consul.ServiceConfig) {
 	serverConfig := &consul.ServerConfig{
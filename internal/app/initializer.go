@@ -56,6 +56,17 @@ func initialize(configPath string, env string) {
 	InitializeTemplates()
 	InitializeCron()
 	InitializeInjector()
+	InitializeHTTP()
+	InitializeRealIP()
+	InitializeIPFilter()
+	InitializeApiKey()
+	InitializeJwt()
+	InitializeAuthz()
+	InitializeRateLimit()
+	InitializeMetering()
+	InitializeIdempotency()
+	InitializeHTTPCache()
+	InitializeRouter()
 	InitializeWebsocket()
 	InitializeMCP()
 	InitializegRPC()
@@ -2,7 +2,8 @@ package hooks
 
 import (
 	"Taurus/pkg/grpc/attributes"
-	"log"
+	"Taurus/pkg/grpc/server/interceptor"
+	"context"
 
 	"google.golang.org/grpc"
 )
@@ -11,10 +12,14 @@ import (
 // Email: 61647649@qq.com
 // Date: 2025-06-13
 
-// gRPC host 中间件
+// gRPC host 中间件, 与 HTTP 的 host 中间件使用同一个 ip_filter.lists.default 列表, 列表未定义时拒绝所有请求
 func HostMiddleware() attributes.UnaryMiddleware {
 	return func(handler grpc.UnaryHandler) grpc.UnaryHandler {
-		log.Printf("gRPC -> 自定义 HostMiddleware")
-		return handler
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := interceptor.CheckHost(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
 	}
 }
@@ -45,7 +45,8 @@ func InitgRPCHooks() {
 		server.RegisterInterceptor(interceptor.AuthServerInterceptor("Bearer 123456"))
 		server.RegisterStreamInterceptor(interceptor.AuthStreamServerInterceptor("Bearer 123456"))
 		// RateLimitServerInterceptor
-		// server.RegisterInterceptor(interceptor.RateLimitServerInterceptor(10))
+		// limiter, _ := ratelimit.NewLimiter(nil, ratelimit.Tier{Limit: 10, Window: time.Second})
+		// server.RegisterInterceptor(interceptor.RateLimitServerInterceptor(limiter, "method", "ip"))
 
 		// 验证器
 		// ValidatorServerInterceptor
@@ -3,6 +3,8 @@ package hooks
 import (
 	"Taurus/pkg/contextx"
 	"Taurus/pkg/logx"
+	"Taurus/pkg/realip"
+	"Taurus/pkg/router"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -43,9 +45,11 @@ func CreateTraceSimpleMiddleware() func(next http.Handler) http.Handler {
 				requestid = uuid.New().String()
 			}
 			atTime := time.Now()
+			clientIP := realip.FromRequest(r)
 			ctx := contextx.WithRequestContext(r.Context(), &contextx.RequestContext{
-				TraceID: requestid,
-				AtTime:  atTime,
+				TraceID:  requestid,
+				AtTime:   atTime,
+				ClientIP: clientIP,
 			})
 			wr := WrapResponseWriter(w)
 			next.ServeHTTP(wr, r.WithContext(ctx))
@@ -58,6 +62,7 @@ func CreateTraceSimpleMiddleware() func(next http.Handler) http.Handler {
 				AtTime:     atTime.Format(time.DateTime),
 				URL:        r.URL.String(),
 				Method:     r.Method,
+				ClientIP:   clientIP,
 				Status:     wr.statusCode,
 				DurationMs: duration.Milliseconds(),
 			})
@@ -74,6 +79,7 @@ type traceLogMessage struct {
 	AtTime     string `json:"at_time"`
 	URL        string `json:"url"`
 	Method     string `json:"method"`
+	ClientIP   string `json:"client_ip"`
 	Status     int    `json:"status"`
 	DurationMs int64  `json:"duration_ms"`
 }
@@ -105,4 +111,7 @@ func (f *traceSimpleFormatter) Format(level logx.LogLevel, file string, line int
 
 func init() {
 	logx.RegisterFormatter("trace_simple", &traceSimpleFormatter{})
+	router.RegisterMiddleware("trace_simple", func(router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		return CreateTraceSimpleMiddleware(), nil
+	})
 }
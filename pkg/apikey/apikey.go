@@ -0,0 +1,329 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package apikey 管理 API key: 多个 key 哈希存储, 按 key 的权限范围(scope)鉴权, 过期, 最后使用时间, 轮换时新旧 key 同时有效
+//
+// key 的格式为 tk_<id>.<secret>, id 是公开的身份标识, 只有 secret 的哈希会被存储, secret 只在签发时返回一次
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"Taurus/pkg/util/secure"
+)
+
+const prefix = "tk_"
+
+var (
+	ErrNotFound = errors.New("api key not found")
+	ErrInvalid  = errors.New("api key is invalid")
+	ErrExpired  = errors.New("api key is expired")
+	ErrRevoked  = errors.New("api key is revoked")
+)
+
+// Key is a stored API key, the secret is never stored
+type Key struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:32"`
+	Name        string     `json:"name" gorm:"size:128;index"`
+	Hash        string     `json:"hash,omitempty" gorm:"size:128"` // sha256:<hex> or a bcrypt hash of the secret
+	Scopes      []string   `json:"scopes" gorm:"serializer:json"`  // e.g. orders:read, orders:*, *
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RotatedFrom string     `json:"rotated_from,omitempty" gorm:"size:32"` // id of the key replaced by this key
+}
+
+// TableName is the table of the DB store
+func (Key) TableName() string {
+	return "api_keys"
+}
+
+// HasScopes reports whether the key has all the scopes
+func (k *Key) HasScopes(scopes ...string) bool {
+	return HasScopes(k.Scopes, scopes...)
+}
+
+// HasScopes reports whether the granted scopes cover all the required scopes,
+// * grants all scopes and orders:* grants orders:read
+func HasScopes(granted []string, required ...string) bool {
+	for _, scope := range required {
+		if !hasScope(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasScope(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == "*" || g == scope {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(scope, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Public returns a copy without the hash, used by the admin API
+func (k *Key) Public() *Key {
+	public := *k
+	public.Hash = ""
+	return &public
+}
+
+// Config holds the options of a Manager, zero values use the defaults
+type Config struct {
+	Hash          string        // sha256 or bcrypt, default sha256. bcrypt costs tens of milliseconds per uncached verification
+	CacheTTL      time.Duration // verified keys are cached, revocation takes effect after the TTL, default 10s, -1 disables
+	TouchInterval time.Duration // minimal interval of last used updates of a key, default 1m
+}
+
+// Manager issues, verifies, rotates and revokes API keys
+type Manager struct {
+	store  Store
+	config Config
+
+	mu      sync.Mutex
+	cache   map[string]cachedKey
+	touched map[string]time.Time
+}
+
+type cachedKey struct {
+	key     *Key
+	digest  string // sha256 of the verified secret, a different secret of the same id is verified again
+	expires time.Time
+}
+
+// Default is the manager used by the API key middleware, set by the application at startup
+var Default *Manager
+
+// NewManager creates a manager of the store
+func NewManager(store Store, config Config) *Manager {
+	if config.Hash == "" {
+		config.Hash = "sha256"
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 10 * time.Second
+	}
+	if config.TouchInterval <= 0 {
+		config.TouchInterval = time.Minute
+	}
+	return &Manager{
+		store:   store,
+		config:  config,
+		cache:   make(map[string]cachedKey),
+		touched: make(map[string]time.Time),
+	}
+}
+
+// Issue creates a key, the returned secret key is shown once and can not be recovered. ttl 0 means never expires
+func (m *Manager) Issue(ctx context.Context, name string, scopes []string, ttl time.Duration) (*Key, string, error) {
+	key, raw, err := m.newKey(name, scopes, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := m.store.Save(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+// Rotate issues a key with the name and scopes of the old key, the old key stays valid for the overlap,
+// so clients can switch to the new key without downtime
+func (m *Manager) Rotate(ctx context.Context, id string, overlap time.Duration) (*Key, string, error) {
+	old, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if old.RevokedAt != nil {
+		return nil, "", ErrRevoked
+	}
+	var ttl time.Duration
+	if old.ExpiresAt != nil {
+		// the new key keeps the lifetime of the old key
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	key, raw, err := m.newKey(old.Name, old.Scopes, ttl)
+	if err != nil {
+		return nil, "", err
+	}
+	key.RotatedFrom = old.ID
+	if err := m.store.Save(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	deadline := time.Now().Add(overlap)
+	if old.ExpiresAt == nil || old.ExpiresAt.After(deadline) {
+		old.ExpiresAt = &deadline
+		if err := m.store.Save(ctx, old); err != nil {
+			return nil, "", err
+		}
+		m.forget(old.ID)
+	}
+	return key, raw, nil
+}
+
+// Revoke disables the key immediately on this instance, other instances after the cache TTL
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	key, err := m.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := m.store.Save(ctx, key); err != nil {
+		return err
+	}
+	m.forget(id)
+	return nil
+}
+
+// List returns all keys, including expired and revoked keys
+func (m *Manager) List(ctx context.Context) ([]*Key, error) {
+	return m.store.List(ctx)
+}
+
+// Verify returns the key of the raw API key if it is valid
+func (m *Manager) Verify(ctx context.Context, raw string) (*Key, error) {
+	id, secret, ok := parse(raw)
+	if !ok {
+		return nil, ErrInvalid
+	}
+
+	now := time.Now()
+	digest := secure.SHA256String(secret)
+	m.mu.Lock()
+	cached, hit := m.cache[id]
+	m.mu.Unlock()
+
+	var key *Key
+	if hit && now.Before(cached.expires) && subtle.ConstantTimeCompare([]byte(cached.digest), []byte(digest)) == 1 {
+		key = cached.key
+	} else {
+		var err error
+		if key, err = m.store.Get(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, ErrInvalid
+			}
+			return nil, err
+		}
+		if !checkHash(secret, key.Hash) {
+			return nil, ErrInvalid
+		}
+		if m.config.CacheTTL > 0 {
+			m.mu.Lock()
+			m.cache[id] = cachedKey{key: key, digest: digest, expires: now.Add(m.config.CacheTTL)}
+			m.mu.Unlock()
+		}
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	m.touch(key.ID, now)
+	return key, nil
+}
+
+// touch records the last used time in the background, at most once per TouchInterval per key
+func (m *Manager) touch(id string, now time.Time) {
+	m.mu.Lock()
+	if now.Sub(m.touched[id]) < m.config.TouchInterval {
+		m.mu.Unlock()
+		return
+	}
+	m.touched[id] = now
+	m.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.store.Touch(ctx, id, now); err != nil {
+			log.Printf("Failed to update last used time of api key %s: %v\n", id, err)
+		}
+	}()
+}
+
+func (m *Manager) forget(id string) {
+	m.mu.Lock()
+	delete(m.cache, id)
+	m.mu.Unlock()
+}
+
+func (m *Manager) newKey(name string, scopes []string, ttl time.Duration) (*Key, string, error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, "", err
+	}
+	id := hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	var hash string
+	switch m.config.Hash {
+	case "sha256":
+		hash = "sha256:" + secure.SHA256String(secret)
+	case "bcrypt":
+		hash = secure.BcryptHash(secret)
+	default:
+		return nil, "", fmt.Errorf("unsupported api key hash %s", m.config.Hash)
+	}
+
+	key := &Key{ID: id, Name: name, Hash: hash, Scopes: scopes, CreatedAt: time.Now()}
+	if ttl > 0 {
+		expiresAt := key.CreatedAt.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	return key, prefix + id + "." + secret, nil
+}
+
+// parse splits a raw key into id and secret
+func parse(raw string) (id string, secret string, ok bool) {
+	if !strings.HasPrefix(raw, prefix) {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(strings.TrimPrefix(raw, prefix), ".")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// checkHash compares the secret with the stored hash in constant time
+func checkHash(secret string, hash string) bool {
+	if strings.HasPrefix(hash, "sha256:") {
+		expected := "sha256:" + secure.SHA256String(secret)
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(hash)) == 1
+	}
+	return secure.BcryptCheck(secret, hash)
+}
@@ -0,0 +1,134 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	m := NewManager(store, Config{CacheTTL: time.Minute})
+
+	key, raw, err := m.Issue(ctx, "partner", []string{"orders:*", "users:read"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(raw, "tk_"+key.ID+".") || strings.Contains(key.Hash, strings.SplitN(raw, ".", 2)[1]) {
+		t.Fatalf("unexpected key %s %+v", raw, key)
+	}
+
+	verified, err := m.Verify(ctx, raw)
+	if err != nil || verified.ID != key.ID {
+		t.Fatalf("verify: %v %+v", err, verified)
+	}
+	if !verified.HasScopes("orders:write", "users:read") || verified.HasScopes("users:write") {
+		t.Errorf("unexpected scopes %v", verified.Scopes)
+	}
+	// a cached key must not accept a different secret
+	if _, err := m.Verify(ctx, "tk_"+key.ID+".wrong"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("wrong secret: %v", err)
+	}
+	for _, invalid := range []string{"", "tk_", "tk_abc", "abc.def", "tk_unknown.secret"} {
+		if _, err := m.Verify(ctx, invalid); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: %v", invalid, err)
+		}
+	}
+
+	// rotation keeps the old key valid during the overlap
+	rotated, rotatedRaw, err := m.Rotate(ctx, key.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RotatedFrom != key.ID || rotated.Name != "partner" || len(rotated.Scopes) != 2 {
+		t.Errorf("unexpected rotated key %+v", rotated)
+	}
+	if _, err := m.Verify(ctx, raw); err != nil {
+		t.Errorf("old key within overlap: %v", err)
+	}
+	if _, err := m.Verify(ctx, rotatedRaw); err != nil {
+		t.Errorf("new key: %v", err)
+	}
+	if _, _, err := m.Rotate(ctx, rotated.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, rotatedRaw); !errors.Is(err, ErrExpired) {
+		t.Errorf("rotated without overlap should expire: %v", err)
+	}
+
+	// revocation takes effect immediately on this instance
+	if err := m.Revoke(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, raw); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked key: %v", err)
+	}
+	if err := m.Revoke(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revoke unknown: %v", err)
+	}
+
+	keys, err := m.List(ctx)
+	if err != nil || len(keys) != 3 {
+		t.Fatalf("list: %v %d", err, len(keys))
+	}
+}
+
+func TestManagerExpiryAndTouch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	m := NewManager(store, Config{Hash: "bcrypt", CacheTTL: -1})
+
+	key, raw, err := m.Issue(ctx, "short", nil, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := m.Verify(ctx, raw); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired key: %v", err)
+	}
+
+	key, raw, err = m.Issue(ctx, "long", []string{"*"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, raw); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if stored, _ := store.Get(ctx, key.ID); stored.LastUsedAt != nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("last used time is not recorded")
+}
+
+// failingStore returns an error which must not reach the client
+type failingStore struct{ *MemoryStore }
+
+func (failingStore) List(context.Context) ([]*Key, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:6379: connection refused")
+}
+
+func TestHandlerErrors(t *testing.T) {
+	m := NewManager(failingStore{NewMemoryStore()}, Config{})
+	rec := httptest.NewRecorder()
+	m.HandleList(rec, httptest.NewRequest(http.MethodGet, "/apikeys", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "10.0.0.1") {
+		t.Errorf("store error leaked: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/apikeys/unknown", nil)
+	req.SetPathValue("id", "unknown")
+	rec = httptest.NewRecorder()
+	m.HandleRevoke(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), ErrNotFound.Error()) {
+		t.Errorf("unknown key: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"Taurus/pkg/httpx"
+)
+
+// 管理接口, 需要由调用方加上鉴权中间件, 例如 middleware.ApiKeyScopeMiddleware("apikey:admin")
+//
+//	GET    {prefix}                 列出所有 key, 不包含哈希
+//	POST   {prefix}                 签发 key, 请求体 {"name": "partner-a", "scopes": ["orders:read"], "ttl": "720h"}
+//	POST   {prefix}/{id}/rotate     轮换 key, 请求体 {"overlap": "24h"}, 旧 key 在 overlap 内仍然有效
+//	DELETE {prefix}/{id}            吊销 key
+
+// issueRequest is the body of the issue API
+type issueRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	TTL    string   `json:"ttl"` // e.g. 720h, empty means never expires
+}
+
+// issueResponse holds the secret key, it is only returned once
+type issueResponse struct {
+	Key    *Key   `json:"key"`
+	Secret string `json:"secret"`
+}
+
+// HandleList lists all keys without hashes
+func (m *Manager) HandleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := m.List(r.Context())
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+		return
+	}
+	public := make([]*Key, 0, len(keys))
+	for _, key := range keys {
+		public = append(public, key.Public())
+	}
+	httpx.SendResponse(w, http.StatusOK, public, nil)
+}
+
+// HandleIssue issues a key
+func (m *Manager) HandleIssue(w http.ResponseWriter, r *http.Request) {
+	var req issueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		httpx.WriteError(w, r, httpx.NewError(http.StatusBadRequest, "apikey.name_required", "name is required"))
+		return
+	}
+	ttl, err := parseDuration(req.TTL)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "apikey.invalid_ttl", "invalid ttl"))
+		return
+	}
+	key, secret, err := m.Issue(r.Context(), req.Name, req.Scopes, ttl)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, issueResponse{Key: key.Public(), Secret: secret}, nil)
+}
+
+// HandleRotate rotates the key of the path value id
+func (m *Manager) HandleRotate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Overlap string `json:"overlap"` // e.g. 24h, empty means the old key expires immediately
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "apikey.invalid_body", "invalid body"))
+			return
+		}
+	}
+	overlap, err := parseDuration(req.Overlap)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "apikey.invalid_overlap", "invalid overlap"))
+		return
+	}
+	key, secret, err := m.Rotate(r.Context(), r.PathValue("id"), overlap)
+	if err != nil {
+		sendError(w, r, err)
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, issueResponse{Key: key.Public(), Secret: secret}, nil)
+}
+
+// HandleRevoke revokes the key of the path value id
+func (m *Manager) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := m.Revoke(r.Context(), r.PathValue("id")); err != nil {
+		sendError(w, r, err)
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, "revoked", nil)
+}
+
+// sendError maps the errors of the manager, the messages of store errors are not returned to the client
+func sendError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusNotFound, "apikey.not_found", ErrNotFound.Error()))
+	case errors.Is(err, ErrRevoked):
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "apikey.revoked", ErrRevoked.Error()))
+	default:
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+	}
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
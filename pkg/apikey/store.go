@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+
+	"gorm.io/gorm"
+)
+
+// Store persists the keys, Get returns ErrNotFound for unknown ids
+type Store interface {
+	Get(ctx context.Context, id string) (*Key, error)
+	Save(ctx context.Context, key *Key) error
+	List(ctx context.Context) ([]*Key, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// MemoryStore keeps the keys in memory, for tests and single instance deployments
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]Key)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = *key
+	return nil
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		key := key
+		keys = append(keys, &key)
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+func (s *MemoryStore) Touch(_ context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[id]; ok {
+		key.LastUsedAt = &at
+		s.keys[id] = key
+	}
+	return nil
+}
+
+// RedisStore keeps the keys as JSON in a redis hash, field is the key id.
+// Last used times are kept in the hash <hash>:last_used, so touching never overwrites a revocation.
+type RedisStore struct {
+	client *redisx.RedisClient
+	hash   string
+}
+
+// NewRedisStore creates a store in the redis hash, default hash is apikeys
+func NewRedisStore(client *redisx.RedisClient, hash string) *RedisStore {
+	if hash == "" {
+		hash = "apikeys"
+	}
+	return &RedisStore{client: client, hash: hash}
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Key, error) {
+	value, err := s.client.HGet(ctx, s.hash, id)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, ErrNotFound
+	}
+	var key Key
+	if err := json.Unmarshal([]byte(value), &key); err != nil {
+		return nil, err
+	}
+	if lastUsed, err := s.client.HGet(ctx, s.hash+":last_used", id); err == nil && lastUsed != "" {
+		if at, err := time.Parse(time.RFC3339Nano, lastUsed); err == nil {
+			key.LastUsedAt = &at
+		}
+	}
+	return &key, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, key *Key) error {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.hash, key.ID, string(value))
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]*Key, error) {
+	values, err := s.client.HGetList(ctx, s.hash)
+	if err != nil {
+		return nil, err
+	}
+	lastUsed, err := s.client.HGetList(ctx, s.hash+":last_used")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(values))
+	for id, value := range values {
+		var key Key
+		if err := json.Unmarshal([]byte(value), &key); err != nil {
+			return nil, err
+		}
+		if at, err := time.Parse(time.RFC3339Nano, lastUsed[id]); err == nil {
+			key.LastUsedAt = &at
+		}
+		keys = append(keys, &key)
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+func (s *RedisStore) Touch(ctx context.Context, id string, at time.Time) error {
+	return s.client.HSet(ctx, s.hash+":last_used", id, at.Format(time.RFC3339Nano))
+}
+
+// DBStore keeps the keys in the api_keys table
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore creates a store in the database, the table is migrated
+func NewDBStore(db *gorm.DB) (*DBStore, error) {
+	if err := db.AutoMigrate(&Key{}); err != nil {
+		return nil, err
+	}
+	return &DBStore{db: db}, nil
+}
+
+func (s *DBStore) Get(ctx context.Context, id string) (*Key, error) {
+	var key Key
+	if err := s.db.WithContext(ctx).First(&key, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *DBStore) Save(ctx context.Context, key *Key) error {
+	return s.db.WithContext(ctx).Save(key).Error
+}
+
+func (s *DBStore) List(ctx context.Context) ([]*Key, error) {
+	var keys []*Key
+	if err := s.db.WithContext(ctx).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Touch only updates last_used_at, so it never overwrites a concurrent revocation
+func (s *DBStore) Touch(ctx context.Context, id string, at time.Time) error {
+	return s.db.WithContext(ctx).Model(&Key{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func sortKeys(keys []*Key) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package authz 授权策略引擎: 角色 -> 权限的 RBAC, 加上资源属性条件(例如资源所有者、租户)的 ABAC
+//
+// 认证由 jwt 和 api_key 中间件完成, 授权的主体从请求上下文中获取: JWT 的 sub 和 roles claim, 或者 API key 的 scope.
+// 决策结果会被缓存, 拒绝的请求会记录审计日志
+package authz
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/jwtx"
+	"Taurus/pkg/logx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes are the attributes of a subject or a resource, e.g. id, owner, tenant
+type Attributes map[string]string
+
+// Subject is the authenticated caller
+type Subject struct {
+	ID          string
+	Roles       []string
+	Permissions []string   // 直接授予的权限, 例如 API key 的 scope
+	Attributes  Attributes // 条件中的 subject.<name>, 默认包含 id
+}
+
+// Request is an authorization request
+type Request struct {
+	Subject    Subject
+	Permission string
+	Resource   Attributes // 条件中的 resource.<name>
+	Target     string     // 路由或 gRPC 方法, 只用于审计日志
+}
+
+// Decision is the result of an authorization request
+type Decision struct {
+	Allowed bool
+	Reason  string // e.g. role admin, scope orders:*, no grant
+}
+
+// Options holds the options of an Engine, zero values use the defaults
+type Options struct {
+	CacheTTL     time.Duration // 决策缓存时间, 默认 1m, -1 表示不缓存
+	CacheSize    int           // 缓存的最大决策数, 超过后清空, 默认 10000
+	AuditLogger  string        // 审计日志使用的 logx 日志名称, 默认 default
+	AuditAllowed bool          // 是否记录允许的请求, 默认只记录拒绝的请求
+}
+
+// Engine evaluates authorization requests against a policy, the policy can be replaced at runtime
+type Engine struct {
+	options Options
+
+	mu         sync.RWMutex
+	policy     *compiledPolicy
+	generation uint64
+
+	cacheMu sync.Mutex
+	cache   map[string]cachedDecision
+}
+
+type cachedDecision struct {
+	decision   Decision
+	generation uint64
+	expires    time.Time
+}
+
+// Default is the engine used by the authorization middleware and interceptors, set by the application at startup
+var Default *Engine
+
+// NewEngine creates an engine of the policy
+func NewEngine(policy Policy, options Options) (*Engine, error) {
+	if options.CacheTTL == 0 {
+		options.CacheTTL = time.Minute
+	}
+	if options.CacheSize <= 0 {
+		options.CacheSize = 10000
+	}
+	if options.AuditLogger == "" {
+		options.AuditLogger = "default"
+	}
+	e := &Engine{options: options, cache: make(map[string]cachedDecision)}
+	if err := e.SetPolicy(policy); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SetPolicy replaces the policy, cached decisions of the old policy are discarded
+func (e *Engine) SetPolicy(policy Policy) error {
+	compiled, err := compile(policy)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.policy = compiled
+	e.generation++
+	e.mu.Unlock()
+
+	e.cacheMu.Lock()
+	e.cache = make(map[string]cachedDecision)
+	e.cacheMu.Unlock()
+	return nil
+}
+
+// Authorize decides the request, the decision is traced and denials are audit-logged
+func (e *Engine) Authorize(ctx context.Context, req Request) Decision {
+	e.mu.RLock()
+	policy, generation := e.policy, e.generation
+	e.mu.RUnlock()
+
+	key := cacheKey(policy, req)
+	decision, ok := e.cached(key, generation)
+	if !ok {
+		decision = evaluate(policy, req)
+		e.store(key, decision, generation)
+	}
+
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		span.SetAttributes(
+			attribute.String("authz.permission", req.Permission),
+			attribute.Bool("authz.allowed", decision.Allowed),
+			attribute.String("authz.reason", decision.Reason),
+		)
+	}
+	if !decision.Allowed || e.options.AuditAllowed {
+		e.audit(req, decision)
+	}
+	return decision
+}
+
+// Check authorizes the subject of the context with Default, for permission checks in handlers after the resource is loaded
+func Check(ctx context.Context, permission string, resource Attributes) Decision {
+	if Default == nil {
+		return Decision{Reason: "authz is not configured"}
+	}
+	subject, ok := SubjectFromContext(ctx)
+	if !ok {
+		return Decision{Reason: "unauthenticated"}
+	}
+	return Default.Authorize(ctx, Request{Subject: subject, Permission: permission, Resource: resource})
+}
+
+func evaluate(policy *compiledPolicy, req Request) Decision {
+	for _, granted := range req.Subject.Permissions {
+		if matchPermission(granted, req.Permission) {
+			return Decision{Allowed: true, Reason: "scope " + granted}
+		}
+	}
+
+	conditional := false
+	for _, role := range req.Subject.Roles {
+		for _, grant := range policy.grants[role] {
+			if !matchPermission(grant.Permission, req.Permission) {
+				continue
+			}
+			if len(grant.When) == 0 {
+				return Decision{Allowed: true, Reason: "role " + role}
+			}
+			if matchConditions(grant.When, req.Subject.Attributes, req.Resource) {
+				return Decision{Allowed: true, Reason: "role " + role + " with conditions"}
+			}
+			conditional = true
+		}
+	}
+	if conditional {
+		return Decision{Reason: "conditions not met"}
+	}
+	return Decision{Reason: "no grant"}
+}
+
+func (e *Engine) cached(key string, generation uint64) (Decision, bool) {
+	if e.options.CacheTTL < 0 {
+		return Decision{}, false
+	}
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	entry, ok := e.cache[key]
+	if !ok || entry.generation != generation || time.Now().After(entry.expires) {
+		return Decision{}, false
+	}
+	return entry.decision, true
+}
+
+func (e *Engine) store(key string, decision Decision, generation uint64) {
+	if e.options.CacheTTL < 0 {
+		return
+	}
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	if len(e.cache) >= e.options.CacheSize {
+		// 简单起见直接清空, 决策的计算成本很低, 缓存只用于削减高频重复请求
+		e.cache = make(map[string]cachedDecision)
+	}
+	e.cache[key] = cachedDecision{decision: decision, generation: generation, expires: time.Now().Add(e.options.CacheTTL)}
+}
+
+// cacheKey contains everything the decision depends on: the permission, the roles, the direct permissions and
+// the attributes referenced by the conditions of the matching grants. Other attributes, e.g. the iat or jti claims, are left out
+// so that they do not make every key unique
+func cacheKey(policy *compiledPolicy, req Request) string {
+	var b strings.Builder
+	b.WriteString(req.Permission)
+	for _, values := range [][]string{req.Subject.Roles, req.Subject.Permissions} {
+		sorted := append([]string(nil), values...)
+		sort.Strings(sorted)
+		b.WriteString("|")
+		b.WriteString(strings.Join(sorted, ","))
+	}
+	b.WriteString("|")
+	for _, name := range referencedAttributes(policy, req) {
+		value, _ := lookup(name, req.Subject.Attributes, req.Resource)
+		fmt.Fprintf(&b, "%q=%q,", name, value)
+	}
+	return b.String()
+}
+
+// referencedAttributes returns the attributes in the conditions of the grants of the subject which cover the permission, in order
+func referencedAttributes(policy *compiledPolicy, req Request) []string {
+	var names []string
+	for _, role := range req.Subject.Roles {
+		for _, grant := range policy.grants[role] {
+			if !matchPermission(grant.Permission, req.Permission) {
+				continue
+			}
+			for left, right := range grant.When {
+				names = append(names, left)
+				if isAttribute(right) {
+					names = append(names, right)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+func (e *Engine) audit(req Request, decision Decision) {
+	result := "deny"
+	if decision.Allowed {
+		result = "allow"
+	}
+	format := "authz %s subject=%s roles=%v permission=%s target=%s resource=%v reason=%s"
+	args := []any{result, req.Subject.ID, req.Subject.Roles, req.Permission, req.Target, map[string]string(req.Resource), decision.Reason}
+	level := logx.LEVEL_WARN
+	if decision.Allowed {
+		level = logx.LEVEL_INFO
+	}
+	logx.Logf(level, e.options.AuditLogger, format, args...)
+}
+
+// RolesClaim is the JWT claim of the roles, a list or a comma separated string
+var RolesClaim = "roles"
+
+// SubjectFromContext returns the subject authenticated by the jwt or api_key middleware.
+// A JWT subject has the roles of RolesClaim and its string claims as attributes, an API key subject has its scopes as permissions
+func SubjectFromContext(ctx context.Context) (Subject, bool) {
+	if claims, ok := jwtx.GetClaims(ctx); ok {
+		subject := Subject{ID: claims.Subject, Attributes: Attributes{"id": claims.Subject}}
+		for name, value := range claims.Extra {
+			if name == RolesClaim {
+				subject.Roles = roles(value)
+				continue
+			}
+			if v := claims.Get(name); v != "" {
+				switch value.(type) {
+				case []interface{}, map[string]interface{}:
+				default:
+					subject.Attributes[name] = v
+				}
+			}
+		}
+		return subject, true
+	}
+	if identity, ok := contextx.GetApiKey(ctx); ok {
+		return Subject{
+			ID:          "apikey:" + identity.ID,
+			Permissions: identity.Scopes,
+			Attributes:  Attributes{"id": identity.ID, "name": identity.Name},
+		}, true
+	}
+	return Subject{}, false
+}
+
+func roles(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		var result []string
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				result = append(result, role)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, role := range v {
+			if s, ok := role.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
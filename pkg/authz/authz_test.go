@@ -0,0 +1,130 @@
+package authz
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/jwtx"
+)
+
+var testPolicy = Policy{Roles: map[string]Role{
+	"admin":  {Permissions: []string{"*"}},
+	"viewer": {Permissions: []string{"orders:read", "users:read"}},
+	"customer": {
+		Inherits: []string{"viewer"},
+		Grants:   []Grant{{Permission: "orders:cancel", When: map[string]string{"resource.owner": "subject.id"}}},
+	},
+	"tenant_admin": {
+		Grants: []Grant{{Permission: "orders:*", When: map[string]string{"resource.tenant": "subject.tenant", "subject.level": "gold"}}},
+	},
+}}
+
+func TestEngine(t *testing.T) {
+	engine, err := NewEngine(testPolicy, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	customer := Subject{ID: "u1", Roles: []string{"customer"}, Attributes: Attributes{"id": "u1"}}
+	tenantAdmin := Subject{ID: "u2", Roles: []string{"tenant_admin"}, Attributes: Attributes{"id": "u2", "tenant": "t1", "level": "gold"}}
+
+	cases := []struct {
+		subject    Subject
+		permission string
+		resource   Attributes
+		allowed    bool
+		reason     string
+	}{
+		{Subject{Roles: []string{"admin"}}, "anything:goes", nil, true, "role admin"},
+		{customer, "orders:read", nil, true, "role customer"},
+		{customer, "orders:write", nil, false, "no grant"},
+		{customer, "orders:cancel", Attributes{"owner": "u1"}, true, "role customer with conditions"},
+		{customer, "orders:cancel", Attributes{"owner": "u9"}, false, "conditions not met"},
+		{customer, "orders:cancel", nil, false, "conditions not met"},
+		{tenantAdmin, "orders:refund", Attributes{"tenant": "t1"}, true, "role tenant_admin with conditions"},
+		{tenantAdmin, "orders:refund", Attributes{"tenant": "t2"}, false, "conditions not met"},
+		{Subject{Roles: []string{"unknown"}}, "orders:read", nil, false, "no grant"},
+		{Subject{Permissions: []string{"orders:*"}}, "orders:read", nil, true, "scope orders:*"},
+		{Subject{Permissions: []string{"orders:*"}}, "ordersx:read", nil, false, "no grant"},
+	}
+	for i, c := range cases {
+		// evaluated twice, the second decision comes from the cache
+		for j := 0; j < 2; j++ {
+			decision := engine.Authorize(ctx, Request{Subject: c.subject, Permission: c.permission, Resource: c.resource})
+			if decision.Allowed != c.allowed || decision.Reason != c.reason {
+				t.Errorf("case %d: got %+v, want %v %s", i, decision, c.allowed, c.reason)
+			}
+		}
+	}
+
+	// replacing the policy discards the cached decisions
+	if err := engine.SetPolicy(Policy{Roles: map[string]Role{"customer": {}}}); err != nil {
+		t.Fatal(err)
+	}
+	if decision := engine.Authorize(ctx, Request{Subject: customer, Permission: "orders:read"}); decision.Allowed {
+		t.Error("cached decision of the old policy is used")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	policy, _ := compile(testPolicy)
+	request := func(subject Attributes, resource Attributes) Request {
+		return Request{Subject: Subject{Roles: []string{"customer"}, Attributes: subject}, Permission: "orders:cancel", Resource: resource}
+	}
+	// claims which are not referenced by a condition, e.g. jti, share the key
+	first := cacheKey(policy, request(Attributes{"id": "u1", "jti": "a"}, Attributes{"owner": "u1", "status": "paid"}))
+	second := cacheKey(policy, request(Attributes{"id": "u1", "jti": "b"}, Attributes{"owner": "u1", "status": "new"}))
+	if first != second {
+		t.Errorf("unreferenced attributes must not be part of the key: %s != %s", first, second)
+	}
+	if other := cacheKey(policy, request(Attributes{"id": "u2"}, Attributes{"owner": "u1"})); other == first {
+		t.Error("referenced attributes must be part of the key")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	policies := map[string]Policy{
+		"cycle":     {Roles: map[string]Role{"a": {Inherits: []string{"b"}}, "b": {Inherits: []string{"a"}}}},
+		"undefined": {Roles: map[string]Role{"a": {Inherits: []string{"missing"}}}},
+		"condition": {Roles: map[string]Role{"a": {Grants: []Grant{{Permission: "x", When: map[string]string{"owner": "subject.id"}}}}}},
+	}
+	for name, policy := range policies {
+		if _, err := NewEngine(policy, Options{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	merged := testPolicy.Merge(Policy{Roles: map[string]Role{"viewer": {Permissions: []string{"reports:read"}}}})
+	engine, _ := NewEngine(merged, Options{CacheTTL: -1})
+	for _, permission := range []string{"orders:read", "reports:read"} {
+		if !engine.Authorize(context.Background(), Request{Subject: Subject{Roles: []string{"customer"}}, Permission: permission}).Allowed {
+			t.Errorf("merged policy should grant %s", permission)
+		}
+	}
+}
+
+func TestSubjectFromContext(t *testing.T) {
+	claims := &jwtx.Claims{Subject: "42", Extra: map[string]interface{}{"roles": []interface{}{"customer", "viewer"}, "tenant": "t1", "groups": []interface{}{"x"}}}
+	subject, ok := SubjectFromContext(jwtx.WithClaims(context.Background(), claims))
+	if !ok || subject.ID != "42" || strings.Join(subject.Roles, ",") != "customer,viewer" ||
+		subject.Attributes["id"] != "42" || subject.Attributes["tenant"] != "t1" || subject.Attributes["groups"] != "" {
+		t.Errorf("unexpected jwt subject %+v", subject)
+	}
+
+	claims.Extra["roles"] = "admin, viewer"
+	if subject, _ := SubjectFromContext(jwtx.WithClaims(context.Background(), claims)); strings.Join(subject.Roles, ",") != "admin,viewer" {
+		t.Errorf("comma separated roles: %v", subject.Roles)
+	}
+
+	identity := &contextx.ApiKeyIdentity{ID: "k1", Name: "partner", Scopes: []string{"orders:read"}}
+	subject, ok = SubjectFromContext(contextx.WithApiKey(context.Background(), identity))
+	if !ok || subject.ID != "apikey:k1" || subject.Permissions[0] != "orders:read" {
+		t.Errorf("unexpected api key subject %+v", subject)
+	}
+
+	if _, ok := SubjectFromContext(context.Background()); ok {
+		t.Error("anonymous context should have no subject")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Policy maps roles to permissions, e.g.
+//
+//	roles:
+//	  viewer:
+//	    permissions: ["orders:read"]
+//	  customer:
+//	    inherits: [viewer]
+//	    grants:
+//	      - permission: "orders:cancel"
+//	        when: {resource.owner: subject.id}      # 只能取消自己的订单
+//	  tenant_admin:
+//	    grants:
+//	      - permission: "orders:*"
+//	        when: {resource.tenant: subject.tenant} # 只能管理本租户的订单
+type Policy struct {
+	Roles map[string]Role `json:"roles" yaml:"roles" toml:"roles"`
+}
+
+// Role holds the permissions of a role, Permissions are granted without conditions
+type Role struct {
+	Inherits    []string `json:"inherits" yaml:"inherits" toml:"inherits"`          // 继承的角色
+	Permissions []string `json:"permissions" yaml:"permissions" toml:"permissions"` // 无条件授予的权限, 支持 * 和 orders:*
+	Grants      []Grant  `json:"grants" yaml:"grants" toml:"grants"`                // 带条件的权限
+}
+
+// Grant grants a permission when all the conditions match.
+// A condition maps an attribute to another attribute or a literal, attributes are subject.<name> or resource.<name>
+type Grant struct {
+	Permission string            `json:"permission" yaml:"permission" toml:"permission"`
+	When       map[string]string `json:"when" yaml:"when" toml:"when"`
+}
+
+// Merge returns a policy with the roles of both policies, permissions and inherits are combined
+func (p Policy) Merge(other Policy) Policy {
+	merged := Policy{Roles: make(map[string]Role, len(p.Roles)+len(other.Roles))}
+	for _, policy := range []Policy{p, other} {
+		for name, role := range policy.Roles {
+			current := merged.Roles[name]
+			current.Inherits = append(current.Inherits, role.Inherits...)
+			current.Permissions = append(current.Permissions, role.Permissions...)
+			current.Grants = append(current.Grants, role.Grants...)
+			merged.Roles[name] = current
+		}
+	}
+	return merged
+}
+
+// compiledPolicy holds the grants of every role including the inherited roles
+type compiledPolicy struct {
+	grants map[string][]Grant
+}
+
+func compile(policy Policy) (*compiledPolicy, error) {
+	c := &compiledPolicy{grants: make(map[string][]Grant, len(policy.Roles))}
+	for name := range policy.Roles {
+		grants, err := expand(policy, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.grants[name] = grants
+	}
+	return c, nil
+}
+
+// expand returns the grants of the role and its inherited roles, path detects inheritance cycles
+func expand(policy Policy, name string, path []string) ([]Grant, error) {
+	for _, visited := range path {
+		if visited == name {
+			return nil, fmt.Errorf("role inheritance cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+	}
+	role, ok := policy.Roles[name]
+	if !ok {
+		return nil, fmt.Errorf("role %s inherited by %s is not defined", name, strings.Join(path, " -> "))
+	}
+
+	var grants []Grant
+	for _, permission := range role.Permissions {
+		grants = append(grants, Grant{Permission: permission})
+	}
+	for _, grant := range role.Grants {
+		if grant.Permission == "" {
+			return nil, fmt.Errorf("role %s has a grant without permission", name)
+		}
+		for left := range grant.When {
+			if !isAttribute(left) {
+				return nil, fmt.Errorf("role %s: condition %s must be subject.<name> or resource.<name>", name, left)
+			}
+		}
+		grants = append(grants, grant)
+	}
+	for _, parent := range role.Inherits {
+		inherited, err := expand(policy, parent, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, inherited...)
+	}
+	return grants, nil
+}
+
+// matchPermission reports whether the granted permission covers the required permission, * covers all and orders:* covers orders:read
+func matchPermission(granted string, required string) bool {
+	if granted == "*" || granted == required {
+		return true
+	}
+	return strings.HasSuffix(granted, ":*") && strings.HasPrefix(required, strings.TrimSuffix(granted, "*"))
+}
+
+// matchConditions reports whether all the conditions hold, a missing attribute never matches
+func matchConditions(when map[string]string, subject Attributes, resource Attributes) bool {
+	for left, right := range when {
+		leftValue, ok := lookup(left, subject, resource)
+		if !ok {
+			return false
+		}
+		rightValue := right
+		if isAttribute(right) {
+			if rightValue, ok = lookup(right, subject, resource); !ok {
+				return false
+			}
+		}
+		if leftValue != rightValue {
+			return false
+		}
+	}
+	return true
+}
+
+func isAttribute(name string) bool {
+	return strings.HasPrefix(name, "subject.") || strings.HasPrefix(name, "resource.")
+}
+
+func lookup(name string, subject Attributes, resource Attributes) (string, bool) {
+	var value string
+	var ok bool
+	if key, found := strings.CutPrefix(name, "subject."); found {
+		value, ok = subject[key]
+	} else if key, found := strings.CutPrefix(name, "resource."); found {
+		value, ok = resource[key]
+	}
+	return value, ok && value != ""
+}
+
+// RoleModel is a role of the DB policy, table authz_roles
+type RoleModel struct {
+	Name     string   `gorm:"primaryKey;size:64"`
+	Inherits []string `gorm:"serializer:json"`
+}
+
+func (RoleModel) TableName() string {
+	return "authz_roles"
+}
+
+// GrantModel is a permission of a role of the DB policy, table authz_grants, an empty When grants without conditions
+type GrantModel struct {
+	ID         uint              `gorm:"primaryKey"`
+	Role       string            `gorm:"size:64;index"`
+	Permission string            `gorm:"size:128"`
+	When       map[string]string `gorm:"serializer:json"`
+}
+
+func (GrantModel) TableName() string {
+	return "authz_grants"
+}
+
+// MigratePolicy creates or migrates the authz_roles and authz_grants tables, call it once at startup
+func MigratePolicy(db *gorm.DB) error {
+	return db.AutoMigrate(&RoleModel{}, &GrantModel{})
+}
+
+// LoadPolicy loads the policy from the authz_roles and authz_grants tables, it only reads, see MigratePolicy
+func LoadPolicy(ctx context.Context, db *gorm.DB) (Policy, error) {
+	var roles []RoleModel
+	if err := db.WithContext(ctx).Find(&roles).Error; err != nil {
+		return Policy{}, err
+	}
+	var grants []GrantModel
+	if err := db.WithContext(ctx).Order("id").Find(&grants).Error; err != nil {
+		return Policy{}, err
+	}
+
+	policy := Policy{Roles: make(map[string]Role, len(roles))}
+	for _, role := range roles {
+		policy.Roles[role.Name] = Role{Inherits: role.Inherits}
+	}
+	for _, grant := range grants {
+		role := policy.Roles[grant.Role]
+		if len(grant.When) == 0 {
+			role.Permissions = append(role.Permissions, grant.Permission)
+		} else {
+			role.Grants = append(role.Grants, Grant{Permission: grant.Permission, When: grant.When})
+		}
+		policy.Roles[grant.Role] = role
+	}
+	return policy, nil
+}
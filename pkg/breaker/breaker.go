@@ -0,0 +1,331 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package breaker 提供熔断器, 依赖持续失败时快速失败, 避免继续压垮依赖
+//
+// 状态:
+//   - closed: 正常调用, 统计滑动窗口内的失败率和慢调用率, 以及连续失败次数
+//   - open: 拒绝所有调用, OpenTimeout 后进入 half-open
+//   - half-open: 允许少量试探调用, 全部成功后 closed, 任意失败重新 open
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// State is the state of a circuit breaker
+type State int32
+
+const (
+	StateClosed   State = iota // 正常
+	StateOpen                  // 熔断
+	StateHalfOpen              // 半开, 试探调用
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown(%d)", s)
+	}
+}
+
+var (
+	// ErrOpen is returned when the breaker is open
+	ErrOpen = errors.New("circuit breaker is open")
+	// ErrTooManyCalls is returned when the trial calls of the half-open breaker are in flight
+	ErrTooManyCalls = errors.New("circuit breaker is half-open, too many calls")
+)
+
+// Outcome is the result of a call reported to done
+type Outcome int
+
+const (
+	Success Outcome = iota // 成功
+	Failure                // 失败, 计入失败率和连续失败
+	Ignored                // 调用方取消, 与依赖无关: 释放试探名额, 不计入统计
+)
+
+// Config holds the thresholds of a circuit breaker, zero values use the defaults
+type Config struct {
+	Window              time.Duration // sliding window of the rates, default 10s
+	MinCalls            int           // calls in the window before the rates are checked, default 20
+	FailureRate         float64       // failure rate which opens the breaker, 0 - 1, default 0.5
+	ConsecutiveFailures int           // consecutive failures which open the breaker, default 5, -1 disables
+	SlowCall            time.Duration // calls slower than this are slow, 0 disables slow call detection
+	SlowCallRate        float64       // slow call rate which opens the breaker, 0 - 1, default 0.8
+	OpenTimeout         time.Duration // time in open before half-open, default 30s
+	HalfOpenCalls       int           // trial calls in half-open, all must succeed to close, default 3
+
+	// OnStateChange is called after every state change, the default logs it
+	OnStateChange func(name string, from State, to State)
+}
+
+const buckets = 10
+
+// bucket counts the calls of a tenth of the window
+type bucket struct {
+	start    time.Time
+	calls    int
+	failures int
+	slow     int
+}
+
+// Breaker is a circuit breaker, it is safe for concurrent use
+type Breaker struct {
+	name   string
+	config Config
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64 // incremented on every state change, results of older generations are ignored
+	openedAt    time.Time
+	buckets     [buckets]bucket
+	consecutive int
+	trials      int // trial calls started in half-open
+	successes   int // successful trial calls in half-open
+}
+
+// New creates a circuit breaker, the name is used in logs and traces
+func New(name string, config Config) *Breaker {
+	if config.Window <= 0 {
+		config.Window = 10 * time.Second
+	}
+	if config.MinCalls <= 0 {
+		config.MinCalls = 20
+	}
+	if config.FailureRate <= 0 {
+		config.FailureRate = 0.5
+	}
+	if config.ConsecutiveFailures == 0 {
+		config.ConsecutiveFailures = 5
+	}
+	if config.SlowCallRate <= 0 {
+		config.SlowCallRate = 0.8
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenCalls <= 0 {
+		config.HalfOpenCalls = 3
+	}
+	if config.OnStateChange == nil {
+		config.OnStateChange = func(name string, from State, to State) {
+			log.Printf("Circuit breaker %s: %s -> %s\n", name, from, to)
+		}
+	}
+	return &Breaker{name: name, config: config}
+}
+
+// Name returns the name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, an open breaker becomes half-open after OpenTimeout
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout(context.Background(), time.Now())
+	return b.state
+}
+
+// Allow checks whether a call is permitted. If it is, done must be called with the outcome of the call,
+// the duration between Allow and done is used for slow call detection.
+func (b *Breaker) Allow(ctx context.Context) (done func(outcome Outcome), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.checkOpenTimeout(ctx, now)
+	switch b.state {
+	case StateOpen:
+		setRejectToTrace(ctx, b.name, b.state)
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.trials >= b.config.HalfOpenCalls {
+			setRejectToTrace(ctx, b.name, b.state)
+			return nil, ErrTooManyCalls
+		}
+		b.trials++
+	}
+
+	generation := b.generation
+	return func(outcome Outcome) {
+		b.done(ctx, generation, outcome, time.Since(now))
+	}, nil
+}
+
+// Execute runs fn if the breaker allows it, errors of fn are failures except context.Canceled which is ignored
+func (b *Breaker) Execute(ctx context.Context, fn func() error) error {
+	done, err := b.Allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	switch {
+	case isCanceled(err):
+		done(Ignored)
+	case err != nil:
+		done(Failure)
+	default:
+		done(Success)
+	}
+	return err
+}
+
+func (b *Breaker) done(ctx context.Context, generation uint64, outcome Outcome, duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	now := time.Now()
+	failed := outcome == Failure
+
+	if outcome == Ignored {
+		// the trial slot is released so that another call can probe the dependency
+		if b.state == StateHalfOpen && b.trials > 0 {
+			b.trials--
+		}
+		return
+	}
+
+	if b.state == StateHalfOpen {
+		if failed {
+			b.setState(ctx, StateOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenCalls {
+			b.setState(ctx, StateClosed, now)
+		}
+		return
+	}
+
+	slow := b.config.SlowCall > 0 && duration >= b.config.SlowCall
+	current := b.bucket(now)
+	current.calls++
+	if failed {
+		current.failures++
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	if slow {
+		current.slow++
+	}
+
+	if b.config.ConsecutiveFailures > 0 && b.consecutive >= b.config.ConsecutiveFailures {
+		b.setState(ctx, StateOpen, now)
+		return
+	}
+	calls, failures, slowCalls := b.counts(now)
+	if calls < b.config.MinCalls {
+		return
+	}
+	if float64(failures)/float64(calls) >= b.config.FailureRate ||
+		(b.config.SlowCall > 0 && float64(slowCalls)/float64(calls) >= b.config.SlowCallRate) {
+		b.setState(ctx, StateOpen, now)
+	}
+}
+
+// checkOpenTimeout moves an open breaker to half-open after OpenTimeout, must be called with b.mu held
+func (b *Breaker) checkOpenTimeout(ctx context.Context, now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(ctx, StateHalfOpen, now)
+	}
+}
+
+// setState resets the counters of the new state, must be called with b.mu held
+func (b *Breaker) setState(ctx context.Context, state State, now time.Time) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.consecutive = 0
+	b.trials = 0
+	b.successes = 0
+	b.buckets = [buckets]bucket{}
+	if state == StateOpen {
+		b.openedAt = now
+	}
+	setStateToTrace(ctx, b.name, from, state)
+	// the callback may be slow or call the breaker, do not hold the lock
+	go b.config.OnStateChange(b.name, from, state)
+}
+
+// bucket returns the bucket of now, stale buckets are reset
+func (b *Breaker) bucket(now time.Time) *bucket {
+	width := b.config.Window / buckets
+	start := now.Truncate(width)
+	current := &b.buckets[(start.UnixNano()/int64(width))%buckets]
+	if !current.start.Equal(start) {
+		*current = bucket{start: start}
+	}
+	return current
+}
+
+// counts sums the buckets in the window
+func (b *Breaker) counts(now time.Time) (calls int, failures int, slow int) {
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.config.Window {
+			calls += bk.calls
+			failures += bk.failures
+			slow += bk.slow
+		}
+	}
+	return
+}
+
+func setStateToTrace(ctx context.Context, name string, from State, to State) {
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		span.AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+			attribute.String("circuit_breaker.name", name),
+			attribute.String("circuit_breaker.from", from.String()),
+			attribute.String("circuit_breaker.to", to.String()),
+		))
+	}
+}
+
+func setRejectToTrace(ctx context.Context, name string, state State) {
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		span.SetAttributes(
+			attribute.String("circuit_breaker.name", name),
+			attribute.String("circuit_breaker.state", state.String()),
+			attribute.Bool("circuit_breaker.rejected", true),
+		)
+	}
+}
+
+// isCanceled reports whether the call was canceled by the caller, which says nothing about the dependency
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,137 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	changes := make(chan string, 10)
+	b := New("test", Config{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         50 * time.Millisecond,
+		HalfOpenCalls:       2,
+		OnStateChange: func(name string, from State, to State) {
+			changes <- from.String() + "->" + to.String()
+		},
+	})
+	ctx := context.Background()
+	fail := func() error { return errors.New("fail") }
+	ok := func() error { return nil }
+
+	for i := 0; i < 3; i++ {
+		b.Execute(ctx, fail)
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after consecutive failures, got %s", b.State())
+	}
+	if err := b.Execute(ctx, ok); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected ErrOpen, got %v", err)
+	}
+
+	// half-open allows the trial calls only, a failed trial opens again
+	time.Sleep(60 * time.Millisecond)
+	done, err := b.Allow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Allow(ctx)
+	if _, err := b.Allow(ctx); !errors.Is(err, ErrTooManyCalls) {
+		t.Errorf("expected ErrTooManyCalls, got %v", err)
+	}
+	done(Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after failed trial, got %s", b.State())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	b.Execute(ctx, ok)
+	b.Execute(ctx, ok)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after successful trials, got %s", b.State())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	got := map[string]int{}
+	for range want {
+		select {
+		case change := <-changes:
+			got[change]++
+		case <-time.After(time.Second):
+			t.Fatal("state change not reported")
+		}
+	}
+	for _, change := range want {
+		if got[change] == 0 {
+			t.Errorf("state change %s not reported: %v", change, got)
+		}
+	}
+}
+
+func TestBreakerRates(t *testing.T) {
+	b := New("rates", Config{MinCalls: 10, FailureRate: 0.5, ConsecutiveFailures: -1, OnStateChange: func(string, State, State) {}})
+	ctx := context.Background()
+	for i := 0; i < 9; i++ {
+		done, _ := b.Allow(ctx)
+		if i%2 == 0 {
+			done(Failure)
+		} else {
+			done(Success)
+		}
+	}
+	if b.State() != StateClosed {
+		t.Fatal("rates must not be checked before MinCalls")
+	}
+	done, _ := b.Allow(ctx)
+	done(Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open at 60%% failures, got %s", b.State())
+	}
+
+	slow := New("slow", Config{MinCalls: 2, SlowCall: 10 * time.Millisecond, SlowCallRate: 1, OnStateChange: func(string, State, State) {}})
+	for i := 0; i < 2; i++ {
+		slow.Execute(ctx, func() error { time.Sleep(15 * time.Millisecond); return nil })
+	}
+	if slow.State() != StateOpen {
+		t.Fatalf("expected open on slow calls, got %s", slow.State())
+	}
+}
+
+func TestBreakerIgnored(t *testing.T) {
+	b := New("ignored", Config{ConsecutiveFailures: 1, OpenTimeout: 10 * time.Millisecond, HalfOpenCalls: 1, OnStateChange: func(string, State, State) {}})
+	ctx := context.Background()
+	b.Execute(ctx, func() error { return errors.New("fail") })
+	time.Sleep(20 * time.Millisecond)
+
+	// a canceled trial neither closes nor opens the breaker, its slot is released
+	if err := b.Execute(ctx, func() error { return context.Canceled }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open after canceled trial, got %s", b.State())
+	}
+	done, err := b.Allow(ctx)
+	if err != nil {
+		t.Fatalf("trial slot not released: %v", err)
+	}
+	done(Success)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after successful trial, got %s", b.State())
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := NewGroup("hosts", Config{ConsecutiveFailures: 1, OnStateChange: func(string, State, State) {}})
+	if g.Ejected("a") {
+		t.Error("unknown key must not be ejected")
+	}
+	g.Get("a").Execute(context.Background(), func() error { return errors.New("fail") })
+	if !g.Ejected("a") || g.Ejected("b") {
+		t.Errorf("unexpected states: %v", g.States())
+	}
+	if g.Get("a").Name() != "hosts/a" {
+		t.Errorf("unexpected name: %s", g.Get("a").Name())
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package breaker
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Group holds one breaker per key with the same config, e.g. one per host or per service instance
+type Group struct {
+	name     string
+	config   Config
+	mu       sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+// NewGroup creates a group, the breakers are named "name/key"
+func NewGroup(name string, config Config) *Group {
+	return &Group{name: name, config: config, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the breaker of the key, it is created on first use
+func (g *Group) Get(key string) *Breaker {
+	g.mu.RLock()
+	b, ok := g.breakers[key]
+	g.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok = g.breakers[key]; !ok {
+		b = New(g.name+"/"+key, g.config)
+		g.breakers[key] = b
+	}
+	return b
+}
+
+// Ejected reports whether the breaker of the key is open, keys without breaker are not ejected
+func (g *Group) Ejected(key string) bool {
+	g.mu.RLock()
+	b, ok := g.breakers[key]
+	g.mu.RUnlock()
+	return ok && b.State() == StateOpen
+}
+
+// States returns the state of every breaker in the group
+func (g *Group) States() map[string]State {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	states := make(map[string]State, len(g.breakers))
+	for key, b := range g.breakers {
+		states[key] = b.State()
+	}
+	return states
+}
+
+// Transport wraps an http.RoundTripper with one breaker per host.
+// Connection errors and 5xx responses are failures, calls to an open host fail with ErrOpen without being sent.
+func Transport(base http.RoundTripper, group *Group) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, group: group}
+}
+
+type transport struct {
+	base  http.RoundTripper
+	group *Group
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.group.Get(req.URL.Host).Allow(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	done(HTTPOutcome(resp, err))
+	return resp, err
+}
+
+// HTTPOutcome returns the outcome of an HTTP call, 5xx and transport errors are failures, client cancellation is ignored
+func HTTPOutcome(resp *http.Response, err error) Outcome {
+	switch {
+	case err != nil && isCanceled(err):
+		return Ignored
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		return Failure
+	default:
+		return Success
+	}
+}
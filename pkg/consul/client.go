@@ -109,6 +109,33 @@ func (c *ConsulClient) Discover(serviceName string) (*api.ServiceEntry, error) {
 
 }
 
+// DiscoverWithTags 发现带有所有指定标签的健康服务实例, 例如灰度发布时按版本标签选择实例
+func (c *ConsulClient) DiscoverWithTags(serviceName string, tags []string) (*api.ServiceEntry, error) {
+	if len(tags) == 0 {
+		return c.Discover(serviceName)
+	}
+	services, _, err := c.client.Health().ServiceMultipleTags(serviceName, tags, true, nil)
+	if err != nil {
+		log.Printf("Failed to discover service: %v", err)
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, fmt.Errorf("未找到服务: %s, 标签: %v", serviceName, tags)
+	}
+	// 随机选择一个服务
+	return services[rand.Intn(len(services))], nil
+}
+
+// HealthyServices 查询带有所有指定标签的健康服务实例, waitIndex 不为 0 时阻塞到实例发生变化或 5 分钟超时
+func (c *ConsulClient) HealthyServices(serviceName string, tags []string, waitIndex uint64) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	opts := &api.QueryOptions{
+		WaitIndex: waitIndex,
+		WaitTime:  time.Minute * 5,
+	}
+	return c.client.Health().ServiceMultipleTags(serviceName, tags, true, opts)
+}
+
 // 向Consul写入KV配置
 func (c *ConsulClient) PutKV(serviceName string, key string, config []byte) (*api.WriteMeta, error) {
 	// 构建完整的key，格式：services/{serviceName}/config/{key}
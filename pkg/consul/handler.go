@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"time"
 
+	"Taurus/pkg/breaker"
 	"Taurus/pkg/util"
 
 	"github.com/hashicorp/consul/api"
@@ -360,17 +361,33 @@ func watchConfig(c *ConsulClient, serviceName string, watcher ConfigWatcher) {
 	}
 }
 
-// 服务调用
+// maxEjectedSkips 服务发现时最多跳过的熔断实例数, 全部被熔断时仍然请求最后一个实例
+const maxEjectedSkips = 5
+
+var (
+	// ServiceBreakers CallService 的熔断器, 每个服务实例一个, 实例持续失败时被剔除, 恢复后重新加入
+	ServiceBreakers = breaker.NewGroup("consul", breaker.Config{})
+	serviceClient   = &http.Client{Transport: breaker.Transport(http.DefaultTransport, ServiceBreakers)}
+)
+
+// 服务调用, 熔断的实例会返回 breaker.ErrOpen
 func CallService(ServerName string, request *http.Request) (interface{}, error) {
-	// 发现服务
-	service, err := Client.Discover(ServerName)
-	if err != nil {
-		return nil, fmt.Errorf("服务发现失败: %v", err)
+	// 发现服务, 跳过被熔断的实例
+	var host string
+	for i := 0; i < maxEjectedSkips; i++ {
+		service, err := Client.Discover(ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("服务发现失败: %v", err)
+		}
+		host = fmt.Sprintf("%s:%d", service.Service.Address, service.Service.Port)
+		if !ServiceBreakers.Ejected(host) {
+			break
+		}
 	}
 
 	// 构建请求
-	request.URL.Host = fmt.Sprintf("%s:%d", service.Service.Address, service.Service.Port)
-	response, err := http.DefaultClient.Do(request)
+	request.URL.Host = host
+	response, err := serviceClient.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %v", err)
 	}
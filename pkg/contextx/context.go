@@ -24,8 +24,9 @@ import (
 )
 
 type RequestContext struct {
-	TraceID string
-	AtTime  time.Time
+	TraceID  string
+	AtTime   time.Time
+	ClientIP string // 经过可信代理解析后的客户端 IP, 由 realip 中间件写入
 	// Future fields for statistics or other metadata
 	// UserID string
 }
@@ -60,3 +61,25 @@ func GetValidateRequest(ctx context.Context) (interface{}, bool) {
 	validateRequest, ok := ctx.Value(validateKey).(interface{})
 	return validateRequest, ok
 }
+
+// ApiKeyIdentity is the identity of the API key of a request, the secret is never kept
+type ApiKeyIdentity struct {
+	ID     string
+	Name   string
+	Scopes []string
+}
+
+type apiKeyContextKey string
+
+const apiKeyKey apiKeyContextKey = "api_key_identity"
+
+// WithApiKey adds the API key identity to the context
+func WithApiKey(ctx context.Context, identity *ApiKeyIdentity) context.Context {
+	return context.WithValue(ctx, apiKeyKey, identity)
+}
+
+// GetApiKey retrieves the API key identity from the context
+func GetApiKey(ctx context.Context) (*ApiKeyIdentity, bool) {
+	identity, ok := ctx.Value(apiKeyKey).(*ApiKeyIdentity)
+	return identity, ok
+}
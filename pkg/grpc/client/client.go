@@ -19,7 +19,10 @@
 package client
 
 import (
+	"Taurus/pkg/breaker"
 	"Taurus/pkg/grpc/attributes"
+	"Taurus/pkg/grpc/client/interceptor"
+	"fmt"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -88,14 +91,22 @@ func (c *GrpcClient) getDialOptions() []grpc.DialOption {
 		opts = append(opts, grpc.WithKeepaliveParams(*c.opts.KeepAlive))
 	}
 
+	// 熔断拦截器在最外层, 重试等拦截器的多次调用只统计一次结果
+	unaryInterceptors := c.opts.UnaryInterceptors
+	streamInterceptors := c.opts.StreamInterceptors
+	if c.opts.Breakers != nil {
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{interceptor.CircuitBreakerClientInterceptor(c.opts.Breakers)}, unaryInterceptors...)
+		streamInterceptors = append([]grpc.StreamClientInterceptor{interceptor.StreamCircuitBreakerClientInterceptor(c.opts.Breakers)}, streamInterceptors...)
+	}
+
 	// 一元拦截器
-	if len(c.opts.UnaryInterceptors) > 0 {
-		opts = append(opts, grpc.WithUnaryInterceptor(attributes.ChainUnaryClient(c.opts.UnaryInterceptors...)))
+	if len(unaryInterceptors) > 0 {
+		opts = append(opts, grpc.WithUnaryInterceptor(attributes.ChainUnaryClient(unaryInterceptors...)))
 	}
 
 	// 流式拦截器
-	if len(c.opts.StreamInterceptors) > 0 {
-		opts = append(opts, grpc.WithStreamInterceptor(attributes.ChainStreamClient(c.opts.StreamInterceptors...)))
+	if len(streamInterceptors) > 0 {
+		opts = append(opts, grpc.WithStreamInterceptor(attributes.ChainStreamClient(streamInterceptors...)))
 	}
 
 	return opts
@@ -103,6 +114,10 @@ func (c *GrpcClient) getDialOptions() []grpc.DialOption {
 
 // GetConn 获取连接，由调用者指定是否为流式连接
 func (c *GrpcClient) GetConn(address string, isStream bool) (*grpc.ClientConn, error) {
+	// 熔断期间不再创建连接, 调用方可以选择其他地址
+	if c.opts.Breakers != nil && c.opts.Breakers.Ejected(address) {
+		return nil, fmt.Errorf("%s: %w", address, breaker.ErrOpen)
+	}
 	return c.pool.GetConn(address, isStream, c.getDialOptions()...)
 }
 
@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package interceptor
+
+import (
+	"Taurus/pkg/breaker"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CircuitBreakerClientInterceptor 熔断拦截器, 每个目标地址一个熔断器, 熔断时直接返回 codes.Unavailable
+func CircuitBreakerClientInterceptor(group *breaker.Group) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := group.Get(cc.Target()).Allow(ctx)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "%s: %v", cc.Target(), err)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(BreakerOutcome(err))
+		return err
+	}
+}
+
+// StreamCircuitBreakerClientInterceptor 流式熔断拦截器, 只统计建立流的结果
+func StreamCircuitBreakerClientInterceptor(group *breaker.Group) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		done, err := group.Get(cc.Target()).Allow(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "%s: %v", cc.Target(), err)
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		done(BreakerOutcome(err))
+		return stream, err
+	}
+}
+
+// BreakerOutcome 返回调用结果, 调用方取消不计入熔断统计
+func BreakerOutcome(err error) breaker.Outcome {
+	switch {
+	case status.Code(err) == codes.Canceled || errors.Is(err, context.Canceled):
+		return breaker.Ignored
+	case IsBreakerFailure(err):
+		return breaker.Failure
+	default:
+		return breaker.Success
+	}
+}
+
+// IsBreakerFailure 判断错误是否是依赖的故障, 业务错误(参数错误, 未找到等)不算失败
+func IsBreakerFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
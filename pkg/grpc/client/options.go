@@ -19,6 +19,7 @@
 package client
 
 import (
+	"Taurus/pkg/breaker"
 	"crypto/tls"
 	"time"
 
@@ -65,6 +66,7 @@ type ClientOptions struct {
 	KeepAlive          *keepalive.ClientParameters    // 保活配置
 	UnaryInterceptors  []grpc.UnaryClientInterceptor  // 一元拦截器
 	StreamInterceptors []grpc.StreamClientInterceptor // 流式拦截器
+	Breakers           *breaker.Group                 // 熔断器, 每个地址一个, 熔断时 GetConn 直接返回错误
 }
 
 // DefaultClientOptions 返回默认配置
@@ -147,3 +149,10 @@ func WithStreamInterceptor(interceptor grpc.StreamClientInterceptor) ClientOptio
 		o.StreamInterceptors = append(o.StreamInterceptors, interceptor)
 	}
 }
+
+// WithCircuitBreaker 设置熔断器, 每个地址一个熔断器, 熔断期间不再创建连接和发起调用
+func WithCircuitBreaker(config breaker.Config) ClientOption {
+	return func(o *ClientOptions) {
+		o.Breakers = breaker.NewGroup("grpc", config)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"Taurus/pkg/apikey"
+	"Taurus/pkg/authz"
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/jwtx"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthzResource is implemented by request messages carrying the attributes of the resource, e.g. the tenant of the order
+type AuthzResource interface {
+	AuthzResource() authz.Attributes
+}
+
+// AuthzServerInterceptor 授权拦截器, methods 为完整方法名到权限的映射, 例如 {"/order.OrderService/GetOrder": "orders:read"},
+// "/order.OrderService/*" 匹配服务的所有方法, 未配置的方法不校验权限.
+// 主体来自 metadata 中的 authorization: Bearer <jwt> 或 x-api-key, 校验后的 claims 会写入 context
+func AuthzServerInterceptor(methods map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		permission, ok := methodPermission(methods, info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		var resource authz.Attributes
+		if r, ok := req.(AuthzResource); ok {
+			resource = r.AuthzResource()
+		}
+		ctx, err := authorize(ctx, info.FullMethod, permission, resource)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthzStreamServerInterceptor 流式授权拦截器, 与 AuthzServerInterceptor 相同, 在建立流时校验一次
+func AuthzStreamServerInterceptor(methods map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		permission, ok := methodPermission(methods, info.FullMethod)
+		if !ok {
+			return handler(srv, stream)
+		}
+		ctx, err := authorize(stream.Context(), info.FullMethod, permission, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authzServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authzServerStream carries the context with the verified identity
+type authzServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authzServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func methodPermission(methods map[string]string, fullMethod string) (string, bool) {
+	if permission, ok := methods[fullMethod]; ok {
+		return permission, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		permission, ok := methods[fullMethod[:i]+"/*"]
+		return permission, ok
+	}
+	return "", false
+}
+
+func authorize(ctx context.Context, fullMethod string, permission string, resource authz.Attributes) (context.Context, error) {
+	ctx, err := authenticate(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	subject, ok := authz.SubjectFromContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if authz.Default == nil {
+		return ctx, status.Error(codes.PermissionDenied, "permission denied: authz is not configured")
+	}
+	decision := authz.Default.Authorize(ctx, authz.Request{Subject: subject, Permission: permission, Resource: resource, Target: fullMethod})
+	if !decision.Allowed {
+		return ctx, status.Errorf(codes.PermissionDenied, "permission denied: %s", permission)
+	}
+	return ctx, nil
+}
+
+// authenticate verifies the jwt or api key of the metadata, unless the context already has an identity
+func authenticate(ctx context.Context) (context.Context, error) {
+	if _, ok := authz.SubjectFromContext(ctx); ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 && apikey.Default != nil {
+		key, err := apikey.Default.Verify(ctx, keys[0])
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		return contextx.WithApiKey(ctx, &contextx.ApiKeyIdentity{ID: key.ID, Name: key.Name, Scopes: key.Scopes}), nil
+	}
+	if values := md.Get("authorization"); len(values) > 0 && jwtx.Default != nil {
+		token := jwtx.BearerToken(values[0])
+		if token == "" {
+			return ctx, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		claims, err := jwtx.Default.Verify(ctx, token)
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return jwtx.WithClaims(ctx, claims), nil
+	}
+	return ctx, status.Error(codes.Unauthenticated, "missing credentials")
+}
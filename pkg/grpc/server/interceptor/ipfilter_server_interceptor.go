@@ -0,0 +1,64 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package interceptor
+
+import (
+	"context"
+
+	"Taurus/pkg/ipfilter"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IPFilterServerInterceptor 按命名的 IP 列表检查对端 IP, 拒绝时返回 PermissionDenied, 列表通过 ipfilter.Set 热更新
+func IPFilterServerInterceptor(list string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := CheckIPFilter(ctx, list); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// IPFilterStreamServerInterceptor 流式请求的 IP 过滤拦截器
+func IPFilterStreamServerInterceptor(list string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := CheckIPFilter(stream.Context(), list); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// CheckHost returns PermissionDenied when the peer ip is denied by ipfilter.DefaultList, an undefined list denies all ips
+func CheckHost(ctx context.Context) error {
+	if ip := peerIP(ctx); !ipfilter.HostAllowed(ip) {
+		return status.Errorf(codes.PermissionDenied, "access denied for ip %s", ip)
+	}
+	return nil
+}
+
+// CheckIPFilter returns PermissionDenied when the peer ip is denied by the named list
+func CheckIPFilter(ctx context.Context, list string) error {
+	if ip := peerIP(ctx); !ipfilter.Allowed(list, ip) {
+		return status.Errorf(codes.PermissionDenied, "access denied for ip %s", ip)
+	}
+	return nil
+}
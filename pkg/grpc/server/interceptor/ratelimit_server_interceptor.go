@@ -19,20 +19,99 @@ package interceptor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/jwtx"
+	"Taurus/pkg/ratelimit"
 
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
-// RateLimitServerInterceptor 限流拦截器
-func RateLimitServerInterceptor(limit int) grpc.UnaryServerInterceptor {
-	limiter := rate.NewLimiter(rate.Limit(limit), limit)
+// RateLimitServerInterceptor 限流拦截器, keys 为限流 key 的组成: ip, api_key, user, method, metadata:<name>, 默认 [method, ip].
+// 响应 header 中带有 ratelimit-* 信息, 超限时返回 ResourceExhausted 和 retry-after
+func RateLimitServerInterceptor(limiter *ratelimit.Limiter, keys ...string) grpc.UnaryServerInterceptor {
+	keys = rateLimitKeys(keys)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if !limiter.Allow() {
-			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		result := limiter.Allow(ctx, rateLimitKey(ctx, info.FullMethod, keys))
+		_ = grpc.SetHeader(ctx, metadata.New(result.Headers()))
+		if !result.Allowed {
+			return nil, rateLimitExceeded(result)
 		}
 		return handler(ctx, req)
 	}
 }
+
+// RateLimitStreamServerInterceptor 流式限流拦截器, 在建立流时消耗一次配额
+func RateLimitStreamServerInterceptor(limiter *ratelimit.Limiter, keys ...string) grpc.StreamServerInterceptor {
+	keys = rateLimitKeys(keys)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		result := limiter.Allow(stream.Context(), rateLimitKey(stream.Context(), info.FullMethod, keys))
+		_ = stream.SetHeader(metadata.New(result.Headers()))
+		if !result.Allowed {
+			return rateLimitExceeded(result)
+		}
+		return handler(srv, stream)
+	}
+}
+
+func rateLimitKeys(keys []string) []string {
+	if len(keys) == 0 {
+		return []string{"method", "ip"}
+	}
+	return keys
+}
+
+func rateLimitExceeded(result ratelimit.Result) error {
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", result.Headers()["Retry-After"])
+}
+
+// rateLimitKey 与 HTTP 限流中间件的 key 规则相同, api_key 和 user 缺失时使用 ip
+func rateLimitKey(ctx context.Context, fullMethod string, keys []string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = rateLimitKeyPart(ctx, md, fullMethod, key)
+	}
+	return strings.Join(values, ":")
+}
+
+func rateLimitKeyPart(ctx context.Context, md metadata.MD, fullMethod string, key string) string {
+	switch kind, name, _ := strings.Cut(key, ":"); kind {
+	case "method":
+		return "method=" + fullMethod
+	case "api_key":
+		if identity, ok := contextx.GetApiKey(ctx); ok {
+			return "key=" + identity.ID
+		}
+		if keys := md.Get("x-api-key"); len(keys) > 0 {
+			sum := sha256.Sum256([]byte(keys[0]))
+			return "key=" + hex.EncodeToString(sum[:8])
+		}
+	case "user":
+		if claims, ok := jwtx.GetClaims(ctx); ok && claims.Subject != "" {
+			return "user=" + claims.Subject
+		}
+	case "metadata":
+		return name + "=" + strings.Join(md.Get(name), ",")
+	}
+	return "ip=" + peerIP(ctx)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
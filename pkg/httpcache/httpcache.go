@@ -0,0 +1,95 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+// Package httpcache 保存可缓存的 HTTP 响应, 支持按标签失效.
+//
+// handler 通过 Tag 给当前请求的响应打标签, 数据更新后通过 Invalidate 删除带有该标签的所有缓存, 例如:
+//
+//	httpcache.Tag(r, "order:"+id)
+//	httpcache.Invalidate(ctx, "order:"+id)
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Entry 缓存的响应
+type Entry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	ETag     string      `json:"etag"`
+	Tags     []string    `json:"tags,omitempty"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// Store 响应缓存的存储
+type Store interface {
+	// Get returns the entry of the key, nil when the key is not cached
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Set stores the entry for ttl and indexes it by its tags
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+	// Invalidate deletes the entries with any of the tags
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Default is the store used by the cache middleware and Invalidate
+var Default Store = NewMemoryStore(1000)
+
+// Invalidate deletes the entries of Default with any of the tags, e.g. after an order is updated
+func Invalidate(ctx context.Context, tags ...string) error {
+	if Default == nil || len(tags) == 0 {
+		return nil
+	}
+	return Default.Invalidate(ctx, tags...)
+}
+
+type tagsKey struct{}
+
+// tagSet 当前请求的标签, 由 cache 中间件放入请求上下文
+type tagSet struct {
+	mu   sync.Mutex
+	tags []string
+}
+
+// WithTags returns a context collecting the tags added by Tag, used by the cache middleware
+func WithTags(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tagsKey{}, &tagSet{})
+}
+
+// Tag adds tags to the cached response of the request, it is a no-op for requests not handled by the cache middleware
+func Tag(r *http.Request, tags ...string) {
+	if set, ok := r.Context().Value(tagsKey{}).(*tagSet); ok {
+		set.mu.Lock()
+		set.tags = append(set.tags, tags...)
+		set.mu.Unlock()
+	}
+}
+
+// Tags returns the tags added to the request context
+func Tags(ctx context.Context) []string {
+	set, ok := ctx.Value(tagsKey{}).(*tagSet)
+	if !ok {
+		return nil
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	return append([]string(nil), set.tags...)
+}
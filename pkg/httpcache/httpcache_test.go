@@ -0,0 +1,53 @@
+package httpcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	store.Set(ctx, "a", &Entry{Body: []byte("a")}, time.Minute)
+	store.Set(ctx, "b", &Entry{Body: []byte("b")}, time.Minute)
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", &Entry{Body: []byte("c")}, time.Minute)
+
+	if entry, _ := store.Get(ctx, "b"); entry != nil {
+		t.Error("expected b evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if entry, _ := store.Get(ctx, key); entry == nil || string(entry.Body) != key {
+			t.Errorf("expected %s cached, got %v", key, entry)
+		}
+	}
+
+	now := time.Now()
+	store.now = func() time.Time { return now.Add(time.Minute) }
+	if entry, _ := store.Get(ctx, "a"); entry != nil || store.Len() != 1 {
+		t.Errorf("expected expired entry removed, got %v, len %d", entry, store.Len())
+	}
+}
+
+func TestMemoryStoreInvalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	store.Set(ctx, "order", &Entry{Tags: []string{"order:1"}}, time.Minute)
+	store.Set(ctx, "orders", &Entry{Tags: []string{"order:1", "orders"}}, time.Minute)
+	store.Set(ctx, "order.xml", &Entry{Tags: []string{"order:1"}}, time.Minute)
+	store.Set(ctx, "order.csv", &Entry{Tags: []string{"order:1"}}, time.Minute)
+	store.Set(ctx, "other", &Entry{Tags: []string{"order:2"}}, time.Minute)
+
+	if err := store.Invalidate(ctx, "order:1"); err != nil {
+		t.Fatal(err)
+	}
+	for key, cached := range map[string]bool{"order": false, "orders": false, "order.xml": false, "order.csv": false, "other": true} {
+		if entry, _ := store.Get(ctx, key); (entry != nil) != cached {
+			t.Errorf("expected %s cached %v", key, cached)
+		}
+	}
+	if len(store.tags) != 1 {
+		t.Errorf("expected only the tag of other indexed, got %v", store.tags)
+	}
+}
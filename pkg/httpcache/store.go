@@ -0,0 +1,221 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpcache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryStore 进程内的 LRU 缓存, 超过容量时淘汰最久未使用的响应
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List          // 最近使用的在前
+	tags     map[string][]string // 标签到 key 的索引, 失效时删除
+	now      func() time.Time
+}
+
+type memoryItem struct {
+	key     string
+	entry   *Entry
+	expires time.Time
+}
+
+// NewMemoryStore creates a LRU store of capacity entries, default 1000
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		tags:     make(map[string][]string),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := element.Value.(*memoryItem)
+	if !s.now().Before(item.expires) {
+		s.remove(element)
+		return nil, nil
+	}
+	s.order.MoveToFront(element)
+	return item.entry, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.items[key]; ok {
+		s.remove(element)
+	}
+	s.items[key] = s.order.PushFront(&memoryItem{key: key, entry: entry, expires: s.now().Add(ttl)})
+	for _, tag := range entry.Tags {
+		s.tags[tag] = append(s.tags[tag], key)
+	}
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) Invalidate(_ context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		// remove 会修改 s.tags[tag], 遍历副本
+		for _, key := range slices.Clone(s.tags[tag]) {
+			if element, ok := s.items[key]; ok {
+				s.remove(element)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// remove deletes the element and its tag index
+func (s *MemoryStore) remove(element *list.Element) {
+	item := element.Value.(*memoryItem)
+	s.order.Remove(element)
+	delete(s.items, item.key)
+	for _, tag := range item.entry.Tags {
+		keys := s.tags[tag]
+		for i, key := range keys {
+			if key == item.key {
+				keys = append(keys[:i], keys[i+1:]...)
+				break
+			}
+		}
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		} else {
+			s.tags[tag] = keys
+		}
+	}
+}
+
+// Len returns the number of cached entries
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+var (
+	// tagScript 把响应的 key 加入标签集合, 标签集合的过期时间不短于响应
+	tagScript = redis.NewScript(`
+redis.call('SADD', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+	// popTagScript 原子地取出并删除标签集合, 返回其中的响应 key
+	popTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`)
+)
+
+// RedisStore 使用 Redis 存储, 多个副本共享缓存. 只有标签集合使用 hash tag, 响应分散在集群的各个 slot 中,
+// 失效时逐个删除标签集合中的响应. 失效和保存同时发生时, 刚保存的响应可能保留到过期
+type RedisStore struct {
+	client *redisx.RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a redis store, entries are <prefix>:<key> and tags <prefix>:tag:{<tag>}, default prefix httpcache
+func NewRedisStore(client *redisx.RedisClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "httpcache"
+	}
+	return &RedisStore{client: client, prefix: prefix + ":"}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	value, err := s.client.Get(ctx, s.prefix+key)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Set 先加入标签集合再保存响应, 标签写入失败时不保存, 避免保存无法失效的响应
+func (s *RedisStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	for _, tag := range entry.Tags {
+		if _, err := s.client.RunScript(ctx, tagScript, []string{s.tagKey(tag)}, s.prefix+key, ttl.Milliseconds()); err != nil {
+			return err
+		}
+	}
+	return s.client.Set(ctx, s.prefix+key, value, ttl)
+}
+
+func (s *RedisStore) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		result, err := s.client.RunScript(ctx, popTagScript, []string{s.tagKey(tag)})
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		keys, _ := result.([]interface{})
+		for _, key := range keys {
+			if key, ok := key.(string); ok {
+				if err := s.client.Del(ctx, key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tagKey 标签集合的 key, 每个标签位于自己的 slot
+func (s *RedisStore) tagKey(tag string) string {
+	return s.prefix + "tag:{" + tag + "}"
+}
@@ -0,0 +1,418 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Taurus/pkg/validate"
+)
+
+var (
+	// ErrInvalidRequest 请求参数无法绑定, 每个参数的错误在 Details 中
+	ErrInvalidRequest = NewError(http.StatusBadRequest, "request.invalid", "Invalid request")
+	// ErrValidationFailed 请求校验失败, 字段错误在 Details 中
+	ErrValidationFailed = NewError(http.StatusBadRequest, "validation.failed", "Validation failed")
+)
+
+// bindSources 绑定的来源, 按优先级从低到高, 后面的来源覆盖前面的
+var bindSources = []string{"form", "query", "header", "cookie", "path"}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// bindField 绑定到一个字段的参数
+type bindField struct {
+	index  []int
+	source string
+	name   string
+	file   bool // form 的 *multipart.FileHeader 或 []*multipart.FileHeader 字段
+}
+
+// bindPlan 结构体的绑定计划, 按类型缓存
+type bindPlan struct {
+	fields   []bindField // 带有来源 tag 的字段
+	fallback []bindField // 只有 json tag 的顶层字段, 兼容从 query 和表单按 json 名称绑定
+	err      error
+}
+
+var bindPlans sync.Map // reflect.Type -> *bindPlan
+
+// Bind binds the request into a new T and validates it with validate.Core, see BindInto
+func Bind[T any](r *http.Request) (*T, error) {
+	dst := new(T)
+	if err := BindInto(r, dst); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// BindInto binds the request into dst, a pointer to struct, and validates it with validate.Core.
+//
+// 字段通过 tag 指定来源: path:"id"、query:"page"、header:"X-Tenant"、cookie:"session"、form:"name",
+// form 的 *multipart.FileHeader 和 []*multipart.FileHeader 字段绑定上传的文件. JSON 和 XML 请求体按 json/xml tag 解码到结构体.
+// 优先级从低到高: 只有 json tag 的顶层字段从 query/表单按 json 名称绑定、请求体、form、query、header、cookie、path.
+// 嵌套结构体(包括指针)的字段会被展开, 结构体字段本身的 tag 作为其字段名称的前缀, 例如 query:"filter" 下的 query:"page" 绑定 filter.page.
+// 切片绑定所有的值, 支持 encoding.TextUnmarshaler(例如 time.Time)和 time.Duration.
+// 参数错误返回带有 Details 的 ErrInvalidRequest, 校验错误返回 ErrValidationFailed, 请求体超过限制时返回 *http.MaxBytesError
+func BindInto(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return Wrap(fmt.Errorf("bind target must be a non-nil pointer to struct, got %T", dst), http.StatusInternalServerError, "internal", "Internal Server Error")
+	}
+	plan := planOf(v.Elem().Type())
+	if plan.err != nil {
+		return Wrap(plan.err, http.StatusInternalServerError, "internal", "Internal Server Error")
+	}
+
+	details := make(map[string]string)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isForm := mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+	if isForm {
+		var err error
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(MaxMultipartMemory)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return err
+		}
+		if err != nil {
+			details["body"] = err.Error()
+		}
+	}
+
+	query := r.URL.Query()
+	for _, field := range plan.fallback {
+		values := query[field.name]
+		if isForm && len(r.PostForm[field.name]) > 0 {
+			values = r.PostForm[field.name]
+		}
+		bindValues(v.Elem(), field, values, details)
+	}
+
+	if !isForm && r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 &&
+		(mediaType == "application/json" || mediaType == "application/xml" || mediaType == "text/xml") {
+		if err := decodeBody(r, mediaType, dst); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return err
+			}
+			details["body"] = err.Error()
+		}
+	}
+
+	for _, field := range plan.fields {
+		switch field.source {
+		case "path":
+			if value := r.PathValue(field.name); value != "" {
+				bindValues(v.Elem(), field, []string{value}, details)
+			}
+		case "query":
+			bindValues(v.Elem(), field, query[field.name], details)
+		case "header":
+			bindValues(v.Elem(), field, r.Header.Values(field.name), details)
+		case "cookie":
+			if cookie, err := r.Cookie(field.name); err == nil {
+				bindValues(v.Elem(), field, []string{cookie.Value}, details)
+			}
+		case "form":
+			if field.file {
+				if files := multipartFiles(r, field.name); len(files) > 0 {
+					bindFiles(fieldByIndex(v.Elem(), field.index), files)
+				}
+				continue
+			}
+			bindValues(v.Elem(), field, r.PostForm[field.name], details)
+		}
+	}
+	if len(details) > 0 {
+		return ErrInvalidRequest.WithDetails(details)
+	}
+
+	validationErrors, err := validate.Core.ValidateStruct(dst)
+	if err != nil {
+		return Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error")
+	}
+	if len(validationErrors) > 0 {
+		return ErrValidationFailed.WithDetails(validate.GetFieldErrors(validationErrors))
+	}
+	return nil
+}
+
+// decodeBody 解码请求体, 解码后恢复请求体供后续的 handler 读取
+func decodeBody(r *http.Request, mediaType string, dst any) error {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return err
+	}
+	if mediaType == "application/json" {
+		return json.Unmarshal(body, dst)
+	}
+	return xml.Unmarshal(body, dst)
+}
+
+func multipartFiles(r *http.Request, name string) []*multipart.FileHeader {
+	if r.MultipartForm == nil {
+		return nil
+	}
+	return r.MultipartForm.File[name]
+}
+
+func bindFiles(v reflect.Value, files []*multipart.FileHeader) {
+	if v.Type() == fileHeaderType {
+		v.Set(reflect.ValueOf(files[0]))
+	} else {
+		v.Set(reflect.ValueOf(files))
+	}
+}
+
+// bindValues 设置字段的值, 没有值时保留字段原来的值
+func bindValues(root reflect.Value, field bindField, values []string, details map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	if err := setValues(fieldByIndex(root, field.index), values); err != nil {
+		details[field.name] = err.Error()
+	}
+}
+
+// fieldByIndex 与 reflect.Value.FieldByIndex 相同, 但会创建为 nil 的嵌套结构体指针
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func setValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValues(v.Elem(), values)
+	}
+	if v.Kind() == reflect.Slice && !isTextUnmarshaler(v.Type()) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setString(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setString(v, values[0])
+}
+
+func setString(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setString(v.Elem(), s)
+	}
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func isTextUnmarshaler(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderType || t == fileHeadersType
+}
+
+// bindable 字段可以从字符串绑定: 基本类型、TextUnmarshaler 以及它们的指针和切片
+func bindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isTextUnmarshaler(t) {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if isTextUnmarshaler(t) {
+			return true
+		}
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isNested 需要展开的嵌套结构体
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isTextUnmarshaler(t)
+}
+
+func planOf(t reflect.Type) *bindPlan {
+	if plan, ok := bindPlans.Load(t); ok {
+		return plan.(*bindPlan)
+	}
+	plan := &bindPlan{}
+	plan.err = plan.build(t, nil, map[string]string{}, map[reflect.Type]bool{t: true})
+	actual, _ := bindPlans.LoadOrStore(t, plan)
+	return actual.(*bindPlan)
+}
+
+func (p *bindPlan) build(t reflect.Type, index []int, prefixes map[string]string, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// 未导出的嵌入结构体(非指针)的导出字段仍然可以设置
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct && isNested(field.Type)) {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		names := make(map[string]string)
+		for _, source := range bindSources {
+			if tag, ok := field.Tag.Lookup(source); ok {
+				if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+					names[source] = name
+				}
+			}
+		}
+
+		if isNested(field.Type) && !isFileType(field.Type) {
+			nested := field.Type
+			if nested.Kind() == reflect.Pointer {
+				nested = nested.Elem()
+			}
+			if visiting[nested] {
+				continue // 递归的类型只展开一次
+			}
+			childPrefixes := make(map[string]string, len(prefixes))
+			for source, prefix := range prefixes {
+				childPrefixes[source] = prefix
+			}
+			for source, name := range names {
+				childPrefixes[source] = prefixes[source] + name + "."
+			}
+			visiting[nested] = true
+			err := p.build(nested, fieldIndex, childPrefixes, visiting)
+			delete(visiting, nested)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, source := range bindSources {
+			name, ok := names[source]
+			if !ok {
+				continue
+			}
+			if !bindable(field.Type) && !(source == "form" && isFileType(field.Type)) {
+				return fmt.Errorf("field %s of %s: unsupported type %s for %s binding", field.Name, t, field.Type, source)
+			}
+			p.fields = append(p.fields, bindField{index: fieldIndex, source: source, name: prefixes[source] + name, file: isFileType(field.Type)})
+		}
+
+		if len(names) == 0 && len(index) == 0 && bindable(field.Type) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			p.fallback = append(p.fallback, bindField{index: fieldIndex, name: name})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindFilter struct {
+	Page  int      `query:"page"`
+	Tags  []string `query:"tag"`
+	Since *time.Time
+}
+
+type bindAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type bindRequest struct {
+	ID      int64         `path:"id"`
+	Tenant  string        `header:"X-Tenant"`
+	Session string        `cookie:"session"`
+	Filter  bindFilter    `query:"filter"`
+	Timeout time.Duration `query:"timeout"`
+	At      time.Time     `query:"at"`
+	Limit   *int          `query:"limit"`
+	Name    string        `json:"name" validate:"required"`
+	Address *bindAddress  `json:"address"`
+	Items   []struct {
+		SKU string `json:"sku"`
+	} `json:"items"`
+	Lang string `json:"lang"`
+}
+
+func serveBind[T any](t *testing.T, pattern string, req *http.Request) (*T, error) {
+	t.Helper()
+	var (
+		result *T
+		err    error
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		result, err = Bind[T](r)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	return result, err
+}
+
+func TestBind(t *testing.T) {
+	query := "filter.page=2&filter.tag=a&filter.tag=b&timeout=1s&at=2025-06-13T08:00:00Z&limit=5&lang=zh&name=ignored"
+	body := `{"name":"taurus","address":{"city":"Hangzhou"},"items":[{"sku":"s1"},{"sku":"s2"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/orders/42?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	got, err := serveBind[bindRequest](t, "POST /orders/{id}", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 42 || got.Tenant != "acme" || got.Session != "s1" || got.Lang != "zh" || got.Name != "taurus" {
+		t.Errorf("unexpected scalars %+v", got)
+	}
+	if got.Filter.Page != 2 || len(got.Filter.Tags) != 2 || got.Filter.Tags[1] != "b" || got.Filter.Since != nil {
+		t.Errorf("unexpected nested filter %+v", got.Filter)
+	}
+	if got.Timeout != time.Second || got.At.Hour() != 8 || got.Limit == nil || *got.Limit != 5 {
+		t.Errorf("unexpected converted values %+v", got)
+	}
+	if got.Address == nil || got.Address.City != "Hangzhou" || len(got.Items) != 2 || got.Items[1].SKU != "s2" {
+		t.Errorf("unexpected body %+v", got)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/orders/x?filter.page=two&name=n", nil)
+	_, err := serveBind[bindRequest](t, "GET /orders/{id}", req)
+	var e *Error
+	if !errors.Is(err, ErrInvalidRequest) || !errors.As(err, &e) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+	if details := e.Details.(map[string]string); details["id"] == "" || details["filter.page"] == "" {
+		t.Errorf("expected errors of id and filter.page, got %v", details)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	if _, err := serveBind[bindRequest](t, "GET /orders/{id}", req); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("expected validation failed, got %v", err)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("title", "report")
+	part, _ := writer.CreateFormFile("files", "a.txt")
+	part.Write([]byte("a"))
+	part, _ = writer.CreateFormFile("files", "b.txt")
+	part.Write([]byte("b"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	got, err := serveBind[struct {
+		Title string                  `form:"title"`
+		First *multipart.FileHeader   `form:"files"`
+		Files []*multipart.FileHeader `form:"files"`
+	}](t, "POST /upload", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "report" || got.First.Filename != "a.txt" || len(got.Files) != 2 {
+		t.Errorf("unexpected multipart binding %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(url.Values{"title": {"form"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if got, err := serveBind[struct {
+		Title string `form:"title"`
+	}](t, "POST /upload", req); err != nil || got.Title != "form" {
+		t.Errorf("unexpected form binding %+v %v", got, err)
+	}
+}
+
+func TestBindUnsupportedType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, err := serveBind[struct {
+		Values map[string]string `query:"values"`
+	}](t, "GET /", req)
+	if e := AsError(err); e.Status != http.StatusInternalServerError {
+		t.Errorf("expected unsupported field type to be an internal error, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// isTabular CSV 支持的数据: [][]string, 结构体(或结构体指针)切片, map 切片
+func isTabular(data any) bool {
+	if _, ok := data.([][]string); ok {
+		return true
+	}
+	t := reflect.TypeOf(data)
+	if t == nil || t.Kind() != reflect.Slice {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return (elem.Kind() == reflect.Struct && !elem.Implements(textMarshalerType) && !reflect.PointerTo(elem).Implements(textMarshalerType)) ||
+		(elem.Kind() == reflect.Map && elem.Key().Kind() == reflect.String)
+}
+
+// encodeCSV 第一行是列名: 结构体使用 json tag 或字段名, map 使用所有 key 排序后的结果
+func encodeCSV(w io.Writer, v any) error {
+	writer := csv.NewWriter(w)
+	if rows, ok := v.([][]string); ok {
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	if !isTabular(v) {
+		return fmt.Errorf("%T is not tabular data", v)
+	}
+
+	rows := reflect.ValueOf(v)
+	elem := rows.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.Map {
+		var headers []string
+		for i := 0; i < rows.Len(); i++ {
+			for _, key := range reflect.Indirect(rows.Index(i)).MapKeys() {
+				headers = append(headers, key.String())
+			}
+		}
+		slices.Sort(headers)
+		headers = slices.Compact(headers)
+		writer.Write(headers)
+		for i := 0; i < rows.Len(); i++ {
+			row := reflect.Indirect(rows.Index(i))
+			record := make([]string, len(headers))
+			for j, header := range headers {
+				if row.IsValid() {
+					record[j] = csvValue(row.MapIndex(reflect.ValueOf(header).Convert(elem.Key())))
+				}
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	var headers []string
+	var fields []int
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		headers = append(headers, name)
+		fields = append(fields, i)
+	}
+	writer.Write(headers)
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		record := make([]string, len(fields))
+		if row.IsValid() {
+			for j, index := range fields {
+				record[j] = csvValue(row.Field(index))
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue 格式化单元格, nil 为空字符串, 优先使用 encoding.TextMarshaler(例如 time.Time)
+func csvValue(v reflect.Value) string {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := marshaler.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
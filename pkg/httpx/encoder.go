@@ -0,0 +1,264 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"Taurus/pkg/logx"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encoder 响应编码器, 通过 RegisterEncoder 注册后由 Respond 根据 Accept 选择
+type Encoder struct {
+	MediaType   string                         // 协商使用的媒体类型, 例如 application/json
+	Aliases     []string                       // 同样使用该编码器的媒体类型, 例如 text/xml
+	ContentType string                         // 响应的 Content-Type, 默认 MediaType
+	Raw         bool                           // 直接编码 data, 不使用 Response 包装, 例如 protobuf 和 CSV
+	Supports    func(data any) bool            // 是否可以编码 data, nil 表示支持所有数据
+	Encode      func(w io.Writer, v any) error // 编码响应
+}
+
+// ErrNotAcceptable 没有满足 Accept 的编码器, Details 中是可用的媒体类型
+var ErrNotAcceptable = NewError(http.StatusNotAcceptable, "request.not_acceptable", "Not Acceptable")
+
+var (
+	encodersMu sync.RWMutex
+	encoders   []*Encoder // 按服务端的优先级排列, 第一个是默认的编码器
+)
+
+func init() {
+	RegisterEncoder(Encoder{MediaType: "application/json", ContentType: "application/json;charset=utf-8", Encode: encodeJSON})
+	RegisterEncoder(Encoder{MediaType: "application/xml", Aliases: []string{"text/xml"}, ContentType: "application/xml;charset=utf-8", Encode: encodeXML})
+	RegisterEncoder(Encoder{MediaType: "application/msgpack", Aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, Encode: encodeMsgpack})
+	RegisterEncoder(Encoder{MediaType: "application/x-protobuf", Aliases: []string{"application/protobuf"}, Raw: true, Supports: isProtoMessage, Encode: encodeProtobuf})
+	RegisterEncoder(Encoder{MediaType: "text/csv", ContentType: "text/csv;charset=utf-8", Raw: true, Supports: isTabular, Encode: encodeCSV})
+}
+
+// RegisterEncoder registers an encoder, it replaces the encoder of the same media type, new media types have the lowest priority
+func RegisterEncoder(encoder Encoder) {
+	if encoder.ContentType == "" {
+		encoder.ContentType = encoder.MediaType
+	}
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i, registered := range encoders {
+		if registered.MediaType == encoder.MediaType {
+			encoders[i] = &encoder
+			return
+		}
+	}
+	encoders = append(encoders, &encoder)
+}
+
+// NegotiateContentType 根据 Accept 选择响应的媒体类型, offers 按服务端的优先级排列.
+// 每个 offer 使用最具体的匹配(type/subtype > type/* > */*)的 q 值, 选择 q 值最大的 offer, q 值相同时使用服务端的优先级;
+// 没有 Accept 时返回第一个 offer, 没有可接受的 offer 时返回空字符串
+func NegotiateContentType(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if accept == "" {
+		return offers[0]
+	}
+	weights := parseQualityValues(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q, ok := mediaQuality(weights, offer); ok && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func mediaQuality(weights map[string]float64, offer string) (float64, bool) {
+	if q, ok := weights[offer]; ok {
+		return q, true
+	}
+	for i := 0; i < len(offer); i++ {
+		if offer[i] == '/' {
+			if q, ok := weights[offer[:i]+"/*"]; ok {
+				return q, true
+			}
+			break
+		}
+	}
+	q, ok := weights["*/*"]
+	return q, ok
+}
+
+// negotiateEncoder 选择支持 data 的编码器, 同时返回所有可用的媒体类型
+func negotiateEncoder(accept string, data any) (*Encoder, []string) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	var offers []string
+	byType := make(map[string]*Encoder)
+	for _, encoder := range encoders {
+		if encoder.Supports != nil && !encoder.Supports(data) {
+			continue
+		}
+		for _, mediaType := range append([]string{encoder.MediaType}, encoder.Aliases...) {
+			offers = append(offers, mediaType)
+			byType[mediaType] = encoder
+		}
+	}
+	return byType[NegotiateContentType(accept, offers)], offers
+}
+
+// Respond sends the response encoded by the encoder negotiated from the Accept header, code is mapped like SendResponse.
+// JSON, XML and MessagePack use the Response envelope, protobuf (proto.Message data) and CSV (tabular data) encode data directly.
+// It sends 406 when no encoder is acceptable
+func Respond(w http.ResponseWriter, r *http.Request, code int, data any, headers map[string]string) {
+	w.Header().Add("Vary", "Accept")
+	encoder, offers := negotiateEncoder(r.Header.Get("Accept"), data)
+	if encoder == nil {
+		WriteError(w, r, ErrNotAcceptable.WithDetails(offers))
+		return
+	}
+
+	httpStatus, message := getResponseStatusAndMessage(code)
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set("Content-Type", encoder.ContentType)
+	w.WriteHeader(httpStatus)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	var v any = Response{Code: code, Message: message, Data: data}
+	if encoder.Raw {
+		v = data
+	}
+	if err := encoder.Encode(w, v); err != nil {
+		logx.Logf(logx.LEVEL_ERROR, "default", "Failed to encode response of %s %s as %s: %v\n", r.Method, r.URL.Path, encoder.MediaType, err)
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// encodeJSON 与 json.Encoder 的结果相同, Response 的 data 是切片时逐个编码元素, 不在内存中构建整个响应
+func encodeJSON(w io.Writer, v any) error {
+	if resp, ok := v.(Response); ok && resp.Data != nil {
+		data := reflect.ValueOf(resp.Data)
+		if (data.Kind() == reflect.Slice || data.Kind() == reflect.Array) && !(data.Kind() == reflect.Slice && data.IsNil()) &&
+			data.Type().Elem().Kind() != reflect.Uint8 && !data.Type().Implements(jsonMarshalerType) {
+			return encodeJSONStream(w, resp, func(yield func(any) bool) {
+				for i := 0; i < data.Len(); i++ {
+					if !yield(data.Index(i).Interface()) {
+						return
+					}
+				}
+			})
+		}
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// encodeJSONStream 编码 Response 包装的数组, 每个元素单独编码后写入 w. 出错时停止写入, 客户端收到的是不完整的 JSON
+func encodeJSONStream(w io.Writer, resp Response, elements iter.Seq[any]) error {
+	head, err := json.Marshal(struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{resp.Code, resp.Message})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(head[:len(head)-1], `,"data":[`...)); err != nil {
+		return err
+	}
+
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	for element := range elements {
+		b, err := json.Marshal(element)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			b = append([]byte{','}, b...)
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if count++; flusher != nil && count%256 == 0 {
+			flusher.Flush()
+		}
+	}
+
+	tail := []byte("]")
+	if resp.TraceID != "" {
+		traceID, _ := json.Marshal(resp.TraceID)
+		tail = append(append(tail, `,"trace_id":`...), traceID...)
+	}
+	_, err = w.Write(append(tail, "}\n"...))
+	return err
+}
+
+// StreamJSON sends the elements of seq as the data of a 200 Response, the elements are encoded one by one,
+// e.g. rows read from a database cursor, so the payload is never built in memory
+func StreamJSON[T any](w http.ResponseWriter, seq iter.Seq[T]) error {
+	httpStatus, message := getResponseStatusAndMessage(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(httpStatus)
+	return encodeJSONStream(w, Response{Code: http.StatusOK, Message: message}, func(yield func(any) bool) {
+		for element := range seq {
+			if !yield(element) {
+				return
+			}
+		}
+	})
+}
+
+func encodeXML(w io.Writer, v any) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// encodeMsgpack 使用 json tag 作为字段名称, 与 JSON 响应的字段一致
+func encodeMsgpack(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	return encoder.Encode(v)
+}
+
+func isProtoMessage(data any) bool {
+	_, ok := data.(proto.Message)
+	return ok
+}
+
+func encodeProtobuf(w io.Writer, v any) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	b, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
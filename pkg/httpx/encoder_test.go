@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/csv"}
+	cases := map[string]string{
+		"":                                     "application/json",
+		"application/xml":                      "application/xml",
+		"text/*;q=0.9, application/json;q=0.5": "text/csv",
+		"application/*":                        "application/json",
+		"*/*;q=0.1, application/xml":           "application/xml",
+		"application/json;q=0, */*":            "application/xml",
+		"image/png":                            "",
+		"text/csv;q=0.5, application/*;q=0.5":  "application/json",
+		"application/XML;charset=utf-8;q=0.8":  "application/xml",
+		"application/json;q=0, application/xml;q=0, text/csv;q=0": "",
+	}
+	for accept, expected := range cases {
+		if got := NegotiateContentType(accept, offers); got != expected {
+			t.Errorf("Accept %q: expected %q, got %q", accept, expected, got)
+		}
+	}
+}
+
+type encoderRow struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Secret  string    `json:"-"`
+}
+
+func respond(accept string, data any) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	Respond(rec, req, http.StatusOK, data, nil)
+	return rec
+}
+
+func TestRespondEncodings(t *testing.T) {
+	created := time.Date(2025, 6, 13, 8, 0, 0, 0, time.UTC)
+	rows := []encoderRow{{1, "a<b", created, "s"}, {2, "c,d", created, "s"}}
+
+	rec := respond("", rows)
+	expected, _ := json.Marshal(Response{Code: 200, Message: "OK", Data: rows})
+	if rec.Body.String() != string(expected)+"\n" || rec.Header().Get("Vary") != "Accept" {
+		t.Errorf("expected the streamed JSON to equal json.Marshal\n%s\n%s", expected, rec.Body.String())
+	}
+
+	rec = respond("text/csv", rows)
+	if rec.Header().Get("Content-Type") != "text/csv;charset=utf-8" ||
+		rec.Body.String() != "id,name,created\n1,a<b,2025-06-13T08:00:00Z\n2,\"c,d\",2025-06-13T08:00:00Z\n" {
+		t.Errorf("unexpected CSV %q", rec.Body.String())
+	}
+
+	rec = respond("application/xml", map[string]any{"total": 2, "items": []any{"a", map[string]int{"n": 1}}, "a b": true})
+	if !strings.Contains(rec.Body.String(), `<Data><entry key="a b">true</entry><items><item>a</item><item><n>1</n></item></items><total>2</total></Data>`) {
+		t.Errorf("unexpected XML %s", rec.Body.String())
+	}
+
+	rec = respond("application/x-msgpack", map[string]string{"k": "v"})
+	var decoded struct {
+		Code int               `msgpack:"code"`
+		Data map[string]string `msgpack:"data"`
+	}
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &decoded); err != nil || decoded.Code != 200 || decoded.Data["k"] != "v" {
+		t.Errorf("unexpected msgpack %+v %v", decoded, err)
+	}
+
+	rec = respond("application/x-protobuf", wrapperspb.String("taurus"))
+	var message wrapperspb.StringValue
+	if err := proto.Unmarshal(rec.Body.Bytes(), &message); err != nil || message.Value != "taurus" {
+		t.Errorf("unexpected protobuf %v %v", message.Value, err)
+	}
+}
+
+func TestRespondNotAcceptable(t *testing.T) {
+	rec := respond("text/csv", map[string]string{"k": "v"})
+	var body struct {
+		Code int      `json:"code"`
+		Data []string `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusNotAcceptable || slices.Contains(body.Data, "text/csv") || !slices.Contains(body.Data, "application/json") {
+		t.Errorf("expected 406 listing the available types, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := StreamJSON(rec, func(yield func(int) bool) {
+		for i := 1; i <= 3; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	if err != nil || rec.Body.String() != `{"code":200,"message":"OK","data":[1,2,3]}`+"\n" {
+		t.Errorf("unexpected stream %q %v", rec.Body.String(), err)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, Response{Code: 200, Message: "OK", Data: []int{}}); err != nil || buf.String() != `{"code":200,"message":"OK","data":[]}`+"\n" {
+		t.Errorf("expected empty slices encoded like encoding/json, got %q", buf.String())
+	}
+}
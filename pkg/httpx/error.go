@@ -0,0 +1,295 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	"Taurus/pkg/logx"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorFormat 错误响应的格式
+type ErrorFormat string
+
+const (
+	ErrorFormatLegacy  ErrorFormat = "legacy"  // httpx.Response 信封, 使用真实的 HTTP 状态码, 业务错误码只在报文的 code 中
+	ErrorFormatProblem ErrorFormat = "problem" // RFC 7807 application/problem+json, 使用真实的 HTTP 状态码
+)
+
+var (
+	// DefaultErrorFormat is the format of WriteError, configured by server.error_format
+	DefaultErrorFormat = ErrorFormatLegacy
+	// ProblemTypeBase is the prefix of the problem type, the type is <base><message key>; empty means about:blank
+	ProblemTypeBase = ""
+)
+
+// Error 应用错误, handler 通过 HandlerFunc 返回, 由 WriteError 渲染.
+// Message 为空时按 MessageKey 和 Accept-Language 查找 RegisterMessages 注册的消息, Cause 和调用栈只记录到日志, 不返回给客户端
+type Error struct {
+	Code       int    // 业务错误码, 默认等于 Status
+	Status     int    // HTTP 状态码
+	MessageKey string // 消息 key, 例如 order.not_found
+	Message    string // 默认消息
+	Details    any    // 错误详情, 例如字段校验错误
+	Cause      error  // 原始错误
+
+	stack []uintptr
+}
+
+// NewError creates an error of the HTTP status, key and default message
+func NewError(status int, key, message string) *Error {
+	return &Error{Code: status, Status: status, MessageKey: key, Message: message, stack: callers()}
+}
+
+// Wrap wraps the cause as an error of the status and key, the message of the cause is not returned to the client
+func Wrap(cause error, status int, key, message string) *Error {
+	e := NewError(status, key, message)
+	e.Cause = cause
+	return e
+}
+
+func (e *Error) Error() string {
+	message := e.MessageKey
+	if e.Message != "" {
+		message = e.Message
+	}
+	if e.Cause != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, message, e.Cause)
+	}
+	return fmt.Sprintf("%d %s", e.Status, message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether the target is an *Error with the same message key, errors created from the same template match
+func (e *Error) Is(target error) bool {
+	var t *Error
+	return errors.As(target, &t) && t.MessageKey != "" && t.MessageKey == e.MessageKey && t.Status == e.Status
+}
+
+// WithCode returns a copy with the business code
+func (e *Error) WithCode(code int) *Error {
+	c := e.clone()
+	c.Code = code
+	return c
+}
+
+// WithDetails returns a copy with the details
+func (e *Error) WithDetails(details any) *Error {
+	c := e.clone()
+	c.Details = details
+	return c
+}
+
+// WithCause returns a copy with the cause
+func (e *Error) WithCause(cause error) *Error {
+	c := e.clone()
+	c.Cause = cause
+	return c
+}
+
+// clone 复制错误并记录新的调用栈, 包级的错误模板可以安全地复用
+func (e *Error) clone() *Error {
+	c := *e
+	c.stack = callers()
+	return &c
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+// Stack returns the formatted call stack where the error was created
+func (e *Error) Stack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// AsError converts err to *Error, unknown errors are internal server errors
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return Wrap(err, http.StatusRequestEntityTooLarge, "request.too_large", fmt.Sprintf("Request body exceeds %d bytes", maxErr.Limit))
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, http.StatusGatewayTimeout, "request.timeout", "Request timeout")
+	}
+	return Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error")
+}
+
+var (
+	messagesMu sync.RWMutex
+	messages   = map[string]map[string]string{} // 语言 -> 消息 key -> 消息
+)
+
+// RegisterMessages registers the messages of a language (e.g. zh, en-US), they are selected by Accept-Language
+func RegisterMessages(lang string, catalog map[string]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	lang = strings.ToLower(lang)
+	if messages[lang] == nil {
+		messages[lang] = make(map[string]string)
+	}
+	for key, message := range catalog {
+		messages[lang][key] = message
+	}
+}
+
+// localize 按 Accept-Language 的 q 值查找消息, 也匹配主语言, 例如 zh-CN 匹配 zh
+func localize(r *http.Request, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	if len(messages) == 0 {
+		return "", false
+	}
+	header := r.Header.Get("Accept-Language")
+	weights := parseQualityValues(header)
+	best, bestQ := "", 0.0
+	// q 值相同时使用请求头中靠前的语言
+	for _, item := range strings.Split(header, ",") {
+		lang, _, _ := strings.Cut(item, ";")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if q := weights[lang]; q > bestQ {
+			for _, candidate := range []string{lang, strings.SplitN(lang, "-", 2)[0]} {
+				if message, ok := messages[candidate][key]; ok {
+					best, bestQ = message, q
+					break
+				}
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// Problem RFC 7807 的错误响应
+type Problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	Code       int    `json:"code,omitempty"`
+	MessageKey string `json:"message_key,omitempty"`
+	Details    any    `json:"details,omitempty"`
+	TraceID    string `json:"trace_id,omitempty"`
+}
+
+// WriteError renders err with DefaultErrorFormat. 5xx errors are logged with the cause and the stack, the trace ID of the request is included in the body
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := AsError(err)
+	message := e.Message
+	if localized, ok := localize(r, e.MessageKey); ok {
+		message = localized
+	}
+	traceID := requestTraceID(r)
+	if e.Status >= http.StatusInternalServerError {
+		logx.Logf(logx.LEVEL_ERROR, "default", "%s %s failed, trace_id: %s: %v\n%s", r.Method, r.URL.Path, traceID, e, e.Stack())
+	}
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.RecordError(e)
+		if e.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, message)
+		}
+	}
+
+	if DefaultErrorFormat != ErrorFormatProblem {
+		title, ok := errorMessages[e.Code]
+		if !ok {
+			title = http.StatusText(e.Status)
+		}
+		var data any = message
+		if e.Details != nil {
+			data = e.Details
+		}
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		w.WriteHeader(e.Status)
+		json.NewEncoder(w).Encode(Response{Code: e.Code, Message: title, Data: data, TraceID: traceID})
+		return
+	}
+
+	problem := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(e.Status),
+		Status:     e.Status,
+		Detail:     message,
+		Instance:   r.URL.Path,
+		MessageKey: e.MessageKey,
+		Details:    e.Details,
+		TraceID:    traceID,
+	}
+	if ProblemTypeBase != "" && e.MessageKey != "" {
+		problem.Type = ProblemTypeBase + e.MessageKey
+	}
+	if e.Code != e.Status {
+		problem.Code = e.Code
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// requestTraceID 返回请求的 trace id, trace 中间件写入 RequestContext 的 TraceID 与 span 的 trace id 相同
+func requestTraceID(r *http.Request) string {
+	if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+// HandlerFunc 返回错误的 handler, 错误由 WriteError 渲染, 例如:
+//
+//	mux.Handle("GET /orders/{id}", httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		return httpx.NewError(http.StatusNotFound, "order.not_found", "Order not found")
+//	}))
+//
+// handler 已经写入响应后不应再返回错误
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errOrderNotFound = NewError(http.StatusNotFound, "order.not_found", "Order not found")
+
+func serveError(t *testing.T, format ErrorFormat, err error, header http.Header) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	old := DefaultErrorFormat
+	DefaultErrorFormat = format
+	defer func() { DefaultErrorFormat = old }()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID})))
+	rec := httptest.NewRecorder()
+	HandlerFunc(func(w http.ResponseWriter, r *http.Request) error { return err }).ServeHTTP(rec, req)
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %s: %v", rec.Body.String(), err)
+	}
+	return rec, body
+}
+
+func TestWriteErrorProblem(t *testing.T) {
+	RegisterMessages("zh", map[string]string{"order.not_found": "订单不存在"})
+	defer func() { messages = map[string]map[string]string{} }()
+
+	err := errOrderNotFound.WithCode(40401).WithDetails(map[string]string{"id": "1"})
+	rec, body := serveError(t, ErrorFormatProblem, err, http.Header{"Accept-Language": {"en;q=0.5, zh-CN"}})
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected 404 problem, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	expected := map[string]any{
+		"type": "about:blank", "title": "Not Found", "status": 404.0, "detail": "订单不存在", "instance": "/orders/1",
+		"code": 40401.0, "message_key": "order.not_found", "trace_id": "0102030405060708090a0b0c0d0e0f10",
+	}
+	for key, value := range expected {
+		if body[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, body[key])
+		}
+	}
+	if details, _ := body["details"].(map[string]any); details["id"] != "1" {
+		t.Errorf("expected details, got %v", body["details"])
+	}
+	if !errors.Is(err, errOrderNotFound) {
+		t.Error("expected the copy to match the template")
+	}
+}
+
+func TestWriteErrorHidesCause(t *testing.T) {
+	rec, body := serveError(t, ErrorFormatProblem, io.ErrUnexpectedEOF, nil)
+	if rec.Code != http.StatusInternalServerError || body["detail"] != "Internal Server Error" {
+		t.Errorf("expected a generic 500, got %d %v", rec.Code, body)
+	}
+
+	rec, body = serveError(t, ErrorFormatLegacy, Wrap(io.ErrUnexpectedEOF, http.StatusBadGateway, "upstream", "Upstream failed"), nil)
+	if rec.Code != http.StatusBadGateway || body["code"] != 502.0 || body["data"] != "Upstream failed" || body["trace_id"] == nil {
+		t.Errorf("expected the legacy envelope, got %d %v", rec.Code, body)
+	}
+
+	// the business code is only in the body
+	rec, body = serveError(t, ErrorFormatLegacy, NewError(http.StatusNotFound, "order.not_found", "Order not found").WithCode(StatusInvalidParams), nil)
+	if rec.Code != http.StatusNotFound || body["code"] != float64(StatusInvalidParams) || body["message"] != "Invalid Parameters" {
+		t.Errorf("expected the status of the error, got %d %v", rec.Code, body)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// precompressedExtensions 编码对应的预压缩文件扩展名
+var precompressedExtensions = map[string]string{
+	"br":   ".br",
+	"zstd": ".zst",
+	"gzip": ".gz",
+}
+
+// FileServer 静态文件服务, precompressed 为 true 时如果客户端接受, 优先返回同目录下预压缩的 .br/.zst/.gz 文件,
+// 例如请求 app.js 时返回 app.js.br, 并设置 Content-Encoding, Vary 和原始文件的 Content-Type. 没有预压缩文件时与 http.FileServer 相同
+func FileServer(root http.FileSystem, precompressed bool) http.Handler {
+	fileServer := http.FileServer(root)
+	if !precompressed {
+		return fileServer
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasSuffix(r.URL.Path, "/") {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		if !hasPrecompressed(name) {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		// 存在的预压缩文件, 按服务端优先级排列
+		var available []string
+		for _, encoding := range []string{"br", "zstd", "gzip"} {
+			if file, err := root.Open(name + precompressedExtensions[encoding]); err == nil {
+				file.Close()
+				available = append(available, encoding)
+			}
+		}
+		if len(available) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"), available)
+			if encoding != "" && servePrecompressed(w, r, root, name, encoding) {
+				return
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// hasPrecompressed 只为有扩展名且不是压缩文件本身的请求查找预压缩文件
+func hasPrecompressed(name string) bool {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return false
+	}
+	for _, compressed := range precompressedExtensions {
+		if ext == compressed {
+			return false
+		}
+	}
+	return true
+}
+
+func servePrecompressed(w http.ResponseWriter, r *http.Request, root http.FileSystem, name, encoding string) bool {
+	file, err := root.Open(name + precompressedExtensions[encoding])
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", encoding)
+	http.ServeContent(w, r, name, stat.ModTime(), file)
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NegotiateEncoding 根据 Accept-Encoding 选择响应的编码, supported 按服务端的优先级排列.
+// 选择 q 值最大的编码, q 值相同时使用服务端的优先级; 没有可用的编码时返回空字符串, 表示不压缩
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return ""
+	}
+	weights := parseQualityValues(acceptEncoding)
+	if q, ok := weights["x-gzip"]; ok {
+		if _, exists := weights["gzip"]; !exists {
+			weights["gzip"] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := weights[encoding]
+		if !ok {
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// parseQualityValues parses a header like "gzip;q=0.8, br, *;q=0", tokens are lower cased, invalid q values are 0
+func parseQualityValues(header string) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(item, ";")
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.EqualFold(strings.TrimSpace(name), "q") {
+				var err error
+				if q, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+			}
+		}
+		weights[token] = q
+	}
+	return weights
+}
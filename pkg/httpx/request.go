@@ -127,10 +127,13 @@ func ParseText(r *http.Request) (string, error) {
 	return string(body), nil
 }
 
+// MaxMultipartMemory 解析 multipart/form-data 时保存在内存中的最大字节数, 超出的部分写入临时文件; 请求体的大小由 body_limit 中间件限制
+var MaxMultipartMemory int64 = 10 << 20
+
 // ParseMultipartFile 解析(multipart/form-data)表单上传的文件
 func ParseMultipartFile(r *http.Request, key string) ([]*multipart.FileHeader, error) {
-	// 解析 multipart/form-data, 10MB 内存缓冲， 如果文件不上传完， 会报错， 所以当前函数只要返回没有错误， 就可以返回数据给客户端，不用等待
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max memory
+	// 解析 multipart/form-data, MaxMultipartMemory 内存缓冲， 如果文件不上传完， 会报错， 所以当前函数只要返回没有错误， 就可以返回数据给客户端，不用等待
+	if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
 		return nil, fmt.Errorf("failed to parse multipart form data: %w", err)
 	}
 
@@ -147,7 +150,7 @@ func ParseMultipartFile(r *http.Request, key string) ([]*multipart.FileHeader, e
 // ParseMultipartData 解析 multipart/form-data 请求，获取所有文件和参数数据
 func ParseMultipartData(r *http.Request) (map[string][]*multipart.FileHeader, map[string][]string, error) {
 	// 解析 multipart/form-data
-	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB max memory
+	if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
 		return nil, nil, fmt.Errorf("failed to parse multipart form data: %w", err)
 	}
 	defer r.Body.Close()
@@ -34,6 +34,7 @@ type Response struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	TraceID string      `json:"trace_id,omitempty"` // 错误响应的 TraceID, 见 WriteError
 }
 
 const (
@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package httpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	xmlMarshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+	xmlName          = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+)
+
+// MarshalXML 编码 Response, 与默认的元素名称相同, Data 支持 encoding/xml 不支持的 map 和 interface 切片
+func (r Response) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Code, xml.StartElement{Name: xml.Name{Local: "Code"}}); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Message, xml.StartElement{Name: xml.Name{Local: "Message"}}); err != nil {
+		return err
+	}
+	if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: "Data"}}, reflect.ValueOf(r.Data)); err != nil {
+		return err
+	}
+	if r.TraceID != "" {
+		if err := e.EncodeElement(r.TraceID, xml.StartElement{Name: xml.Name{Local: "TraceID"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// IsXMLName reports whether the name is written as an element or attribute name as it is
+func IsXMLName(name string) bool {
+	return xmlName.MatchString(name)
+}
+
+// XMLElement returns the start element of a map key, a key which is not an XML name becomes <entry key="...">
+func XMLElement(name string) xml.StartElement {
+	if xmlName.MatchString(name) {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+}
+
+// EncodeXML encodes v as the start element like the Data of Response, maps and interface slices are supported
+func EncodeXML(e *xml.Encoder, start xml.StartElement, v any) error {
+	return encodeXMLValue(e, start, reflect.ValueOf(v))
+}
+
+// encodeXMLValue map 的 key 作为元素名称(不是合法的名称时使用 <entry key="...">), 切片的元素为 <item>, 其他类型使用 encoding/xml
+func encodeXMLValue(e *xml.Encoder, start xml.StartElement, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(xmlMarshalerType) || v.Type().Implements(textMarshalerType) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(xmlMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return e.EncodeElement(v.Interface(), start)
+	}
+
+	switch {
+	case v.Kind() == reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			if err := encodeXMLValue(e, XMLElement(fmt.Sprint(key.Interface())), v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: "item"}}, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(v.Interface(), start)
+}
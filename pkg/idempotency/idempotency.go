@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+// Package idempotency 保存 Idempotency-Key 对应的请求指纹和响应, 重复的请求直接返回第一次的响应
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Record 一个 Idempotency-Key 的状态, Status 为 0 表示请求正在处理
+type Record struct {
+	Token       string      `json:"token"`       // 保留 key 的请求的随机标识, 只有它可以保存响应或释放 key
+	Fingerprint string      `json:"fingerprint"` // 请求的方法、路径和请求体的哈希
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// InFlight reports whether the request of the record is still being processed
+func (r *Record) InFlight() bool {
+	return r.Status == 0
+}
+
+// Store 保存 Idempotency-Key 的记录
+type Store interface {
+	// Reserve 原子地保留 key, 成功时返回 nil; key 已存在时返回已有的记录(处理中或已完成)
+	Reserve(ctx context.Context, key string, reserved *Record, ttl time.Duration) (*Record, error)
+	// Complete 保存响应, key 仍被 reserved 保留时才保存
+	Complete(ctx context.Context, key string, reserved *Record, record *Record, ttl time.Duration) error
+	// Release 删除 reserved 的保留, 请求失败后允许客户端重试
+	Release(ctx context.Context, key string, reserved *Record) error
+}
+
+var (
+	// Default is the store used by the idempotency middleware
+	Default Store = NewMemoryStore()
+	// DefaultTTL is the time the responses are kept
+	DefaultTTL = 24 * time.Hour
+	// DefaultLockTTL is the time an in-flight request keeps the key, the key can be retried after it
+	DefaultLockTTL = time.Minute
+)
+
+// ErrReleased is returned by Complete when the reservation expired or was replaced
+var ErrReleased = errors.New("idempotency key is no longer reserved")
+
+// NewReservation creates the in-flight record of the request fingerprint
+func NewReservation(fingerprint string) *Record {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return &Record{Token: hex.EncodeToString(token), Fingerprint: fingerprint}
+}
+
+// Fingerprint returns the hash of the method, the request uri and the body
+func Fingerprint(method, requestURI string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + requestURI + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryStore 进程内存储, 用于单实例部署和测试, 过期的记录在访问时清理
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	now     func() time.Time
+	sweepAt time.Time
+}
+
+type memoryRecord struct {
+	record  Record
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord), now: time.Now}
+}
+
+func (s *MemoryStore) Reserve(_ context.Context, key string, reserved *Record, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if current, ok := s.records[key]; ok && now.Before(current.expires) {
+		record := current.record
+		return &record, nil
+	}
+	s.records[key] = memoryRecord{record: *reserved, expires: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, reserved *Record, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if current, ok := s.records[key]; !ok || current.record.Token != reserved.Token || !now.Before(current.expires) {
+		return ErrReleased
+	}
+	s.records[key] = memoryRecord{record: *record, expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string, reserved *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.records[key]; ok && current.record.Token == reserved.Token {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// sweep removes the expired records once a minute
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.sweepAt) {
+		return
+	}
+	s.sweepAt = now.Add(time.Minute)
+	for key, record := range s.records {
+		if !now.Before(record.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// Len returns the number of records, including the expired ones not swept yet
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+var (
+	// reserveScript 不存在时保留 key, 否则返回已有的记录
+	reserveScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return false
+end
+return redis.call('GET', KEYS[1])
+`)
+	// completeScript 仍被保留时保存响应
+	completeScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+	// releaseScript 仍被保留时删除 key
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+// RedisStore 使用 Redis 存储, 多个副本共享记录, 保留和保存都是原子的
+type RedisStore struct {
+	client *redisx.RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a redis store, keys are <prefix><key>, default prefix idempotency:
+func NewRedisStore(client *redisx.RedisClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "idempotency:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, reserved *Record, ttl time.Duration) (*Record, error) {
+	value, err := json.Marshal(reserved)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.client.RunScript(ctx, reserveScript, []string{s.prefix + key}, value, ttl.Milliseconds())
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, _ := result.(string)
+	var record Record
+	if err := json.Unmarshal([]byte(current), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, reserved *Record, record *Record, ttl time.Duration) error {
+	// 与 Reserve 的序列化结果相同, 用于比较
+	current, err := json.Marshal(reserved)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	result, err := s.client.RunScript(ctx, completeScript, []string{s.prefix + key}, current, value, ttl.Milliseconds())
+	if err != nil {
+		return err
+	}
+	if saved, _ := result.(int64); saved == 0 {
+		return ErrReleased
+	}
+	return nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string, reserved *Record) error {
+	current, err := json.Marshal(reserved)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.RunScript(ctx, releaseScript, []string{s.prefix + key}, current)
+	return err
+}
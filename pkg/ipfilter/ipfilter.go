@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+// Package ipfilter 按 IP 允许/拒绝访问.
+//
+// 规则是 IP、CIDR(支持 IPv6), 使用前缀树匹配. 命名的列表可以在运行时替换,
+// HTTP 的 ip_filter/host 中间件、gRPC 拦截器和 TCP 服务器在每次检查时读取最新的列表
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// Rule 允许和拒绝的 IP 或 CIDR, 拒绝优先; allow 为空时允许所有未被拒绝的地址
+type Rule struct {
+	Allow []string `json:"allow" yaml:"allow" toml:"allow"` // 允许的 IP 或 CIDR
+	Deny  []string `json:"deny" yaml:"deny" toml:"deny"`    // 拒绝的 IP 或 CIDR
+}
+
+// Filter is a compiled rule
+type Filter struct {
+	allow trie
+	deny  trie
+}
+
+// New compiles the rule
+func New(rule Rule) (*Filter, error) {
+	f := &Filter{}
+	for _, item := range []struct {
+		values []string
+		trie   *trie
+	}{{rule.Allow, &f.allow}, {rule.Deny, &f.deny}} {
+		for _, value := range item.values {
+			prefix, err := parsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip filter rule %s: %w", value, err)
+			}
+			item.trie.insert(prefix)
+		}
+	}
+	return f, nil
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Allowed reports whether the ip is allowed, invalid ips are only allowed by a filter without allow rules and deny rules
+func (f *Filter) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return f.allow.empty() && f.deny.empty()
+	}
+	return f.AllowedAddr(addr)
+}
+
+// AllowedAddr reports whether the addr is allowed
+func (f *Filter) AllowedAddr(addr netip.Addr) bool {
+	if f.deny.contains(addr) {
+		return false
+	}
+	return f.allow.empty() || f.allow.contains(addr)
+}
+
+// DefaultList is the list checked by the host middleware of HTTP and gRPC
+const DefaultList = "default"
+
+var (
+	listsMu sync.RWMutex
+	lists   = make(map[string]*Filter)
+)
+
+// Set compiles and replaces the named list, requests checked afterwards use the new rules
+func Set(name string, rule Rule) error {
+	f, err := New(rule)
+	if err != nil {
+		return fmt.Errorf("ip filter list %s: %w", name, err)
+	}
+	listsMu.Lock()
+	defer listsMu.Unlock()
+	lists[name] = f
+	return nil
+}
+
+// Delete removes the named list
+func Delete(name string) {
+	listsMu.Lock()
+	defer listsMu.Unlock()
+	delete(lists, name)
+}
+
+// Get returns the named list
+func Get(name string) (*Filter, bool) {
+	listsMu.RLock()
+	defer listsMu.RUnlock()
+	f, ok := lists[name]
+	return f, ok
+}
+
+// Allowed checks the ip with the named list, an undefined list allows all ips
+func Allowed(name, ip string) bool {
+	f, ok := Get(name)
+	return !ok || f.Allowed(ip)
+}
+
+// HostAllowed checks the ip with DefaultList for the host middleware, unlike Allowed an undefined list denies all ips,
+// so a missing default list does not open the service to everyone
+func HostAllowed(ip string) bool {
+	f, ok := Get(DefaultList)
+	return ok && f.Allowed(ip)
+}
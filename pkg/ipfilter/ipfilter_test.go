@@ -0,0 +1,101 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestFilterAllowed(t *testing.T) {
+	f, err := New(Rule{
+		Allow: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"},
+		Deny:  []string{"10.1.0.0/16", "2001:db8:bad::/48"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"10.0.0.1":          true,
+		"10.1.2.3":          false, // 拒绝优先
+		"192.168.1.10":      true,
+		"192.168.1.11":      false,
+		"::ffff:10.0.0.1":   true, // IPv4-mapped
+		"2001:db8::1":       true,
+		"2001:db8:bad::1":   false,
+		"2001:db9::1":       false,
+		"not-an-ip":         false,
+		"fe80::1%eth0":      false,
+		"255.255.255.255":   false,
+		"0.0.0.0":           false,
+		"2001:db8:ffff::ff": true,
+	}
+	for ip, expected := range cases {
+		if got := f.Allowed(ip); got != expected {
+			t.Errorf("%s: expected %v, got %v", ip, expected, got)
+		}
+	}
+}
+
+func TestFilterDenyOnly(t *testing.T) {
+	f, err := New(Rule{Deny: []string{"0.0.0.0/0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Allowed("1.2.3.4") || !f.Allowed("::1") {
+		t.Error("expected all IPv4 denied and IPv6 allowed")
+	}
+}
+
+func TestTrieMatchesLinearScan(t *testing.T) {
+	var prefixes []netip.Prefix
+	var tr trie
+	for i := 0; i < 256; i += 7 {
+		prefix := netip.MustParsePrefix(fmt.Sprintf("10.%d.0.0/%d", i, 16+i%9))
+		prefixes = append(prefixes, prefix.Masked())
+		tr.insert(prefix.Masked())
+	}
+	for i := 0; i < 256; i++ {
+		for _, j := range []int{0, 1, 64, 128, 255} {
+			addr := netip.AddrFrom4([4]byte{10, byte(i), byte(j), 1})
+			expected := false
+			for _, prefix := range prefixes {
+				if prefix.Contains(addr) {
+					expected = true
+					break
+				}
+			}
+			if got := tr.contains(addr); got != expected {
+				t.Fatalf("%s: expected %v, got %v", addr, expected, got)
+			}
+		}
+	}
+}
+
+func TestSetReplacesList(t *testing.T) {
+	defer Delete("test")
+	if !Allowed("test", "1.2.3.4") {
+		t.Error("undefined lists allow all ips")
+	}
+	if _, ok := Get(DefaultList); !ok && HostAllowed("1.2.3.4") {
+		t.Error("undefined default list should deny all ips")
+	}
+	if err := Set("test", Rule{Deny: []string{"1.2.3.4"}}); err != nil {
+		t.Fatal(err)
+	}
+	if Allowed("test", "1.2.3.4") {
+		t.Error("expected ip denied")
+	}
+	if err := Set("test", Rule{Allow: []string{"1.2.3.0/24"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !Allowed("test", "1.2.3.4") || Allowed("test", "1.2.4.1") {
+		t.Error("expected the replaced list used")
+	}
+	if err := Set("test", Rule{Allow: []string{"1.2.3.0/33"}}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+	if !Allowed("test", "1.2.3.4") {
+		t.Error("an invalid list must not replace the current one")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package ipfilter
+
+import "net/netip"
+
+// trie 按位存储前缀的二叉前缀树, 查找的时间只和地址长度有关, 与规则数量无关
+type trie struct {
+	v4 *trieNode
+	v6 *trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	terminal bool // 从根到该节点的路径是一个完整的前缀
+}
+
+// insert adds the prefix, IPv4-mapped IPv6 prefixes are stored as IPv4
+func (t *trie) insert(prefix netip.Prefix) {
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr, bits = addr.Unmap(), bits-96
+	}
+	root := &t.v6
+	if addr.Is4() {
+		root = &t.v4
+	}
+	if *root == nil {
+		*root = &trieNode{}
+	}
+
+	node := *root
+	raw := addr.AsSlice()
+	for i := 0; i < bits; i++ {
+		bit := bitAt(raw, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.terminal = true
+}
+
+// contains reports whether any prefix contains the addr
+func (t *trie) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	node := t.v6
+	if addr.Is4() {
+		node = t.v4
+	}
+
+	raw := addr.AsSlice()
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+		if i == len(raw)*8 {
+			return false
+		}
+		node = node.children[bitAt(raw, i)]
+	}
+	return false
+}
+
+func (t *trie) empty() bool {
+	return t.v4 == nil && t.v6 == nil
+}
+
+func bitAt(raw []byte, i int) int {
+	return int(raw[i/8]>>(7-uint(i%8))) & 1
+}
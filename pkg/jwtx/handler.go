@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package jwtx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"Taurus/pkg/httpx"
+)
+
+// HandleJWKS serves the public keys at /.well-known/jwks.json, the body is a plain JWKS so other services can consume it
+func (s *Service) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(s.JWKS())
+}
+
+// HandleRefresh exchanges the refresh token of the body {"refresh_token": "..."} for a new token pair
+func (s *Service) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		httpx.WriteError(w, r, httpx.NewError(http.StatusBadRequest, "jwt.refresh_token_required", "refresh_token is required"))
+		return
+	}
+	pair, err := s.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		sendError(w, r, err)
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, pair, nil)
+}
+
+// HandleRevoke revokes the session of the bearer token or the token of the body {"token": "..."}, e.g. at logout
+func (s *Service) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	token := BearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		token = req.Token
+	}
+	if token == "" {
+		httpx.WriteError(w, r, httpx.NewError(http.StatusBadRequest, "jwt.token_required", "token is required"))
+		return
+	}
+	if err := s.Revoke(r.Context(), token); err != nil {
+		sendError(w, r, err)
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, "revoked", nil)
+}
+
+// sendError 渲染 token 错误, 其他错误只记录到日志, 不返回给客户端
+func sendError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrExpired) || errors.Is(err, ErrRevoked) {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusUnauthorized, "jwt.invalid", err.Error()))
+		return
+	}
+	httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+}
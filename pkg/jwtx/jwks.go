@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package jwtx
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// RemoteJWKS verifies tokens of another issuer by its JWKS url, e.g. https://idp.example.com/.well-known/jwks.json.
+// Keys are cached for the TTL, an unknown key id refreshes the cache at most once per minRefresh,
+// so the issuer can rotate keys without a restart and forged key ids can not flood it.
+// Concurrent refreshes share one fetch, which runs without the lock and is not canceled by the requests waiting for it
+type RemoteJWKS struct {
+	url        string
+	ttl        time.Duration
+	minRefresh time.Duration
+	timeout    time.Duration
+	client     *http.Client
+	group      singleflight.Group
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewRemoteJWKS creates a remote key set, ttl defaults to 10m
+func NewRemoteJWKS(url string, ttl time.Duration) *RemoteJWKS {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	return &RemoteJWKS{
+		url:        url,
+		ttl:        ttl,
+		minRefresh: 30 * time.Second,
+		timeout:    10 * time.Second,
+		client:     &http.Client{},
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+// Key returns the public key of the key id, ok is false if the key set does not have it
+func (j *RemoteJWKS) Key(ctx context.Context, id string) (crypto.PublicKey, bool, error) {
+	j.mu.Lock()
+	age := time.Since(j.fetched)
+	key, ok := j.keys[id]
+	refetch := !j.fetched.IsZero() && age < j.minRefresh
+	j.mu.Unlock()
+	if ok && age < j.ttl {
+		return key, true, nil
+	}
+	if !ok && refetch {
+		return nil, false, nil
+	}
+
+	var keys map[string]crypto.PublicKey
+	var err error
+	select {
+	case result := <-j.group.DoChan(j.url, j.refresh):
+		if err = result.Err; err == nil {
+			keys = result.Val.(map[string]crypto.PublicKey)
+		}
+	case <-ctx.Done():
+		// the fetch continues for the other requests and fills the cache
+		err = ctx.Err()
+	}
+	if err != nil {
+		if ok {
+			// 拉取失败时继续使用缓存的 key, 避免身份提供方短暂不可用导致所有请求失败
+			log.Printf("Failed to refresh jwks %s, the cached keys are used: %v\n", j.url, err)
+			return key, true, nil
+		}
+		return nil, false, err
+	}
+	key, ok = keys[id]
+	return key, ok, nil
+}
+
+// refresh fetches the key set with its own timeout and caches it
+func (j *RemoteJWKS) refresh() (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), j.timeout)
+	defer cancel()
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.fetched = time.Now()
+	j.mu.Unlock()
+	return keys, nil
+}
+
+func (j *RemoteJWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks %s responded %d", j.url, resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks %s: %v", j.url, err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// 跳过不支持的 key, 其他 key 仍然可用
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,466 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package jwtx 签发和校验 JWT: RS256/ES256/EdDSA 非对称签名, 带 key id 的 JWKS, 远程 JWKS 校验,
+// access + refresh token 对和 refresh token 轮换, 基于 jti/sid 的吊销列表
+//
+// refresh token 每次刷新都会被吊销并签发新的 token 对, 同一会话(sid)中已经被使用过的 refresh token 再次出现时,
+// 说明 refresh token 可能泄露, 整个会话会被吊销
+package jwtx
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+var (
+	ErrInvalidToken = errors.New("jwt is invalid")
+	ErrExpired      = errors.New("jwt is expired")
+	ErrRevoked      = errors.New("jwt is revoked")
+	ErrNoSigningKey = errors.New("jwt service has no signing key")
+)
+
+// Claims are the claims of the tokens, custom claims are kept in Extra and serialized at the top level
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	Session   string   `json:"sid,omitempty"`        // 会话 id, 同一次登录刷新出的所有 token 共享
+	Type      string   `json:"token_type,omitempty"` // access or refresh
+
+	Extra map[string]interface{} `json:"-"` // 自定义 claims, 例如 uid, username, roles
+}
+
+var standardClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "sid", "token_type"}
+
+// Valid implements jwt.Claims, the claims are validated by Service.Verify with the leeway instead
+func (c *Claims) Valid() error {
+	return nil
+}
+
+// Get returns a claim as a string, e.g. sub or uid, empty if it is not set
+func (c *Claims) Get(name string) string {
+	switch name {
+	case "iss":
+		return c.Issuer
+	case "sub":
+		return c.Subject
+	case "jti":
+		return c.ID
+	case "sid":
+		return c.Session
+	}
+	switch v := c.Extra[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+type claimsContextKey string
+
+const claimsKey claimsContextKey = "jwt_claims"
+
+// WithClaims adds the verified claims to the context, see the jwt middleware
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// GetClaims retrieves the verified claims from the context
+func GetClaims(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+func (c Claims) MarshalJSON() ([]byte, error) {
+	type standard Claims
+	data, err := json.Marshal(standard(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+	merged := make(map[string]interface{}, len(c.Extra)+len(standardClaims))
+	for name, value := range c.Extra {
+		merged[name] = value
+	}
+	// 标准 claims 优先, Extra 不能覆盖 exp, aud 等
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type standard Claims
+	if err := json.Unmarshal(data, (*standard)(c)); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&all); err != nil {
+		return err
+	}
+	for _, name := range standardClaims {
+		delete(all, name)
+	}
+	c.Extra = nil
+	if len(all) > 0 {
+		c.Extra = all
+	}
+	return nil
+}
+
+// Audience is the aud claim, a single audience is serialized as a string
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// TokenPair is issued at login and refresh, the fields follow the OAuth 2.0 token response
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"` // Bearer
+	ExpiresIn        int64  `json:"expires_in"` // seconds
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+}
+
+// Config holds the options of a Service, zero values use the defaults
+type Config struct {
+	Issuer        string          // 签发的 iss, 校验时 token 的 iss 必须是 Issuer 或 AcceptIssuers 之一
+	AcceptIssuers []string        // 额外接受的 iss, 例如远程 JWKS 对应的身份提供方
+	Audience      []string        // 签发的 aud, 校验时 token 的 aud 至少包含其中一个, 为空表示不校验
+	Leeway        time.Duration   // 校验 exp, nbf, iat 时允许的时钟偏差, 默认 30s
+	AccessTTL     time.Duration   // access token 有效期, 默认 15m
+	RefreshTTL    time.Duration   // refresh token 有效期, 默认 7 天
+	TrustedKeys   []JWK           // 额外信任的公钥, 例如轮换前的签名 key, 会出现在 JWKS 中
+	Remotes       []*RemoteJWKS   // 远程 JWKS, 校验其他签发方的 token
+	Revocations   RevocationStore // 吊销列表, 默认内存
+}
+
+// Service issues and verifies tokens
+type Service struct {
+	config Config
+	signer *SigningKey
+	keys   map[string]crypto.PublicKey
+	jwks   JWKS
+	parser *jwt.Parser
+}
+
+// Default is the service used by the JWT middleware, set by the application at startup
+var Default *Service
+
+// NewService creates a service, signer may be nil for a service that only verifies tokens
+func NewService(signer *SigningKey, config Config) (*Service, error) {
+	if config.Leeway == 0 {
+		config.Leeway = 30 * time.Second
+	}
+	if config.AccessTTL <= 0 {
+		config.AccessTTL = 15 * time.Minute
+	}
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if config.Revocations == nil {
+		config.Revocations = NewMemoryRevocations()
+	}
+
+	s := &Service{
+		config: config,
+		signer: signer,
+		keys:   make(map[string]crypto.PublicKey),
+		jwks:   JWKS{Keys: []JWK{}},
+		// 只接受非对称算法, 避免用公钥作为 HMAC 密钥的算法混淆攻击
+		parser: &jwt.Parser{ValidMethods: []string{RS256, ES256, EdDSA}, UseJSONNumber: true, SkipClaimsValidation: true},
+	}
+	trusted := config.TrustedKeys
+	if signer != nil {
+		jwk, err := signer.JWK()
+		if err != nil {
+			return nil, err
+		}
+		trusted = append([]JWK{jwk}, trusted...)
+	}
+	for _, jwk := range trusted {
+		if jwk.KeyID == "" {
+			jwk.KeyID = jwk.Thumbprint()
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("trusted key %s: %v", jwk.KeyID, err)
+		}
+		if _, ok := s.keys[jwk.KeyID]; ok {
+			continue
+		}
+		s.keys[jwk.KeyID] = key
+		s.jwks.Keys = append(s.jwks.Keys, jwk)
+	}
+	return s, nil
+}
+
+// JWKS returns the public keys of the service
+func (s *Service) JWKS() JWKS {
+	return s.jwks
+}
+
+// IssuePair issues an access token and a refresh token of a new session, e.g. at login
+func (s *Service) IssuePair(ctx context.Context, subject string, extra map[string]interface{}) (*TokenPair, error) {
+	return s.issuePair(subject, extra, newID())
+}
+
+// Refresh exchanges a refresh token for a new pair, the refresh token can only be used once.
+// A reused refresh token revokes the whole session
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.parse(ctx, refreshToken, TypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	first, err := s.config.Revocations.Revoke(ctx, claims.ID, time.Unix(claims.ExpiresAt, 0).Add(s.config.Leeway))
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		// refresh token 被重复使用, 可能已经泄露, 吊销整个会话
+		if _, err := s.config.Revocations.Revoke(ctx, claims.Session, time.Now().Add(s.config.RefreshTTL)); err != nil {
+			return nil, err
+		}
+		return nil, ErrRevoked
+	}
+	return s.issuePair(claims.Subject, claims.Extra, claims.Session)
+}
+
+// Revoke revokes the session of the token, all access and refresh tokens of the session become invalid, e.g. at logout
+func (s *Service) Revoke(ctx context.Context, token string) error {
+	claims, err := s.parse(ctx, token, "")
+	if err != nil {
+		return err
+	}
+	id := claims.Session
+	if id == "" {
+		id = claims.ID
+	}
+	if id == "" {
+		return fmt.Errorf("%w: no jti or sid", ErrInvalidToken)
+	}
+	_, err = s.config.Revocations.Revoke(ctx, id, time.Now().Add(s.config.RefreshTTL))
+	return err
+}
+
+// Verify verifies an access token and returns its claims
+func (s *Service) Verify(ctx context.Context, token string) (*Claims, error) {
+	return s.parse(ctx, token, TypeAccess)
+}
+
+// parse verifies the signature, the claims and the revocation, typ is the required token type, empty accepts both
+func (s *Service) parse(ctx context.Context, token string, typ string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := s.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := s.validate(claims, typ, time.Now()); err != nil {
+		return nil, err
+	}
+
+	for _, id := range []string{claims.ID, claims.Session} {
+		if id == "" {
+			continue
+		}
+		revoked, err := s.config.Revocations.IsRevoked(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// 单独吊销的 refresh token 由 Refresh 处理重复使用
+		if revoked && !(typ == TypeRefresh && id == claims.ID) {
+			return nil, ErrRevoked
+		}
+	}
+	return claims, nil
+}
+
+// key returns the public key of the key id, local keys first and then the remote key sets
+func (s *Service) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	for _, remote := range s.config.Remotes {
+		key, ok, err := remote.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *Service) validate(claims *Claims, typ string, now time.Time) error {
+	leeway := int64(s.config.Leeway / time.Second)
+	unix := now.Unix()
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: exp is required", ErrInvalidToken)
+	}
+	if unix > claims.ExpiresAt+leeway {
+		return ErrExpired
+	}
+	if claims.NotBefore != 0 && unix < claims.NotBefore-leeway {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if claims.IssuedAt != 0 && unix < claims.IssuedAt-leeway {
+		return fmt.Errorf("%w: token is issued in the future", ErrInvalidToken)
+	}
+	if s.config.Issuer != "" || len(s.config.AcceptIssuers) > 0 {
+		if claims.Issuer != s.config.Issuer && !contains(s.config.AcceptIssuers, claims.Issuer) {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+		}
+	}
+	if len(s.config.Audience) > 0 && !containsAny(claims.Audience, s.config.Audience) {
+		return fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, claims.Audience)
+	}
+	// 其他签发方的 token 通常没有 token_type, 视为 access token
+	if typ == TypeAccess && claims.Type == TypeRefresh || typ == TypeRefresh && claims.Type != TypeRefresh {
+		return fmt.Errorf("%w: unexpected token type %q", ErrInvalidToken, claims.Type)
+	}
+	return nil
+}
+
+func (s *Service) issuePair(subject string, extra map[string]interface{}, session string) (*TokenPair, error) {
+	if s.signer == nil {
+		return nil, ErrNoSigningKey
+	}
+	now := time.Now()
+	access, err := s.sign(s.newClaims(subject, extra, session, TypeAccess, now, s.config.AccessTTL))
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(s.newClaims(subject, extra, session, TypeRefresh, now, s.config.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(s.config.AccessTTL / time.Second),
+		RefreshExpiresIn: int64(s.config.RefreshTTL / time.Second),
+	}, nil
+}
+
+func (s *Service) newClaims(subject string, extra map[string]interface{}, session string, typ string, now time.Time, ttl time.Duration) *Claims {
+	return &Claims{
+		Issuer:    s.config.Issuer,
+		Subject:   subject,
+		Audience:  s.config.Audience,
+		ExpiresAt: now.Add(ttl).Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        newID(),
+		Session:   session,
+		Type:      typ,
+		Extra:     extra,
+	}
+}
+
+func (s *Service) sign(claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signer.method(), claims)
+	token.Header["kid"] = s.signer.ID
+	return token.SignedString(s.signer.Private)
+}
+
+// BearerToken returns the token of the Authorization: Bearer header, empty if there is none
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package jwtx
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestServiceAlgorithms(t *testing.T) {
+	ctx := context.Background()
+	for _, algorithm := range []string{RS256, ES256, EdDSA} {
+		signer, err := GenerateSigningKey(algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewService(signer, Config{Issuer: "taurus", Audience: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pair, err := s.IssuePair(ctx, "42", map[string]interface{}{"uid": 42, "username": "tom"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := s.Verify(ctx, pair.AccessToken)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if claims.Subject != "42" || claims.Get("uid") != "42" || claims.Get("username") != "tom" || claims.Type != TypeAccess {
+			t.Errorf("%s: unexpected claims %+v", algorithm, claims)
+		}
+		if _, err := s.Verify(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: refresh token must not be accepted as access token: %v", algorithm, err)
+		}
+		token, _, _ := new(jwt.Parser).ParseUnverified(pair.AccessToken, &Claims{})
+		if token.Header["kid"] != signer.ID || token.Header["alg"] != algorithm {
+			t.Errorf("%s: unexpected header %v", algorithm, token.Header)
+		}
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	ctx := context.Background()
+	signer, _ := GenerateSigningKey(EdDSA)
+	s, _ := NewService(signer, Config{Issuer: "taurus", Audience: []string{"api"}, Leeway: time.Minute})
+
+	sign := func(claims *Claims) string {
+		token, err := s.sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	now := time.Now()
+	valid := func() *Claims {
+		return &Claims{Issuer: "taurus", Audience: Audience{"web", "api"}, ExpiresAt: now.Add(time.Minute).Unix(), Subject: "1"}
+	}
+
+	if _, err := s.Verify(ctx, sign(valid())); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+	claims := valid()
+	claims.ExpiresAt = now.Add(-30 * time.Second).Unix()
+	if _, err := s.Verify(ctx, sign(claims)); err != nil {
+		t.Errorf("expired within leeway: %v", err)
+	}
+	claims.ExpiresAt = now.Add(-2 * time.Minute).Unix()
+	if _, err := s.Verify(ctx, sign(claims)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+	claims = valid()
+	claims.Issuer = "other"
+	if _, err := s.Verify(ctx, sign(claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("issuer: %v", err)
+	}
+	claims = valid()
+	claims.Audience = Audience{"web"}
+	if _, err := s.Verify(ctx, sign(claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("audience: %v", err)
+	}
+	claims = valid()
+	claims.NotBefore = now.Add(5 * time.Minute).Unix()
+	if _, err := s.Verify(ctx, sign(claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("not before: %v", err)
+	}
+
+	// HS256 is rejected, even when signed with the public key
+	jwk, _ := signer.JWK()
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+	hmac.Header["kid"] = signer.ID
+	forged, _ := hmac.SignedString([]byte(jwk.X))
+	if _, err := s.Verify(ctx, forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("hmac token: %v", err)
+	}
+
+	// a token of another key is rejected
+	other, _ := GenerateSigningKey(EdDSA)
+	otherService, _ := NewService(other, Config{Issuer: "taurus", Audience: []string{"api"}})
+	pair, _ := otherService.IssuePair(ctx, "1", nil)
+	if _, err := s.Verify(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown key: %v", err)
+	}
+}
+
+func TestServiceRefreshAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	signer, _ := GenerateSigningKey(ES256)
+	s, _ := NewService(signer, Config{})
+
+	pair, err := s.IssuePair(ctx, "7", map[string]interface{}{"role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := s.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.Verify(ctx, refreshed.AccessToken)
+	if err != nil || claims.Get("role") != "admin" || claims.Subject != "7" {
+		t.Fatalf("refreshed access token: %v %+v", err, claims)
+	}
+	first, _ := s.parse(ctx, pair.AccessToken, TypeAccess)
+	if claims.Session != first.Session {
+		t.Error("refreshed tokens should keep the session")
+	}
+	if _, err := s.Refresh(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token can not be refreshed: %v", err)
+	}
+
+	// reusing a refresh token revokes the whole session
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("reused refresh token: %v", err)
+	}
+	if _, err := s.Verify(ctx, refreshed.AccessToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("session should be revoked: %v", err)
+	}
+	if _, err := s.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("session should be revoked: %v", err)
+	}
+
+	// logout revokes the session
+	pair, _ = s.IssuePair(ctx, "8", nil)
+	if err := s.Revoke(ctx, pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(ctx, pair.AccessToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked access token: %v", err)
+	}
+	if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked refresh token: %v", err)
+	}
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	ctx := context.Background()
+	idpKey, _ := GenerateSigningKey(RS256)
+	idp, _ := NewService(idpKey, Config{Issuer: "idp"})
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		idp.HandleJWKS(w, r)
+	}))
+	defer server.Close()
+
+	s, err := NewService(nil, Config{Issuer: "taurus", AcceptIssuers: []string{"idp"}, Remotes: []*RemoteJWKS{NewRemoteJWKS(server.URL, time.Minute)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, _ := idp.IssuePair(ctx, "remote", nil)
+	for i := 0; i < 3; i++ {
+		if claims, err := s.Verify(ctx, pair.AccessToken); err != nil || claims.Subject != "remote" {
+			t.Fatalf("remote token: %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("jwks should be cached, fetched %d times", fetches)
+	}
+	if _, err := s.IssuePair(ctx, "1", nil); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("verify only service: %v", err)
+	}
+
+	// unknown key ids do not refetch within the minimal refresh interval
+	other, _ := GenerateSigningKey(RS256)
+	otherIdp, _ := NewService(other, Config{Issuer: "idp"})
+	pair, _ = otherIdp.IssuePair(ctx, "remote", nil)
+	s.Verify(ctx, pair.AccessToken)
+	s.Verify(ctx, pair.AccessToken)
+	if fetches != 1 {
+		t.Errorf("unknown key ids should be rate limited, fetched %d times", fetches)
+	}
+
+	var set JWKS
+	rec := httptest.NewRecorder()
+	idp.HandleJWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil || len(set.Keys) != 1 || set.Keys[0].KeyID != idpKey.ID || strings.Contains(rec.Body.String(), `"d"`) {
+		t.Errorf("unexpected jwks %s", rec.Body.String())
+	}
+}
+
+func TestRemoteJWKSSharedFetch(t *testing.T) {
+	idpKey, _ := GenerateSigningKey(ES256)
+	idp, _ := NewService(idpKey, Config{Issuer: "idp"})
+
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		idp.HandleJWKS(w, r)
+	}))
+	defer server.Close()
+	remote := NewRemoteJWKS(server.URL, time.Minute)
+
+	// a canceled caller gives up without canceling the fetch of the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := remote.Key(canceled, idpKey.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, err := remote.Key(context.Background(), idpKey.ID); !ok || err != nil {
+				t.Errorf("key not found: %v", err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("concurrent lookups should share one fetch, fetched %d times", n)
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	generated, _ := GenerateSigningKey(ES256)
+	der, err := x509.MarshalPKCS8PrivateKey(generated.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	loaded, err := LoadSigningKey(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the default key id is the thumbprint, so every instance with the same key agrees on it
+	if loaded.Algorithm != ES256 || loaded.ID != generated.ID {
+		t.Errorf("unexpected key %s %s, want %s", loaded.Algorithm, loaded.ID, generated.ID)
+	}
+	if _, err := LoadSigningKey(path, RS256, ""); err == nil {
+		t.Error("algorithm mismatch should fail")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package jwtx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+)
+
+// RevocationStore records revoked token and session ids until the tokens expire.
+// Revoke reports whether the id was not revoked before, it must be atomic so a refresh token is only exchanged once
+type RevocationStore interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryRevocations keeps revoked ids in memory, only for single instance deployments
+type MemoryRevocations struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevocations creates an empty revocation list
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{revoked: make(map[string]time.Time)}
+}
+
+func (s *MemoryRevocations) Revoke(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// 顺便清理已经过期的记录, 过期的 token 不需要吊销记录
+	for revokedID, until := range s.revoked {
+		if now.After(until) {
+			delete(s.revoked, revokedID)
+		}
+	}
+	if _, ok := s.revoked[id]; ok {
+		return false, nil
+	}
+	s.revoked[id] = expiresAt
+	return true, nil
+}
+
+func (s *MemoryRevocations) IsRevoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.revoked[id]
+	return ok && time.Now().Before(until), nil
+}
+
+// RedisRevocations keeps each revoked id as a redis key <prefix><jti>, which expires with the token
+type RedisRevocations struct {
+	client *redisx.RedisClient
+	prefix string
+}
+
+// NewRedisRevocations creates a revocation list in redis, default prefix is jwt:revoked:
+func NewRedisRevocations(client *redisx.RedisClient, prefix string) *RedisRevocations {
+	if prefix == "" {
+		prefix = "jwt:revoked:"
+	}
+	return &RedisRevocations{client: client, prefix: prefix}
+}
+
+func (s *RedisRevocations) Revoke(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return true, nil
+	}
+	// SET NX, 只有第一次吊销返回 true
+	return s.client.Lock(ctx, s.prefix+id, "1", ttl)
+}
+
+func (s *RedisRevocations) IsRevoked(ctx context.Context, id string) (bool, error) {
+	value, err := s.client.Get(ctx, s.prefix+id)
+	if err != nil {
+		return false, err
+	}
+	return value != "", nil
+}
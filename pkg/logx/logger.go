@@ -133,6 +133,25 @@ func (lm LoggerMap) Fatal(name string, format string, a ...any) {
 // 将 Core 定义为 LoggerMap 类型的实例
 var Core = LoggerMap{}
 
+// Logf 按等级写入名为 name 的日志, Core 还没有初始化时(例如测试和工具包单独使用时)使用标准库 log 输出.
+// 不支持 LEVEL_FATAL, 其他等级按 LEVEL_ERROR 输出
+func Logf(level LogLevel, name string, format string, a ...any) {
+	if len(Core) == 0 {
+		log.Printf(format, a...)
+		return
+	}
+	switch level {
+	case LEVEL_DEBUG:
+		Core.Debug(name, format, a...)
+	case LEVEL_INFO:
+		Core.Info(name, format, a...)
+	case LEVEL_WARN:
+		Core.Warn(name, format, a...)
+	default:
+		Core.Error(name, format, a...)
+	}
+}
+
 func Initialize(configs []Config) {
 	for _, config := range configs {
 		if _, ok := Core[config.Name]; ok {
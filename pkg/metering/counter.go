@@ -0,0 +1,243 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// pendingField is the field of a count not yet flushed, a JSON array so that any part may contain separators
+func pendingField(day string, consumer string, route string) string {
+	field, _ := json.Marshal([3]string{day, consumer, route})
+	return string(field)
+}
+
+func parsePending(field string, count int64) (Record, bool) {
+	var parts []string
+	if err := json.Unmarshal([]byte(field), &parts); err != nil || len(parts) != 3 {
+		return Record{}, false
+	}
+	return Record{Day: parts[0], Consumer: parts[1], Route: parts[2], Count: count}, true
+}
+
+// MemoryCounter counts in memory, for tests and single instance deployments
+type MemoryCounter struct {
+	mu      sync.Mutex
+	periods map[string]int64 // <day|month>|<consumer> -> count
+	pending map[string]int64
+	lastDay string // 上一个请求的日期, 日期变化时清理过期的周期
+}
+
+// NewMemoryCounter creates a memory counter
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{periods: make(map[string]int64), pending: make(map[string]int64)}
+}
+
+func (c *MemoryCounter) Take(_ context.Context, consumer string, route string, now time.Time, plan Plan) (int64, int64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day, month := now.Format(dayLayout), now.Format(monthLayout)
+	// 过期的周期在新周期的第一个请求时清理
+	if day != c.lastDay {
+		for key := range c.periods {
+			if period, _, _ := strings.Cut(key, "|"); period != day && period != month {
+				delete(c.periods, key)
+			}
+		}
+		c.lastDay = day
+	}
+
+	daily, monthly := c.periods[day+"|"+consumer], c.periods[month+"|"+consumer]
+	if (plan.Daily > 0 && daily >= plan.Daily) || (plan.Monthly > 0 && monthly >= plan.Monthly) {
+		return daily, monthly, false, nil
+	}
+	c.periods[day+"|"+consumer]++
+	c.periods[month+"|"+consumer]++
+	c.pending[pendingField(day, consumer, route)]++
+	return daily + 1, monthly + 1, true, nil
+}
+
+func (c *MemoryCounter) Usage(_ context.Context, consumer string, now time.Time) (int64, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.periods[now.Format(dayLayout)+"|"+consumer], c.periods[now.Format(monthLayout)+"|"+consumer], nil
+}
+
+func (c *MemoryCounter) Drain(_ context.Context, fn func(records []Record) error) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[string]int64)
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	records := make([]Record, 0, len(pending))
+	for field, count := range pending {
+		if record, ok := parsePending(field, count); ok {
+			records = append(records, record)
+		}
+	}
+	if err := fn(records); err != nil {
+		// 写入失败时放回, 下次继续写入
+		c.mu.Lock()
+		for field, count := range pending {
+			c.pending[field] += count
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// takeScript checks the quotas and counts the request, KEYS are the day counter, the month counter and the pending hash,
+// ARGV are the daily limit, the monthly limit, the pending field and the ttl of the day and month counters in seconds
+var takeScript = redis.NewScript(`
+local daily = tonumber(redis.call('GET', KEYS[1]) or '0')
+local monthly = tonumber(redis.call('GET', KEYS[2]) or '0')
+local dailyLimit = tonumber(ARGV[1])
+local monthlyLimit = tonumber(ARGV[2])
+if (dailyLimit > 0 and daily >= dailyLimit) or (monthlyLimit > 0 and monthly >= monthlyLimit) then
+  return {0, daily, monthly}
+end
+daily = redis.call('INCR', KEYS[1])
+if daily == 1 then
+  redis.call('EXPIRE', KEYS[1], ARGV[4])
+end
+monthly = redis.call('INCR', KEYS[2])
+if monthly == 1 then
+  redis.call('EXPIRE', KEYS[2], ARGV[5])
+end
+redis.call('HINCRBY', KEYS[3], ARGV[3], 1)
+return {1, daily, monthly}
+`)
+
+// claimScript moves the pending hash to the flushing hash and returns it, a flushing hash left by a failed flush is returned again
+var claimScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+  if redis.call('EXISTS', KEYS[1]) == 0 then
+    return {}
+  end
+  redis.call('RENAME', KEYS[1], KEYS[2])
+end
+return redis.call('HGETALL', KEYS[2])
+`)
+
+// RedisCounter counts in Redis, shared by all the replicas. All keys have the hash tag {<prefix>} so the scripts
+// work in cluster mode, the counters of all consumers are on the same node
+type RedisCounter struct {
+	client *redisx.RedisClient
+	prefix string
+}
+
+// NewRedisCounter creates a Redis counter, default prefix metering
+func NewRedisCounter(client *redisx.RedisClient, prefix string) *RedisCounter {
+	if prefix == "" {
+		prefix = "metering"
+	}
+	return &RedisCounter{client: client, prefix: "{" + prefix + "}:"}
+}
+
+func (c *RedisCounter) Take(ctx context.Context, consumer string, route string, now time.Time, plan Plan) (int64, int64, bool, error) {
+	day, month := now.Format(dayLayout), now.Format(monthLayout)
+	keys := []string{c.prefix + "d:" + day + ":" + consumer, c.prefix + "m:" + month + ":" + consumer, c.prefix + "pending"}
+	// 计数器保留到周期结束后一天, 避免时钟偏差导致提前过期
+	dayTTL := 48 * time.Hour
+	monthTTL := 32 * 24 * time.Hour
+	reply, err := c.client.RunScript(ctx, takeScript, keys,
+		plan.Daily, plan.Monthly, pendingField(day, consumer, route), int64(dayTTL.Seconds()), int64(monthTTL.Seconds()))
+	if err != nil {
+		return 0, 0, false, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		return 0, 0, false, fmt.Errorf("unexpected metering script reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	daily, _ := values[1].(int64)
+	monthly, _ := values[2].(int64)
+	return daily, monthly, allowed == 1, nil
+}
+
+func (c *RedisCounter) Usage(ctx context.Context, consumer string, now time.Time) (int64, int64, error) {
+	var counts [2]int64
+	for i, key := range []string{c.prefix + "d:" + now.Format(dayLayout) + ":" + consumer, c.prefix + "m:" + now.Format(monthLayout) + ":" + consumer} {
+		value, err := c.client.Get(ctx, key)
+		if err != nil {
+			return 0, 0, err
+		}
+		if value != "" {
+			if counts[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return counts[0], counts[1], nil
+}
+
+// Drain claims the pending counts under a lock, so only one replica flushes at a time
+func (c *RedisCounter) Drain(ctx context.Context, fn func(records []Record) error) error {
+	lockKey, token := c.prefix+"flush:lock", uuid.NewString()
+	locked, err := c.client.Lock(ctx, lockKey, token, 5*time.Minute)
+	if err != nil || !locked {
+		return err
+	}
+	defer c.client.Unlock(context.Background(), lockKey, token)
+
+	flushing := c.prefix + "flushing"
+	reply, err := c.client.RunScript(ctx, claimScript, []string{c.prefix + "pending", flushing})
+	if err != nil {
+		return err
+	}
+	values, _ := reply.([]interface{})
+	records := make([]Record, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		field, _ := values[i].(string)
+		value, _ := values[i+1].(string)
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if record, ok := parsePending(field, count); ok {
+			records = append(records, record)
+		}
+	}
+	if len(records) > 0 {
+		if err := fn(records); err != nil {
+			return err
+		}
+	}
+	return c.client.Del(ctx, flushing)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package metering
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Taurus/pkg/httpx"
+)
+
+// 管理接口, 需要由调用方加上鉴权中间件, 例如 middleware.ApiKeyScopeMiddleware("usage:admin")
+//
+//	GET {prefix}                        使用报表, 参数 consumer, route, from, to (2006-01-02), period (day, month), by_route (true)
+//	GET {prefix}/export                 以 CSV 导出使用报表, 参数同上
+//	GET {prefix}/consumers/{consumer}   调用方当天和当月的实时使用量及配额, 包含尚未写入数据库的请求
+
+// csvHeaders are the columns of the CSV export
+var csvHeaders = []string{"period", "consumer", "route", "count"}
+
+// HandleReport returns the usage report
+func (m *Meter) HandleReport(w http.ResponseWriter, r *http.Request) {
+	query, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+	records, err := m.Report(r.Context(), query)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+		return
+	}
+	if records == nil {
+		records = []Record{}
+	}
+	httpx.SendResponse(w, http.StatusOK, records, nil)
+}
+
+// HandleExport streams the usage report as a CSV file
+func (m *Meter) HandleExport(w http.ResponseWriter, r *http.Request) {
+	query, ok := parseQuery(w, r)
+	if !ok {
+		return
+	}
+	records, err := m.Report(r.Context(), query)
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usage-`+time.Now().UTC().Format("20060102150405")+`.csv"`)
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeaders)
+	for _, record := range records {
+		writer.Write([]string{record.Day, record.Consumer, record.Route, strconv.FormatInt(record.Count, 10)})
+	}
+	writer.Flush()
+}
+
+// HandleConsumer returns the live usage of the path value consumer
+func (m *Meter) HandleConsumer(w http.ResponseWriter, r *http.Request) {
+	usage, err := m.Usage(r.Context(), r.PathValue("consumer"))
+	if err != nil {
+		httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "internal", "Internal Server Error"))
+		return
+	}
+	httpx.SendResponse(w, http.StatusOK, usage, nil)
+}
+
+func parseQuery(w http.ResponseWriter, r *http.Request) (Query, bool) {
+	values := r.URL.Query()
+	query := Query{
+		Consumer: values.Get("consumer"),
+		Route:    values.Get("route"),
+		From:     values.Get("from"),
+		To:       values.Get("to"),
+		ByRoute:  values.Get("by_route") == "true",
+	}
+	for _, day := range []string{query.From, query.To} {
+		if _, err := time.Parse(dayLayout, day); day != "" && err != nil {
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "usage.invalid_date", "from and to must be 2006-01-02"))
+			return query, false
+		}
+	}
+	switch values.Get("period") {
+	case "", "day":
+	case "month":
+		query.Monthly = true
+	default:
+		httpx.WriteError(w, r, httpx.NewError(http.StatusBadRequest, "usage.invalid_period", "period must be day or month"))
+		return query, false
+	}
+	return query, true
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package metering 按调用方计量 API 使用量并执行日/月配额.
+//
+// 调用方是 API key、JWT 的租户或用户, 每个调用方属于一个套餐(plan), 套餐定义每日和每月的请求数上限.
+// 请求计数保存在 Redis 中, 定期汇总写入数据库表 metering_usage, 用于账单和使用报表
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/db"
+	"Taurus/pkg/jwtx"
+	"Taurus/pkg/logx"
+
+	"gorm.io/gorm"
+)
+
+// Plan is a quota of requests per day and per month, zero means unlimited
+type Plan struct {
+	Daily   int64 `json:"daily" yaml:"daily" toml:"daily"`
+	Monthly int64 `json:"monthly" yaml:"monthly" toml:"monthly"`
+}
+
+// Usage is the usage of a consumer in the current day and month
+type Usage struct {
+	Consumer     string `json:"consumer"`
+	Plan         string `json:"plan"`
+	Daily        int64  `json:"daily"`
+	Monthly      int64  `json:"monthly"`
+	DailyLimit   int64  `json:"daily_limit"`
+	MonthlyLimit int64  `json:"monthly_limit"`
+}
+
+// Record is the number of requests of a consumer to a route in a day, Day is 2006-01-02 in UTC
+type Record struct {
+	Day      string `json:"day"`
+	Consumer string `json:"consumer"`
+	Route    string `json:"route"`
+	Count    int64  `json:"count"`
+}
+
+// Counter counts the requests, Take counts a request only when the usage is within the plan.
+// Drain passes the counts not yet flushed to fn and removes them when fn succeeds
+type Counter interface {
+	Take(ctx context.Context, consumer string, route string, now time.Time, plan Plan) (daily int64, monthly int64, allowed bool, err error)
+	Usage(ctx context.Context, consumer string, now time.Time) (daily int64, monthly int64, err error)
+	Drain(ctx context.Context, fn func(records []Record) error) error
+}
+
+// Config holds the plans and how consumers are identified
+type Config struct {
+	Plans       map[string]Plan   // 套餐
+	Consumers   map[string]string // 调用方到套餐的映射, 例如 {"key:8f3a": basic, "tenant:acme": pro}
+	DefaultPlan string            // 未指定套餐的调用方使用的套餐, 为空时不限制但仍然计量
+	PlanClaim   string            // JWT 中套餐的 claim, 默认 plan, 优先于 DefaultPlan
+	TenantClaim string            // JWT 中租户的 claim, 默认 tenant, 存在时按租户计量, 否则按 sub
+	DB          string            // 使用量写入的数据库名称
+}
+
+// ErrUnknownPlan is returned by NewMeter when a plan is not defined
+var ErrUnknownPlan = errors.New("unknown plan")
+
+// Meter counts the requests of consumers and enforces the quotas of their plans
+type Meter struct {
+	counter Counter
+	config  Config
+}
+
+// Default is the meter used by the quota middleware, set by the application at startup
+var Default *Meter
+
+// NewMeter creates a meter, the plans of the consumers must be defined and the database must be initialized
+func NewMeter(counter Counter, config Config) (*Meter, error) {
+	if config.PlanClaim == "" {
+		config.PlanClaim = "plan"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant"
+	}
+	if config.DefaultPlan != "" {
+		if _, ok := config.Plans[config.DefaultPlan]; !ok {
+			return nil, fmt.Errorf("%w: default plan %s", ErrUnknownPlan, config.DefaultPlan)
+		}
+	}
+	for consumer, plan := range config.Consumers {
+		if _, ok := config.Plans[plan]; !ok {
+			return nil, fmt.Errorf("%w: %s of consumer %s", ErrUnknownPlan, plan, consumer)
+		}
+	}
+	if _, ok := db.DbList()[config.DB]; !ok {
+		return nil, fmt.Errorf("metering database %s not found", config.DB)
+	}
+	return &Meter{counter: counter, config: config}, nil
+}
+
+// Consumer returns the consumer and its plan of the identity in the context: key:<id> for an API key,
+// tenant:<tenant> or user:<sub> for a JWT. ok is false for anonymous requests
+func (m *Meter) Consumer(ctx context.Context) (consumer string, plan string, ok bool) {
+	if identity, found := contextx.GetApiKey(ctx); found {
+		consumer = "key:" + identity.ID
+	} else if claims, found := jwtx.GetClaims(ctx); found {
+		if tenant := claims.Get(m.config.TenantClaim); tenant != "" {
+			consumer = "tenant:" + tenant
+		} else if claims.Subject != "" {
+			consumer = "user:" + claims.Subject
+		}
+		plan = claims.Get(m.config.PlanClaim)
+	}
+	if consumer == "" {
+		return "", "", false
+	}
+	if assigned, found := m.config.Consumers[consumer]; found {
+		plan = assigned
+	}
+	if _, found := m.config.Plans[plan]; !found {
+		plan = m.config.DefaultPlan
+	}
+	return consumer, plan, true
+}
+
+// Decision is the result of a quota check
+type Decision struct {
+	Allowed    bool
+	Usage      Usage
+	RetryAfter time.Duration // 超出配额时到配额重置的时间
+}
+
+// Allow counts a request of the consumer to the route. When the counter fails the request is allowed and not counted
+func (m *Meter) Allow(ctx context.Context, consumer string, plan string, route string) Decision {
+	now := time.Now().UTC()
+	limits := m.config.Plans[plan]
+	daily, monthly, allowed, err := m.counter.Take(ctx, consumer, route, now, limits)
+	if err != nil {
+		logx.Logf(logx.LEVEL_WARN, "default", "metering counter failed, request of %s is not counted: %v", consumer, err)
+		return Decision{Allowed: true, Usage: Usage{Consumer: consumer, Plan: plan, DailyLimit: limits.Daily, MonthlyLimit: limits.Monthly}}
+	}
+	decision := Decision{
+		Allowed: allowed,
+		Usage:   Usage{Consumer: consumer, Plan: plan, Daily: daily, Monthly: monthly, DailyLimit: limits.Daily, MonthlyLimit: limits.Monthly},
+	}
+	if !allowed {
+		decision.RetryAfter = retryAfter(now, limits, monthly)
+	}
+	return decision
+}
+
+// Usage returns the usage of the consumer in the current day and month, including requests not yet flushed.
+// The plan is the configured plan of the consumer, the plan claim of a JWT is not known here
+func (m *Meter) Usage(ctx context.Context, consumer string) (Usage, error) {
+	daily, monthly, err := m.counter.Usage(ctx, consumer, time.Now().UTC())
+	if err != nil {
+		return Usage{}, err
+	}
+	plan := m.config.Consumers[consumer]
+	if plan == "" {
+		plan = m.config.DefaultPlan
+	}
+	limits := m.config.Plans[plan]
+	return Usage{Consumer: consumer, Plan: plan, Daily: daily, Monthly: monthly, DailyLimit: limits.Daily, MonthlyLimit: limits.Monthly}, nil
+}
+
+// retryAfter is the time until the exceeded quota is reset, the monthly quota is reset at the first day of the next month
+func retryAfter(now time.Time, plan Plan, monthly int64) time.Duration {
+	if plan.Monthly > 0 && monthly >= plan.Monthly {
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Sub(now)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// UsageModel is the usage of a consumer to a route in a day, table metering_usage
+type UsageModel struct {
+	ID       uint   `gorm:"primaryKey"`
+	Day      string `gorm:"size:10;uniqueIndex:idx_metering_usage"`
+	Consumer string `gorm:"size:128;uniqueIndex:idx_metering_usage"`
+	Route    string `gorm:"size:255;uniqueIndex:idx_metering_usage"`
+	Count    int64
+}
+
+func (UsageModel) TableName() string {
+	return "metering_usage"
+}
+
+// Migrate creates the metering_usage table
+func (m *Meter) Migrate() error {
+	return db.DbList()[m.config.DB].AutoMigrate(&UsageModel{})
+}
+
+// Flush adds the counts not yet flushed to the metering_usage table in a transaction.
+// The counts are removed from the counter after the commit, a crash in between flushes them again
+func (m *Meter) Flush(ctx context.Context) error {
+	return m.counter.Drain(ctx, func(records []Record) error {
+		return db.ExecuteInTransaction(m.config.DB, func(tx *gorm.DB) error {
+			tx = tx.WithContext(ctx)
+			for _, record := range records {
+				result := tx.Model(&UsageModel{}).
+					Where("day = ? AND consumer = ? AND route = ?", record.Day, record.Consumer, record.Route).
+					UpdateColumn("count", gorm.Expr("count + ?", record.Count))
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					continue
+				}
+				if err := tx.Create(&UsageModel{Day: record.Day, Consumer: record.Consumer, Route: record.Route, Count: record.Count}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Run flushes the counts every interval until the context is canceled, then flushes once more
+func (m *Meter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Flush(context.Background()); err != nil {
+				logx.Logf(logx.LEVEL_WARN, "default", "failed to flush metering usage: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				logx.Logf(logx.LEVEL_WARN, "default", "failed to flush metering usage: %v", err)
+			}
+		}
+	}
+}
+
+// Query filters the usage report, empty fields match all, From and To are days 2006-01-02 inclusive
+type Query struct {
+	Consumer string
+	Route    string
+	From     string
+	To       string
+	Monthly  bool // 按月汇总, Day 为 2006-01
+	ByRoute  bool // 按路由分组, 否则 Route 为空
+}
+
+// Report returns the flushed usage grouped by period, consumer and optionally route
+func (m *Meter) Report(ctx context.Context, query Query) ([]Record, error) {
+	period := "day"
+	if query.Monthly {
+		period = "SUBSTR(day, 1, 7)"
+	}
+	columns := period + " AS day, consumer, SUM(count) AS count"
+	groups := period + ", consumer"
+	if query.ByRoute {
+		columns += ", route"
+		groups += ", route"
+	}
+
+	tx := db.DbList()[m.config.DB].WithContext(ctx).Model(&UsageModel{})
+	if query.Consumer != "" {
+		tx = tx.Where("consumer = ?", query.Consumer)
+	}
+	if query.Route != "" {
+		tx = tx.Where("route = ?", query.Route)
+	}
+	if query.From != "" {
+		tx = tx.Where("day >= ?", query.From)
+	}
+	if query.To != "" {
+		tx = tx.Where("day <= ?", query.To)
+	}
+
+	var records []Record
+	err := tx.Select(columns).Group(groups).Order(groups).Scan(&records).Error
+	return records, err
+}
@@ -0,0 +1,179 @@
+package metering
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/db"
+	"Taurus/pkg/jwtx"
+
+	"gorm.io/gorm/logger"
+)
+
+func newTestMeter(t *testing.T, config Config) *Meter {
+	t.Helper()
+	name := "metering_" + strings.ReplaceAll(t.Name(), "/", "_")
+	db.InitDB(name, "sqlite", filepath.Join(t.TempDir(), "usage.db"), logger.Default.LogMode(logger.Silent), 1, 0)
+	config.DB = name
+	meter, err := NewMeter(NewMemoryCounter(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := meter.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	return meter
+}
+
+func TestMeterConsumer(t *testing.T) {
+	meter := newTestMeter(t, Config{
+		Plans:       map[string]Plan{"free": {Daily: 10}, "basic": {Monthly: 1000}},
+		Consumers:   map[string]string{"key:k1": "basic"},
+		DefaultPlan: "free",
+	})
+
+	cases := []struct {
+		ctx      context.Context
+		consumer string
+		plan     string
+	}{
+		{contextx.WithApiKey(context.Background(), &contextx.ApiKeyIdentity{ID: "k1"}), "key:k1", "basic"},
+		{contextx.WithApiKey(context.Background(), &contextx.ApiKeyIdentity{ID: "k2"}), "key:k2", "free"},
+		{jwtx.WithClaims(context.Background(), &jwtx.Claims{Subject: "u1", Extra: map[string]interface{}{"tenant": "acme", "plan": "basic"}}), "tenant:acme", "basic"},
+		{jwtx.WithClaims(context.Background(), &jwtx.Claims{Subject: "u1", Extra: map[string]interface{}{"plan": "gold"}}), "user:u1", "free"},
+	}
+	for _, c := range cases {
+		consumer, plan, ok := meter.Consumer(c.ctx)
+		if !ok || consumer != c.consumer || plan != c.plan {
+			t.Errorf("expected %s %s, got %s %s %v", c.consumer, c.plan, consumer, plan, ok)
+		}
+	}
+	if _, _, ok := meter.Consumer(context.Background()); ok {
+		t.Error("anonymous requests have no consumer")
+	}
+}
+
+func TestMeterQuotaAndFlush(t *testing.T) {
+	meter := newTestMeter(t, Config{Plans: map[string]Plan{"free": {Daily: 2}}, DefaultPlan: "free"})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if decision := meter.Allow(ctx, "key:k1", "free", "GET /orders"); !decision.Allowed {
+			t.Fatalf("request %d must be allowed: %+v", i, decision)
+		}
+	}
+	decision := meter.Allow(ctx, "key:k1", "free", "GET /orders")
+	if decision.Allowed || decision.RetryAfter <= 0 || decision.RetryAfter > 24*time.Hour {
+		t.Fatalf("expected quota exceeded until tomorrow, got %+v", decision)
+	}
+	meter.Allow(ctx, "key:k2", "", "POST /orders")
+
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	meter.Allow(ctx, "key:k2", "", "POST /orders")
+	if err := meter.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := meter.Report(ctx, Query{ByRoute: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC().Format(dayLayout)
+	expected := []Record{
+		{Day: today, Consumer: "key:k1", Route: "GET /orders", Count: 2},
+		{Day: today, Consumer: "key:k2", Route: "POST /orders", Count: 2},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, records)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], records[i])
+		}
+	}
+
+	monthly, err := meter.Report(ctx, Query{Consumer: "key:k2", Monthly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(monthly) != 1 || monthly[0].Day != today[:7] || monthly[0].Count != 2 {
+		t.Errorf("unexpected monthly report %v", monthly)
+	}
+
+	usage, err := meter.Usage(ctx, "key:k1")
+	if err != nil || usage.Daily != 2 || usage.DailyLimit != 2 || usage.Plan != "free" {
+		t.Errorf("unexpected usage %+v %v", usage, err)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	meter := newTestMeter(t, Config{})
+	rec := httptest.NewRecorder()
+	meter.HandleExport(rec, httptest.NewRequest(http.MethodGet, "/admin/usage/export", nil))
+	if rec.Body.String() != "period,consumer,route,count\n" {
+		t.Errorf("expected only the headers, got %q", rec.Body.String())
+	}
+
+	meter.Allow(context.Background(), "key:k1", "", "GET /orders")
+	if err := meter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	meter.HandleExport(rec, httptest.NewRequest(http.MethodGet, "/admin/usage/export?by_route=true", nil))
+	expected := "period,consumer,route,count\n" + time.Now().UTC().Format(dayLayout) + ",key:k1,GET /orders,1\n"
+	if rec.Body.String() != expected || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected export %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	meter.HandleExport(rec, httptest.NewRequest(http.MethodGet, "/admin/usage/export?from=yesterday", nil))
+	if !strings.Contains(rec.Body.String(), "2006-01-02") {
+		t.Errorf("expected invalid date error, got %s", rec.Body.String())
+	}
+}
+
+func TestNewMeterValidatesPlans(t *testing.T) {
+	if _, err := NewMeter(NewMemoryCounter(), Config{DefaultPlan: "free"}); err == nil {
+		t.Error("expected error for undefined default plan")
+	}
+	if _, err := NewMeter(NewMemoryCounter(), Config{Plans: map[string]Plan{"free": {}}, Consumers: map[string]string{"key:k1": "gold"}}); err == nil {
+		t.Error("expected error for undefined plan of consumer")
+	}
+}
+
+func TestMemoryCounterExpiresPeriods(t *testing.T) {
+	counter := NewMemoryCounter()
+	ctx := context.Background()
+	day := time.Date(2025, 6, 30, 23, 0, 0, 0, time.UTC)
+	counter.Take(ctx, "user:1", "/orders", day, Plan{})
+	counter.Take(ctx, "user:1", "/orders", day.Add(time.Minute), Plan{})
+	if len(counter.periods) != 2 {
+		t.Fatalf("unexpected periods: %v", counter.periods)
+	}
+
+	// the first request of a new month removes the previous day and month
+	counter.Take(ctx, "user:1", "/orders", day.Add(2*time.Hour), Plan{})
+	if daily, monthly, _ := counter.Usage(ctx, "user:1", day.Add(2*time.Hour)); daily != 1 || monthly != 1 || len(counter.periods) != 2 {
+		t.Errorf("old periods should be removed: %d %d %v", daily, monthly, counter.periods)
+	}
+}
+
+func TestPendingField(t *testing.T) {
+	for _, parts := range [][3]string{{"2025-06-13", "key:k1", "GET /orders"}, {"2025-06-13", "jwt:a|b", "GET /a|b"}, {"2025-06-13", `"`, `\`}} {
+		record, ok := parsePending(pendingField(parts[0], parts[1], parts[2]), 3)
+		if !ok || record.Day != parts[0] || record.Consumer != parts[1] || record.Route != parts[2] || record.Count != 3 {
+			t.Errorf("%q: %+v %v", parts, record, ok)
+		}
+	}
+	if _, ok := parsePending("2025-06-13|key:k1|GET /", 1); ok {
+		t.Error("malformed field must be skipped")
+	}
+}
@@ -20,46 +20,83 @@ package middleware
 
 import (
 	"Taurus/config"
+	"Taurus/pkg/apikey"
+	"Taurus/pkg/contextx"
 	"Taurus/pkg/httpx"
+	"crypto/subtle"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// ApiKeyAuthMiddleware validates the API key from the request headers
+// ApiKeyAuthMiddleware validates the API key from the request headers, any scope is accepted
 func ApiKeyAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("Authorization")
-		setAuthorizationToTrace(r, apiKey)
-		if apiKey == "" {
-			httpx.SendResponse(w, http.StatusUnauthorized, "Authorization is empty", nil)
-			return
-		}
+	return ApiKeyScopeMiddleware()(next)
+}
 
-		// Validate the API key (this is a placeholder, replace with actual validation logic)
-		if !isValidApiKey(apiKey) {
-			httpx.SendResponse(w, http.StatusUnauthorized, "Authorization is invalid", nil)
-			return
-		}
+// ApiKeyScopeMiddleware validates the API key from the X-API-Key or Authorization header and requires all the scopes.
+// Keys are verified by apikey.Default, the authorization of the config is accepted with all scopes for compatibility.
+// The identity of the key is added to the request context, see contextx.GetApiKey
+func ApiKeyScopeMiddleware(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// X-API-Key 优先, Authorization 可能携带的是 JWT
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				key = r.Header.Get("Authorization")
+			}
+			if key == "" {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusUnauthorized, "api_key.empty", "Authorization is empty"))
+				return
+			}
+
+			identity, err := verifyApiKey(r, key)
+			if err != nil {
+				setApiKeyToTrace(r, nil, err)
+				httpx.WriteError(w, r, httpx.Wrap(err, http.StatusUnauthorized, "api_key.invalid", "Authorization is invalid"))
+				return
+			}
+			setApiKeyToTrace(r, identity, nil)
+			if !apikey.HasScopes(identity.Scopes, scopes...) {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusForbidden, "api_key.scope_denied", "Authorization has no scope "+strings.Join(scopes, ", ")))
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r.WithContext(contextx.WithApiKey(r.Context(), identity)))
+		})
+	}
 }
 
-// isValidAPIKey checks if the provided API key is valid
-func isValidApiKey(apiKey string) bool {
-	// Implement your API key validation logic here
-	if apiKey == config.Core.Authorization {
-		return true
-	} else {
-		return false
+// verifyApiKey returns the identity of the key, the key may be prefixed with Bearer
+func verifyApiKey(r *http.Request, key string) (*contextx.ApiKeyIdentity, error) {
+	// the authorization of the config is compared with the whole header value
+	if legacy := config.Core.Authorization; legacy != "" && subtle.ConstantTimeCompare([]byte(key), []byte(legacy)) == 1 {
+		return &contextx.ApiKeyIdentity{ID: "authorization", Name: "config", Scopes: []string{"*"}}, nil
+	}
+	if apikey.Default == nil {
+		return nil, apikey.ErrInvalid
+	}
+	verified, err := apikey.Default.Verify(r.Context(), strings.TrimPrefix(key, "Bearer "))
+	if err != nil {
+		if !errors.Is(err, apikey.ErrInvalid) && !errors.Is(err, apikey.ErrExpired) && !errors.Is(err, apikey.ErrRevoked) {
+			log.Printf("Failed to verify api key: %v\n", err)
+		}
+		return nil, err
 	}
+	return &contextx.ApiKeyIdentity{ID: verified.ID, Name: verified.Name, Scopes: verified.Scopes}, nil
 }
 
-// 将Authorization添加到trace中
-func setAuthorizationToTrace(r *http.Request, authorization string) {
+// 将 API key 的身份添加到trace中, 不记录 key 本身
+func setApiKeyToTrace(r *http.Request, identity *contextx.ApiKeyIdentity, err error) {
 	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
-		span.SetAttributes(attribute.String("Authorization", authorization))
+		if err != nil {
+			span.SetAttributes(attribute.String("api_key.error", err.Error()))
+			return
+		}
+		span.SetAttributes(attribute.String("api_key.id", identity.ID), attribute.String("api_key.name", identity.Name))
 	}
 }
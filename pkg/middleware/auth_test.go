@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/apikey"
+	"Taurus/pkg/contextx"
+)
+
+func TestApiKeyScopeMiddleware(t *testing.T) {
+	old := apikey.Default
+	defer func() { apikey.Default = old }()
+	apikey.Default = apikey.NewManager(apikey.NewMemoryStore(), apikey.Config{})
+	_, raw, err := apikey.Default.Issue(context.Background(), "reader", []string{"orders:read"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := ApiKeyScopeMiddleware("orders:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := contextx.GetApiKey(r.Context())
+		w.Write([]byte(identity.Name))
+	}))
+	writer := ApiKeyScopeMiddleware("orders:write")(handler)
+
+	serve := func(h http.Handler, header string, value string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		if json.Unmarshal(rec.Body.Bytes(), &body) == nil && body.Code != 0 {
+			if rec.Code != body.Code {
+				t.Errorf("errors are sent with the real status, got %d for code %d", rec.Code, body.Code)
+			}
+			return body.Code, ""
+		}
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, name := serve(handler, "X-API-Key", raw); code != http.StatusOK || name != "reader" {
+		t.Errorf("x-api-key: %d %s", code, name)
+	}
+	if code, name := serve(handler, "Authorization", "Bearer "+raw); code != http.StatusOK || name != "reader" {
+		t.Errorf("bearer: %d %s", code, name)
+	}
+	if code, _ := serve(handler, "", ""); code != http.StatusUnauthorized {
+		t.Errorf("missing key: %d", code)
+	}
+	if code, _ := serve(handler, "X-API-Key", raw+"x"); code != http.StatusUnauthorized {
+		t.Errorf("invalid key: %d", code)
+	}
+	if code, _ := serve(writer, "X-API-Key", raw); code != http.StatusForbidden {
+		t.Errorf("missing scope: %d", code)
+	}
+}
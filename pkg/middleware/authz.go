@@ -0,0 +1,84 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"Taurus/pkg/authz"
+	"Taurus/pkg/httpx"
+)
+
+// ResourceFunc returns the attributes of the requested resource, used by the conditions of the policy
+type ResourceFunc func(r *http.Request) authz.Attributes
+
+// RequirePermission requires the permission for the subject authenticated by the jwt or api_key middleware,
+// it must be placed after the authentication middleware
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return RequirePermissionWith(permission, nil)
+}
+
+// RequirePermissionWith requires the permission, the conditions of the policy are evaluated with the attributes of the resource.
+// Attributes only known after loading the resource (e.g. the owner of an order) are checked by authz.Check in the handler
+func RequirePermissionWith(permission string, resource ResourceFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject, ok := authz.SubjectFromContext(r.Context())
+			if !ok {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusUnauthorized, "authz.unauthenticated", "Authentication is required"))
+				return
+			}
+			if authz.Default == nil {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusForbidden, "authz.not_configured", "Permission denied: authz is not configured"))
+				return
+			}
+
+			req := authz.Request{Subject: subject, Permission: permission, Target: r.Method + " " + r.URL.Path}
+			if resource != nil {
+				req.Resource = resource(r)
+			}
+			if decision := authz.Default.Authorize(r.Context(), req); !decision.Allowed {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusForbidden, "authz.denied", "Permission denied: "+permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestResource returns a ResourceFunc reading the attributes from the request, sources are path:<name>, query:<name> or header:<name>,
+// e.g. {"tenant": "path:tenant", "owner": "header:X-Owner-ID"}
+func RequestResource(sources map[string]string) ResourceFunc {
+	return func(r *http.Request) authz.Attributes {
+		attributes := make(authz.Attributes, len(sources))
+		for name, source := range sources {
+			kind, key, _ := strings.Cut(source, ":")
+			switch kind {
+			case "path":
+				attributes[name] = r.PathValue(key)
+			case "query":
+				attributes[name] = r.URL.Query().Get(key)
+			case "header":
+				attributes[name] = r.Header.Get(key)
+			}
+		}
+		return attributes
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/authz"
+	"Taurus/pkg/jwtx"
+)
+
+func TestRequirePermission(t *testing.T) {
+	old := authz.Default
+	defer func() { authz.Default = old }()
+	authz.Default, _ = authz.NewEngine(authz.Policy{Roles: map[string]authz.Role{
+		"viewer": {Permissions: []string{"orders:read"}},
+		"tenant_admin": {Grants: []authz.Grant{
+			{Permission: "orders:write", When: map[string]string{"resource.tenant": "subject.tenant"}},
+		}},
+	}}, authz.Options{})
+
+	mux := http.NewServeMux()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	mux.Handle("GET /orders", RequirePermission("orders:read")(ok))
+	mux.Handle("POST /tenants/{tenant}/orders", RequirePermissionWith("orders:write", RequestResource(map[string]string{"tenant": "path:tenant"}))(ok))
+
+	serve := func(method string, path string, claims *jwtx.Claims) int {
+		req := httptest.NewRequest(method, path, nil)
+		if claims != nil {
+			req = req.WithContext(jwtx.WithClaims(req.Context(), claims))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		if json.Unmarshal(rec.Body.Bytes(), &body) == nil && body.Code != 0 {
+			return body.Code
+		}
+		return rec.Code
+	}
+
+	viewer := &jwtx.Claims{Subject: "1", Extra: map[string]interface{}{"roles": "viewer"}}
+	admin := &jwtx.Claims{Subject: "2", Extra: map[string]interface{}{"roles": "tenant_admin", "tenant": "t1"}}
+	cases := []struct {
+		method string
+		path   string
+		claims *jwtx.Claims
+		code   int
+	}{
+		{http.MethodGet, "/orders", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/orders", viewer, http.StatusOK},
+		{http.MethodGet, "/orders", admin, http.StatusForbidden},
+		{http.MethodPost, "/tenants/t1/orders", admin, http.StatusOK},
+		{http.MethodPost, "/tenants/t2/orders", admin, http.StatusForbidden},
+		{http.MethodPost, "/tenants/t1/orders", viewer, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		if code := serve(c.method, c.path, c.claims); code != c.code {
+			t.Errorf("%s %s: got %d, want %d", c.method, c.path, code, c.code)
+		}
+	}
+
+	if decision := authz.Check(jwtx.WithClaims(context.Background(), admin), "orders:write", authz.Attributes{"tenant": "t1"}); !decision.Allowed {
+		t.Errorf("check in handler: %+v", decision)
+	}
+}
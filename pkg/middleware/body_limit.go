@@ -0,0 +1,57 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"Taurus/pkg/httpx"
+)
+
+// BodyLimitMiddleware 限制请求体大小, Content-Length 超过 maxBytes 时直接返回 413,
+// 否则读取超过 maxBytes 的请求体时返回 *http.MaxBytesError, 使用 IsBodyTooLarge 判断; maxBytes <= 0 时不限制
+func BodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				SendBodyTooLarge(w, r, maxBytes)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsBodyTooLarge reports whether the error is caused by a body exceeding the limit of BodyLimitMiddleware
+func IsBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// SendBodyTooLarge sends 413 by httpx.WriteError
+func SendBodyTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	httpx.WriteError(w, r, httpx.NewError(http.StatusRequestEntityTooLarge, "request.too_large", fmt.Sprintf("Request body exceeds %d bytes", maxBytes)))
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"Taurus/pkg/httpcache"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CacheConfig 响应缓存配置, 零值只计算 ETag 并保存到 httpcache.Default
+type CacheConfig struct {
+	Store       httpcache.Store // 响应存储, 默认 httpcache.Default
+	NoStore     bool            // 只计算 ETag 和处理 If-None-Match, 不保存响应
+	TTL         time.Duration   // 响应没有 max-age 时的保存时间, 默认 1 分钟
+	Vary        []string        // 参与缓存 key 的请求头, 同时加入响应的 Vary
+	Tags        []string        // 响应的标签, 支持路径参数, 例如 order:{id}
+	MaxBodySize int64           // 缓冲的最大响应体, 超过时直接发送, 不计算 ETag, 默认 1MB
+}
+
+// credentialHeaders 带有这些请求头的请求按调用方返回不同的响应, 不在 Vary 中时不使用共享缓存
+var credentialHeaders = []string{"Authorization", "X-API-Key", "Cookie"}
+
+var tagParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// CacheMiddleware 缓存 GET 响应: 200 响应带有强 ETag, If-None-Match 匹配时返回 304, code 不是 200 的 httpx.Response 不缓存;
+// 响应按 method、host、path、排序后的 query 和 Vary 请求头保存, 遵循请求和响应的 Cache-Control(no-store、no-cache、private、max-age、s-maxage),
+// 命中时返回保存的响应和 X-Cache: HIT. handler 可以通过 httpcache.Tag 给响应打标签, 数据更新后通过 httpcache.Invalidate 失效
+func CacheMiddleware(config CacheConfig) func(http.Handler) http.Handler {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+	vary := make([]string, 0, len(config.Vary))
+	for _, name := range config.Vary {
+		vary = append(vary, http.CanonicalHeaderKey(name))
+	}
+	slices.Sort(vary)
+	vary = slices.Compact(vary)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			store := config.Store
+			if store == nil {
+				store = httpcache.Default
+			}
+			directives := parseCacheControl(r.Header.Values("Cache-Control"))
+			if config.NoStore || directives.has("no-store") || !sharedCacheable(r, vary) {
+				store = nil
+			}
+			for _, name := range vary {
+				w.Header().Add("Vary", name)
+			}
+
+			key := cacheKey(r, vary)
+			if store != nil && !directives.has("no-cache") {
+				entry, err := store.Get(r.Context(), key)
+				if err != nil {
+					log.Printf("Response cache unavailable for %s %s: %v\n", r.Method, r.URL.Path, err)
+				}
+				if entry != nil {
+					setCacheToTrace(r, "hit")
+					serveEntry(w, r, entry)
+					return
+				}
+			}
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.WithContext(httpcache.WithTags(r.Context()))
+			recorder := &cacheRecorder{ResponseWriter: w, status: http.StatusOK, limit: config.MaxBodySize}
+			next.ServeHTTP(recorder, r)
+			if recorder.streaming {
+				return
+			}
+			// SendResponse 的错误以 HTTP 200 发送, 报文的 code 不是 200 时同样不缓存
+			if recorder.status != http.StatusOK || errorEnvelope(w.Header().Get("Content-Type"), recorder.body.Bytes()) {
+				recorder.send()
+				return
+			}
+
+			header := w.Header()
+			etag := header.Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(recorder.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				header.Set("ETag", etag)
+			}
+			if store != nil {
+				if ttl, ok := responseTTL(header, config.TTL, vary); ok {
+					entry := &httpcache.Entry{
+						Status:   recorder.status,
+						Header:   header.Clone(),
+						Body:     bytes.Clone(recorder.body.Bytes()),
+						ETag:     etag,
+						Tags:     cacheTags(r, config.Tags),
+						StoredAt: time.Now(),
+					}
+					entry.Header.Del("Date")
+					if err := store.Set(r.Context(), key, entry, ttl); err != nil {
+						log.Printf("Failed to cache response of %s %s: %v\n", r.Method, r.URL.Path, err)
+					}
+				}
+				header.Set("X-Cache", "MISS")
+			}
+			setCacheToTrace(r, "miss")
+			if etagMatch(r.Header.Get("If-None-Match"), etag) {
+				notModified(w)
+				return
+			}
+			recorder.send()
+		})
+	}
+}
+
+// sharedCacheable 带有凭证的请求只有凭证请求头在 Vary 中时才使用共享缓存, 避免把一个调用方的响应返回给其他调用方
+func sharedCacheable(r *http.Request, vary []string) bool {
+	for _, name := range credentialHeaders {
+		if r.Header.Get(name) != "" && !slices.Contains(vary, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheKey 由 method、host、path、按参数名排序的 query 和 Vary 请求头组成, HEAD 和 GET 共享缓存
+func cacheKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString("GET ")
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.Path)
+	if query, err := url.ParseQuery(r.URL.RawQuery); err == nil && len(query) > 0 {
+		b.WriteString("?")
+		b.WriteString(query.Encode())
+	} else if err != nil {
+		b.WriteString("?")
+		b.WriteString(r.URL.RawQuery)
+	}
+	for _, name := range vary {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// responseTTL 返回响应的保存时间, 不可保存时返回 false
+func responseTTL(header http.Header, ttl time.Duration, vary []string) (time.Duration, bool) {
+	if header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	// 响应按 key 之外的请求头变化时无法正确命中
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" || (name != "" && !slices.Contains(vary, name)) {
+				return 0, false
+			}
+		}
+	}
+	directives := parseCacheControl(header.Values("Cache-Control"))
+	if directives.has("no-store") || directives.has("no-cache") || directives.has("private") {
+		return 0, false
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return ttl, true
+}
+
+// errorEnvelope 判断响应是否为 code 不是 200 的 httpx.Response, 其他响应体不是错误
+func errorEnvelope(contentType string, body []byte) bool {
+	var envelope struct {
+		Code    *int    `json:"code" xml:"Code"`
+		Message *string `json:"message" xml:"Message"`
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		if json.Unmarshal(body, &envelope) != nil {
+			return false
+		}
+	case strings.HasSuffix(mediaType, "xml"):
+		if xml.Unmarshal(body, &envelope) != nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return envelope.Code != nil && envelope.Message != nil && *envelope.Code != http.StatusOK
+}
+
+// cacheTags 替换配置的标签中的路径参数, 并加上 handler 通过 httpcache.Tag 添加的标签
+func cacheTags(r *http.Request, tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		result = append(result, tagParam.ReplaceAllStringFunc(tag, func(param string) string {
+			return r.PathValue(param[1 : len(param)-1])
+		}))
+	}
+	result = append(result, httpcache.Tags(r.Context())...)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+func serveEntry(w http.ResponseWriter, r *http.Request, entry *httpcache.Entry) {
+	header := w.Header()
+	for name, values := range entry.Header {
+		if name != "Vary" {
+			header[name] = values
+		}
+	}
+	header.Set("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+	header.Set("X-Cache", "HIT")
+	if etagMatch(r.Header.Get("If-None-Match"), entry.ETag) {
+		notModified(w)
+		return
+	}
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		w.Write(entry.Body)
+	}
+}
+
+// notModified 304 响应不包含响应体和内容相关的头
+func notModified(w http.ResponseWriter) {
+	for _, name := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+		w.Header().Del(name)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagMatch 使用 If-None-Match 的弱比较, 忽略 W/ 前缀
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, value := range strings.Split(ifNoneMatch, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControl Cache-Control 指令, 指令名为小写
+type cacheControl map[string]string
+
+func parseCacheControl(values []string) cacheControl {
+	directives := make(cacheControl)
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+func (c cacheControl) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// cacheRecorder 缓冲 200 响应以计算 ETag, 响应体超过 limit 或 handler 调用 Flush 时改为直接发送
+type cacheRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int64
+	streaming   bool
+}
+
+func (w *cacheRecorder) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *cacheRecorder) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(p)
+	}
+	if int64(w.body.Len()+len(p)) > w.limit {
+		w.stream()
+		return w.ResponseWriter.Write(p)
+	}
+	return w.body.Write(p)
+}
+
+// stream 发送已缓冲的响应, 之后的写入直接发送
+func (w *cacheRecorder) stream() {
+	if !w.streaming {
+		w.streaming = true
+		w.send()
+	}
+}
+
+// send 发送状态码和缓冲的响应体
+func (w *cacheRecorder) send() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+func (w *cacheRecorder) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.stream()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the original writer, used by http.ResponseController
+func (w *cacheRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func setCacheToTrace(r *http.Request, result string) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("http.cache", result))
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/httpcache"
+	"Taurus/pkg/httpx"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	store := httpcache.NewMemoryStore(10)
+	calls := 0
+	mux := http.NewServeMux()
+	mux.Handle("GET /orders/{id}", CacheMiddleware(CacheConfig{Store: store, Tags: []string{"order:{id}"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		httpcache.Tag(r, "orders")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%q,"version":%d}`, r.PathValue("id"), calls)
+	})))
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve("/orders/1?b=2&a=1", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected a miss with ETag, got %d %v", first.Code, first.Header())
+	}
+	second := serve("/orders/1?a=1&b=2", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() || second.Header().Get("ETag") != etag || calls != 1 {
+		t.Fatalf("expected a hit for the normalized query, got %v %s, calls %d", second.Header(), second.Body.String(), calls)
+	}
+	if rec := serve("/orders/1?a=1&b=2", http.Header{"If-None-Match": {`"other", ` + etag}}); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("/orders/1?a=1&b=2", http.Header{"Cache-Control": {"no-cache"}}); rec.Header().Get("X-Cache") != "MISS" || calls != 2 {
+		t.Errorf("expected no-cache to revalidate, got %v, calls %d", rec.Header(), calls)
+	}
+	if rec := serve("/orders/1", http.Header{"Authorization": {"Bearer token"}}); rec.Header().Get("X-Cache") != "" || rec.Header().Get("ETag") == "" || calls != 3 {
+		t.Errorf("expected credentialed request not cached, got %v, calls %d", rec.Header(), calls)
+	}
+
+	serve("/orders/2", nil)
+	old := httpcache.Default
+	httpcache.Default = store
+	defer func() { httpcache.Default = old }()
+	if err := httpcache.Invalidate(context.Background(), "order:1"); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve("/orders/1?a=1&b=2", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected order:1 invalidated, got %v", rec.Header())
+	}
+	if rec := serve("/orders/2", nil); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected order:2 still cached, got %v", rec.Header())
+	}
+	httpcache.Invalidate(context.Background(), "orders")
+	if rec := serve("/orders/2", nil); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected handler tag invalidated, got %v", rec.Header())
+	}
+}
+
+func TestCacheMiddlewareSkipsErrorEnvelopes(t *testing.T) {
+	store := httpcache.NewMemoryStore(10)
+	handler := CacheMiddleware(CacheConfig{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			httpx.SendResponse(w, http.StatusNotFound, "order not found", nil)
+		case "/xml":
+			httpx.SendResponse(w, httpx.StatusInvalidParams, "bad id", map[string]string{"Content-Type": "application/xml"})
+		default:
+			httpx.SendResponse(w, http.StatusOK, map[string]string{"code": "A1"}, nil)
+		}
+	}))
+	for _, path := range []string{"/json", "/xml", "/ok"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", path, rec.Code)
+		}
+	}
+	if store.Len() != 1 {
+		t.Errorf("only the successful response should be cached, got %d entries", store.Len())
+	}
+}
+
+func TestCacheMiddlewareHonorsResponseCacheControl(t *testing.T) {
+	store := httpcache.NewMemoryStore(10)
+	cases := []struct {
+		header http.Header
+		stored bool
+	}{
+		{http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{http.Header{"Cache-Control": {"private, max-age=60"}}, false},
+		{http.Header{"Cache-Control": {"no-store"}}, false},
+		{http.Header{"Cache-Control": {"max-age=0"}}, false},
+		{http.Header{"Set-Cookie": {"session=1"}}, false},
+		{http.Header{"Vary": {"Accept-Language"}}, false},
+	}
+	for i, c := range cases {
+		handler := CacheMiddleware(CacheConfig{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, values := range c.header {
+				w.Header()[name] = values
+			}
+			w.Write([]byte("ok"))
+		}))
+		target := fmt.Sprintf("/case/%d", i)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if stored := rec.Header().Get("X-Cache") == "HIT"; stored != c.stored {
+			t.Errorf("%v: expected stored %v", c.header, c.stored)
+		}
+	}
+}
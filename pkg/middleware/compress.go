@@ -0,0 +1,306 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"Taurus/pkg/httpx"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressConfig 响应压缩配置, 零值使用默认值
+type CompressConfig struct {
+	Encodings    []string // 支持的编码, 按服务端优先级排列, 可选 br, zstd, gzip, deflate, 默认 DefaultCompressEncodings
+	Level        int      // 压缩级别, 各编码的取值范围不同(gzip/deflate 1-9, br 0-11, zstd 1-22), 0 使用各编码的默认级别
+	MinSize      int      // 响应体小于该字节数时不压缩, 默认 1024
+	ContentTypes []string // 压缩的 Content-Type, 支持 text/* 和 application/*+json 形式的通配, 默认 DefaultCompressContentTypes
+}
+
+var (
+	// DefaultCompressEncodings 默认支持的编码
+	DefaultCompressEncodings = []string{"br", "zstd", "gzip", "deflate"}
+	// DefaultCompressContentTypes 默认压缩的 Content-Type, 图片、视频等已经压缩过的类型不再压缩
+	DefaultCompressContentTypes = []string{
+		"text/*", "application/json", "application/*+json", "application/javascript", "application/xml",
+		"application/*+xml", "application/x-ndjson", "image/svg+xml", "application/wasm",
+	}
+)
+
+// compressor 可以复用的压缩器
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressors 编码到压缩器工厂的映射, level 为 0 时使用默认级别
+var compressors = map[string]func(level int) (compressor, error){
+	"gzip": func(level int) (compressor, error) {
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(io.Discard, level)
+	},
+	// HTTP 的 deflate 是 zlib 格式(RFC 1950), 不是原始的 deflate 数据
+	"deflate": func(level int) (compressor, error) {
+		if level == 0 {
+			level = zlib.DefaultCompression
+		}
+		return zlib.NewWriterLevel(io.Discard, level)
+	},
+	"br": func(level int) (compressor, error) {
+		if level == 0 {
+			level = 4 // 动态内容使用较快的级别, 静态文件使用预压缩的 .br
+		}
+		if level < brotli.BestSpeed || level > brotli.BestCompression {
+			return nil, fmt.Errorf("invalid brotli level %d", level)
+		}
+		return brotli.NewWriterLevel(io.Discard, level), nil
+	},
+	"zstd": func(level int) (compressor, error) {
+		speed := zstd.SpeedDefault
+		if level != 0 {
+			speed = zstd.EncoderLevelFromZstd(level)
+		}
+		return zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(speed), zstd.WithEncoderConcurrency(1))
+	},
+}
+
+// CompressMiddleware 根据 Accept-Encoding 压缩响应, 设置 Vary: Accept-Encoding 并删除 Content-Length.
+// 响应先缓冲 MinSize 字节再决定是否压缩, 已经设置 Content-Encoding、Range 请求、websocket 升级和不在 ContentTypes 中的响应不压缩.
+// 压缩器通过 sync.Pool 复用. 配置无效时 panic, 配置文件中的参数在加载路由时校验
+func CompressMiddleware(config CompressConfig) func(http.Handler) http.Handler {
+	c, err := compileCompress(config)
+	if err != nil {
+		panic(err)
+	}
+	return c.middleware
+}
+
+// compress 编译后的压缩配置
+type compress struct {
+	encodings    []string
+	pools        map[string]*sync.Pool
+	minSize      int
+	contentTypes []string
+}
+
+func compileCompress(config CompressConfig) (*compress, error) {
+	c := &compress{
+		encodings:    config.Encodings,
+		pools:        make(map[string]*sync.Pool),
+		minSize:      config.MinSize,
+		contentTypes: config.ContentTypes,
+	}
+	if len(c.encodings) == 0 {
+		c.encodings = DefaultCompressEncodings
+	}
+	if c.minSize <= 0 {
+		c.minSize = 1024
+	}
+	if len(c.contentTypes) == 0 {
+		c.contentTypes = DefaultCompressContentTypes
+	}
+	for _, encoding := range c.encodings {
+		factory, ok := compressors[encoding]
+		if !ok {
+			return nil, fmt.Errorf("unsupported compress encoding %s", encoding)
+		}
+		// 创建一次以校验压缩级别
+		if _, err := factory(config.Level); err != nil {
+			return nil, err
+		}
+		level := config.Level
+		c.pools[encoding] = &sync.Pool{New: func() interface{} {
+			w, _ := factory(level)
+			return w
+		}}
+	}
+	return c, nil
+}
+
+func (c *compress) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Range 请求的偏移量针对未压缩的内容, websocket 升级需要原始连接
+		if r.Header.Get("Range") != "" || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: w,
+			compress:       c,
+			encoding:       httpx.NegotiateEncoding(r.Header.Get("Accept-Encoding"), c.encodings),
+			head:           r.Method == http.MethodHead,
+		}
+		next.ServeHTTP(cw, r)
+		// 不使用 defer, handler panic 时由外层的 error 中间件发送 500
+		cw.finish()
+	})
+}
+
+// compressible reports whether the media type is in the content types
+func (c *compress) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range c.contentTypes {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard && mediaType == pattern ||
+			wildcard && strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix) && len(mediaType) >= len(prefix)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter 缓冲响应的前 minSize 字节, 然后决定直接发送还是压缩
+type compressWriter struct {
+	http.ResponseWriter
+	compress    *compress
+	encoding    string // 协商的编码, 为空表示客户端不接受压缩
+	head        bool
+	status      int
+	wroteHeader bool // handler 调用了 WriteHeader
+	decided     bool // 已经决定是否压缩并发送了响应头
+	writer      compressor
+	buf         bytes.Buffer
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	// 没有响应体的状态码直接发送
+	if status == http.StatusNoContent || status == http.StatusNotModified || w.head {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if w.buf.Len()+len(p) < w.compress.minSize {
+			return w.buf.Write(p)
+		}
+		w.buf.Write(p)
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide 发送响应头和缓冲的数据, large 表示响应体达到了 minSize
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && w.buf.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	eligible := header.Get("Content-Encoding") == "" && w.compress.compressible(header.Get("Content-Type"))
+	if eligible {
+		// 响应随 Accept-Encoding 变化, 缓存需要区分
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && large && w.encoding != "" {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		// 压缩后的内容不同, 强 ETag 改为弱 ETag
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.writer = w.compress.pools[w.encoding].Get().(compressor)
+		w.writer.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush 流式响应: 立即决定是否压缩, 并刷新压缩器和底层连接
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.writer != nil {
+		w.writer.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the original writer, used by http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish 发送小于 minSize 的响应, 或者关闭压缩器并放回池中
+func (w *compressWriter) finish() {
+	if !w.wroteHeader {
+		// handler 没有写响应
+		return
+	}
+	if !w.decided {
+		w.decide(false)
+		return
+	}
+	if w.writer != nil {
+		w.writer.Close()
+		w.writer.Reset(io.Discard)
+		w.compress.pools[w.encoding].Put(w.writer)
+		w.writer = nil
+	}
+}
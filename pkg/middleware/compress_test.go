@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"Taurus/pkg/httpx"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressMiddleware(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("taurus ", 500) + `"}`
+	handler := CompressMiddleware(CompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "999")
+			w.Write([]byte(large[:100]))
+			w.Write([]byte(large[100:]))
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(large))
+		}
+	}))
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for accept, encoding := range map[string]string{"gzip": "gzip", "gzip, br": "br", "br;q=0.5, zstd": "zstd", "*": "br"} {
+		rec := serve("/large", accept)
+		if rec.Header().Get("Content-Encoding") != encoding || rec.Header().Get("Content-Length") != "" || rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%s: unexpected headers %v", accept, rec.Header())
+		}
+		reader, err := decoders[encoding](rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, err := io.ReadAll(reader); err != nil || string(body) != large {
+			t.Errorf("%s: body not restored: %v", accept, err)
+		}
+	}
+
+	if rec := serve("/large", "identity"); rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != large || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected uncompressed response with Vary, got %v", rec.Header())
+	}
+	if rec := serve("/small", "gzip"); rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"ok":true}` {
+		t.Errorf("expected small response uncompressed, got %v", rec.Header())
+	}
+	if rec := serve("/image", "gzip"); rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("expected image uncompressed, got %v", rec.Header())
+	}
+
+	if _, err := compileCompress(CompressConfig{Encodings: []string{"lzma"}}); err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
+
+func TestPrecompressedFileServer(t *testing.T) {
+	root := fstest.MapFS{
+		"app.js":    {Data: []byte("console.log('taurus')")},
+		"app.js.br": {Data: []byte("brotli")},
+		"app.js.gz": {Data: []byte("gzip")},
+		"style.css": {Data: []byte("body{}")},
+	}
+	server := httpx.FileServer(http.FS(root), true)
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/app.js", "gzip, br")
+	if rec.Body.String() != "brotli" || rec.Header().Get("Content-Encoding") != "br" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("expected app.js.br, got %s %v", rec.Body.String(), rec.Header())
+	}
+	if rec := serve("/app.js", "gzip"); rec.Body.String() != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("expected app.js.gz, got %s %v", rec.Body.String(), rec.Header())
+	}
+	if rec := serve("/app.js", ""); rec.Body.String() != "console.log('taurus')" || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected original file, got %s", rec.Body.String())
+	}
+	if rec := serve("/style.css", "br"); rec.Body.String() != "body{}" || rec.Header().Get("Vary") != "" {
+		t.Errorf("expected original file without Vary, got %v", rec.Header())
+	}
+
+	// 与 http.Dir 一起使用
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.css"), []byte("a{}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "index.css.gz"), []byte("gz"), 0o644)
+	req := httptest.NewRequest(http.MethodGet, "/index.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	httpx.FileServer(http.Dir(dir), true).ServeHTTP(rec, req)
+	if rec.Body.String() != "gz" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("expected index.css.gz, got %s %v", rec.Body.String(), rec.Header())
+	}
+}
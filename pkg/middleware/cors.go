@@ -21,32 +21,265 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// CorsMiddleware adds CORS headers to the response
+// CorsPolicy 跨域策略
+//
+// AllowedOrigins 支持:
+//   - "*": 允许所有来源, 不能与 AllowCredentials 同时使用, 否则任意网站都可以带着用户的 cookie 读取响应
+//   - "https://app.example.com": 精确匹配
+//   - "https://*.example.com": 匹配任意子域名, 不匹配 example.com 本身
+//   - "regex:^https://(a|b)\.example\.com$": 正则匹配
+type CorsPolicy struct {
+	AllowedOrigins      []string      // 允许的来源, 为空表示不允许跨域
+	AllowedMethods      []string      // 允许的方法, 默认 GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
+	AllowedHeaders      []string      // 允许的请求头, "*" 表示允许所有请求头
+	ExposedHeaders      []string      // 浏览器可以读取的响应头
+	AllowCredentials    bool          // 是否允许携带 cookie 等凭证
+	MaxAge              time.Duration // 预检结果的缓存时间, 0 表示不设置
+	AllowPrivateNetwork bool          // 是否允许公网页面访问内网地址 (Private Network Access)
+}
+
+// DefaultCorsPolicy 默认跨域策略, 允许所有来源, 不允许携带凭证, CorsMiddleware 使用, 自定义策略请使用 CorsPolicyMiddleware
+var DefaultCorsPolicy = CorsPolicy{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+	AllowedHeaders: []string{"Content-Type", "Authorization"},
+	MaxAge:         24 * time.Hour,
+}
+
+// corsPolicies 命名的跨域策略, 配置文件中的路由分组通过名称引用
+var (
+	corsPoliciesMu sync.RWMutex
+	corsPolicies   = make(map[string]CorsPolicy)
+)
+
+// SetCorsPolicy 注册命名的跨域策略, 例如 spa, admin, cors 中间件通过 policy 参数引用
+func SetCorsPolicy(name string, policy CorsPolicy) error {
+	if _, err := compileCorsPolicy(policy); err != nil {
+		return fmt.Errorf("cors policy %s: %v", name, err)
+	}
+	corsPoliciesMu.Lock()
+	defer corsPoliciesMu.Unlock()
+	corsPolicies[name] = policy
+	return nil
+}
+
+// GetCorsPolicy 获取命名的跨域策略
+func GetCorsPolicy(name string) (CorsPolicy, bool) {
+	corsPoliciesMu.RLock()
+	defer corsPoliciesMu.RUnlock()
+	policy, ok := corsPolicies[name]
+	return policy, ok
+}
+
+// CorsMiddleware adds CORS headers to the response by DefaultCorsPolicy
 func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		//  w.Header().Set("Access-Control-Allow-Origin", "https://your-allowed-origin.com")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "86400")
+	return defaultCors(next)
+}
+
+var defaultCors = CorsPolicyMiddleware(DefaultCorsPolicy)
+
+// CorsPolicyMiddleware 按跨域策略处理跨域请求, 只有真正的预检请求(OPTIONS + Origin + Access-Control-Request-Method)
+// 会被直接响应, 其他 OPTIONS 请求交给路由处理. 策略无效时 panic, 配置文件中的策略在加载路由时校验
+func CorsPolicyMiddleware(policy CorsPolicy) func(http.Handler) http.Handler {
+	cors, err := compileCorsPolicy(policy)
+	if err != nil {
+		panic(err)
+	}
+	return cors.middleware
+}
 
-		setCorsToTrace(r, "*", "GET, POST, PUT, DELETE, OPTIONS", "Content-Type, Authorization", "86400")
+// corsPolicy 编译后的跨域策略
+type corsPolicy struct {
+	allowAll       bool
+	origins        map[string]bool
+	subdomains     []string // 后缀, 例如 https://*.example.com 为 scheme https:// 和后缀 .example.com
+	patterns       []*regexp.Regexp
+	methods        map[string]bool
+	methodList     string
+	allowAllHeader bool
+	headers        map[string]bool
+	headerList     string
+	exposed        string
+	credentials    bool
+	maxAge         string
+	privateNetwork bool
+}
+
+func compileCorsPolicy(policy CorsPolicy) (*corsPolicy, error) {
+	c := &corsPolicy{
+		origins:        make(map[string]bool),
+		methods:        make(map[string]bool),
+		headers:        make(map[string]bool),
+		credentials:    policy.AllowCredentials,
+		privateNetwork: policy.AllowPrivateNetwork,
+		exposed:        strings.Join(policy.ExposedHeaders, ", "),
+	}
+	for _, origin := range policy.AllowedOrigins {
+		switch {
+		case origin == "*":
+			if policy.AllowCredentials {
+				return nil, fmt.Errorf("allowed origin * can not be used with allow_credentials, list the allowed origins instead")
+			}
+			c.allowAll = true
+		case strings.HasPrefix(origin, "regex:"):
+			pattern, err := regexp.Compile(strings.TrimPrefix(origin, "regex:"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid origin pattern %q: %v", origin, err)
+			}
+			c.patterns = append(c.patterns, pattern)
+		case strings.Contains(origin, "://*."):
+			c.subdomains = append(c.subdomains, strings.ToLower(origin))
+		default:
+			c.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+		}
+	}
+
+	methods := make([]string, 0, len(policy.AllowedMethods))
+	for _, method := range policy.AllowedMethods {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	if len(methods) == 0 {
+		methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	}
+	for _, method := range methods {
+		c.methods[method] = true
+	}
+	c.methodList = strings.Join(methods, ", ")
+
+	for _, header := range policy.AllowedHeaders {
+		if header == "*" {
+			c.allowAllHeader = true
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	c.headerList = strings.Join(policy.AllowedHeaders, ", ")
+
+	if policy.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+	return c, nil
+}
 
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+func (c *corsPolicy) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// 响应随 Origin 变化时必须声明 Vary, 否则缓存会把一个来源的响应返回给另一个来源
+		if !c.allowAll {
+			w.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.preflight(w, r, origin)
 			return
 		}
 
+		if origin != "" && c.allowOrigin(origin) {
+			c.setOrigin(w, origin)
+			if c.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+			}
+			setCorsToTrace(r, origin, c.methodList, c.headerList, c.maxAge)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+func (c *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	headers := requestedHeaders(r)
+	if !c.allowOrigin(origin) || !c.methods[method] || !c.allowHeaders(headers) {
+		setCorsToTrace(r, "rejected: "+origin, method, strings.Join(headers, ", "), "")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	c.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", c.methodList)
+	if len(headers) > 0 {
+		// 回显请求的头, "*" 在携带凭证时不被浏览器认可
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.maxAge != "" {
+		w.Header().Set("Access-Control-Max-Age", c.maxAge)
+	}
+	if c.privateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		w.Header().Set("Access-Control-Allow-Private-Network", "true")
+	}
+	setCorsToTrace(r, origin, c.methodList, strings.Join(headers, ", "), c.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *corsPolicy) setOrigin(w http.ResponseWriter, origin string) {
+	if c.allowAll {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *corsPolicy) allowOrigin(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.origins[origin] {
+		return true
+	}
+	for _, subdomain := range c.subdomains {
+		scheme, suffix, _ := strings.Cut(subdomain, "*")
+		if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) && len(origin) > len(scheme)+len(suffix) {
+			return true
+		}
+	}
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsPolicy) allowHeaders(headers []string) bool {
+	if c.allowAllHeader {
+		return true
+	}
+	for _, header := range headers {
+		if !c.headers[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders returns the canonical headers of Access-Control-Request-Headers
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+	return headers
+}
+
 func setCorsToTrace(r *http.Request, origin string, methods string, headers string, maxAge string) {
 	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
 		span.SetAttributes(attribute.String("Access-Control-Allow-Origin", fmt.Sprintf("origin: %v, methods: %v, headers: %v, maxAge: %v", origin, methods, headers, maxAge)))
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCorsPolicyMiddleware(t *testing.T) {
+	handler := CorsPolicyMiddleware(CorsPolicy{
+		AllowedOrigins:      []string{"https://app.example.com", "https://*.preview.example.com", `regex:^https://admin-\d+\.example\.com$`},
+		AllowedHeaders:      []string{"Content-Type", "token"},
+		ExposedHeaders:      []string{"X-Request-ID"},
+		AllowCredentials:    true,
+		MaxAge:              10 * time.Minute,
+		AllowPrivateNetwork: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	}))
+
+	serve := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, origin := range []string{"https://app.example.com", "https://pr-1.preview.example.com", "https://admin-7.example.com"} {
+		rec := serve(http.MethodGet, map[string]string{"Origin": origin})
+		if rec.Header().Get("Access-Control-Allow-Origin") != origin || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("origin %s should be allowed: %v", origin, rec.Header())
+		}
+		if rec.Header().Get("Vary") != "Origin" || rec.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+			t.Errorf("unexpected headers: %v", rec.Header())
+		}
+	}
+	for _, origin := range []string{"https://evil.com", "https://preview.example.com", "https://app.example.com.evil.com", "http://admin-7.example.com"} {
+		rec := serve(http.MethodGet, map[string]string{"Origin": origin})
+		if rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Body.String() != "GET" {
+			t.Errorf("origin %s should not be allowed: %v", origin, rec.Header())
+		}
+	}
+
+	// real preflight is answered by the middleware
+	rec := serve(http.MethodOptions, map[string]string{
+		"Origin":                                 "https://app.example.com",
+		"Access-Control-Request-Method":          "PUT",
+		"Access-Control-Request-Headers":         "content-type,token",
+		"Access-Control-Request-Private-Network": "true",
+	})
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 ||
+		rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Token" ||
+		rec.Header().Get("Access-Control-Max-Age") != "600" ||
+		rec.Header().Get("Access-Control-Allow-Private-Network") != "true" {
+		t.Errorf("unexpected preflight response: %d %v", rec.Code, rec.Header())
+	}
+
+	rec = serve(http.MethodOptions, map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "X-Other",
+	})
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight with a disallowed header should be rejected: %d %v", rec.Code, rec.Header())
+	}
+
+	// OPTIONS without Access-Control-Request-Method is not a preflight
+	rec = serve(http.MethodOptions, map[string]string{"Origin": "https://app.example.com"})
+	if rec.Body.String() != "OPTIONS" {
+		t.Error("plain OPTIONS request should reach the handler")
+	}
+
+	// the default policy answers * without Vary
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://any.com")
+	CorsMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Vary") != "" {
+		t.Errorf("unexpected default policy headers: %v", rec.Header())
+	}
+
+	// * with credentials would let any site read the responses of a logged-in user
+	if err := SetCorsPolicy("unsafe", CorsPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}); err == nil {
+		t.Error("* with credentials should be rejected")
+	}
+}
@@ -19,25 +19,23 @@
 package middleware
 
 import (
-	"Taurus/pkg/httpx"
 	"fmt"
-	"log"
 	"net/http"
-	"runtime/debug"
+
+	"Taurus/pkg/httpx"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// ErrorHandlerMiddleware handles errors and recovers from panics in HTTP requests
+// ErrorHandlerMiddleware handles errors and recovers from panics in HTTP requests,
+// the panic is rendered by httpx.WriteError which logs the stack trace
 func ErrorHandlerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				// Log the error and stack trace
-				log.Printf("Recovered from panic: %v\n%s", err, debug.Stack())
 				setErrorToTrace(r, err)
-				httpx.SendResponse(w, http.StatusInternalServerError, "Internal Server Error", nil)
+				httpx.WriteError(w, r, httpx.Wrap(fmt.Errorf("recovered from panic: %v", err), http.StatusInternalServerError, "internal", "Internal Server Error"))
 			}
 		}()
 		// Call the next handler
@@ -0,0 +1,227 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/idempotency"
+	"Taurus/pkg/jwtx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdempotencyConfig 幂等配置, 零值使用默认值
+type IdempotencyConfig struct {
+	Store       idempotency.Store // 记录存储, 默认 idempotency.Default
+	Methods     []string          // 检查 Idempotency-Key 的方法, 默认 [POST, PATCH]
+	TTL         time.Duration     // 响应的保存时间, 默认 idempotency.DefaultTTL
+	LockTTL     time.Duration     // 处理中的请求保留 key 的时间, 超过后允许重试, 默认 idempotency.DefaultLockTTL
+	MaxBodySize int64             // 请求体和保存的响应体的最大字节数, 默认 1MB
+}
+
+// IdempotencyMiddleware 带有 Idempotency-Key 请求头的 POST/PATCH 请求只执行一次:
+// 相同 key 和相同请求的重复请求返回保存的响应(带 Idempotent-Replayed: true), 第一次请求还在处理时返回 409,
+// 相同 key 但请求不同时返回 422. 5xx 响应不保存, 客户端可以重试. key 按 API key 或 JWT 的调用方隔离
+func IdempotencyMiddleware(config IdempotencyConfig) func(http.Handler) http.Handler {
+	methods := make(map[string]bool)
+	for _, method := range config.Methods {
+		methods[method] = true
+	}
+	if len(methods) == 0 {
+		methods = map[string]bool{http.MethodPost: true, http.MethodPatch: true}
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusBadRequest, "idempotency.key_too_long", "Idempotency-Key must not exceed 255 characters"))
+				return
+			}
+			store, ttl, lockTTL := config.Store, config.TTL, config.LockTTL
+			if store == nil {
+				store = idempotency.Default
+			}
+			if ttl <= 0 {
+				ttl = idempotency.DefaultTTL
+			}
+			if lockTTL <= 0 {
+				lockTTL = idempotency.DefaultLockTTL
+			}
+
+			body, complete, err := readLimited(r.Body, config.MaxBodySize)
+			if err != nil && !IsBodyTooLarge(err) {
+				httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "request.invalid", "Failed to read request body"))
+				return
+			}
+			if err != nil || !complete {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusRequestEntityTooLarge, "request.too_large", "Request body of idempotent request exceeds "+strconv.FormatInt(config.MaxBodySize, 10)+" bytes"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key = idempotencyScope(r) + key
+			reserved := idempotency.NewReservation(idempotency.Fingerprint(r.Method, r.URL.RequestURI(), body))
+			existing, err := store.Reserve(r.Context(), key, reserved, lockTTL)
+			if err != nil {
+				// 存储不可用时不阻塞请求
+				log.Printf("Idempotency store unavailable, request %s %s is not deduplicated: %v\n", r.Method, r.URL.Path, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if existing != nil {
+				setIdempotencyToTrace(r, "replay")
+				switch {
+				case existing.Fingerprint != reserved.Fingerprint:
+					httpx.WriteError(w, r, httpx.NewError(http.StatusUnprocessableEntity, "idempotency.key_reused", "Idempotency-Key is already used by a different request"))
+				case existing.InFlight():
+					w.Header().Set("Retry-After", "1")
+					httpx.WriteError(w, r, httpx.NewError(http.StatusConflict, "idempotency.in_flight", "A request with the same Idempotency-Key is being processed"))
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			setIdempotencyToTrace(r, "first")
+			recorder := &idempotencyRecorder{ResponseWriter: w, limit: config.MaxBodySize, status: http.StatusOK}
+			released := false
+			release := func() {
+				if !released {
+					released = true
+					// 请求可能已经被取消, 使用新的 context 释放
+					if err := store.Release(context.WithoutCancel(r.Context()), key, reserved); err != nil {
+						log.Printf("Failed to release idempotency key: %v\n", err)
+					}
+				}
+			}
+			defer release() // handler panic 时释放 key
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError || recorder.overflow {
+				return
+			}
+			record := &idempotency.Record{
+				Token:       reserved.Token,
+				Fingerprint: reserved.Fingerprint,
+				Status:      recorder.status,
+				Header:      recorder.Header().Clone(),
+				Body:        recorder.body.Bytes(),
+			}
+			record.Header.Del("Date")
+			if err := store.Complete(context.WithoutCancel(r.Context()), key, reserved, record, ttl); err != nil {
+				log.Printf("Failed to save idempotent response: %v\n", err)
+				return
+			}
+			released = true
+		})
+	}
+}
+
+// idempotencyScope 不同调用方的 key 互不影响
+func idempotencyScope(r *http.Request) string {
+	if identity, ok := contextx.GetApiKey(r.Context()); ok {
+		return "key:" + identity.ID + ":"
+	}
+	if claims, ok := jwtx.GetClaims(r.Context()); ok && claims.Subject != "" {
+		return "user:" + claims.Subject + ":"
+	}
+	return ""
+}
+
+func replay(w http.ResponseWriter, record *idempotency.Record) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// idempotencyRecorder 发送响应的同时记录状态码和响应体
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int64
+	overflow    bool // 响应体超过 limit, 不保存
+}
+
+func (w *idempotencyRecorder) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotencyRecorder) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if int64(w.body.Len()+len(p)) > w.limit {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *idempotencyRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the original writer, used by http.ResponseController
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func setIdempotencyToTrace(r *http.Request, result string) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("idempotency", result))
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/idempotency"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := IdempotencyMiddleware(IdempotencyConfig{Store: idempotency.NewMemoryStore()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("X-Order", "order-1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int32{"call": n})
+	}))
+	serve := func(path, key, body string, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		if apiKey != "" {
+			req = req.WithContext(contextx.WithApiKey(req.Context(), &contextx.ApiKeyIdentity{ID: apiKey}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve("/orders", "k1", `{"amount":1}`, "")
+	replayed := serve("/orders", "k1", `{"amount":1}`, "")
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() ||
+		replayed.Header().Get("X-Order") != "order-1" || replayed.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected replayed response, got %d %s %v", replayed.Code, replayed.Body.String(), replayed.Header())
+	}
+	if calls != 1 {
+		t.Fatalf("expected handler called once, got %d", calls)
+	}
+
+	if rec := serve("/orders", "k1", `{"amount":2}`, ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a different body, got %d", rec.Code)
+	}
+	if rec := serve("/orders", "k1", `{"amount":1}`, "tenant-key"); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("keys of different consumers must not collide, got %d %v", rec.Code, rec.Header())
+	}
+	serve("/orders", "", `{"amount":1}`, "")
+	serve("/orders", "", `{"amount":1}`, "")
+	if calls != 4 {
+		t.Errorf("requests without key are not deduplicated, got %d calls", calls)
+	}
+
+	// 第一次请求还在处理时返回 409
+	done := make(chan struct{})
+	go func() {
+		serve("/slow", "k2", "", "")
+		close(done)
+	}()
+	<-started
+	if rec := serve("/slow", "k2", "", ""); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for in-flight request, got %d", rec.Code)
+	}
+	close(release)
+	<-done
+
+	// 5xx 响应不保存, 可以重试
+	serve("/fail", "k3", "", "")
+	serve("/fail", "k3", "", "")
+	if calls != 7 {
+		t.Errorf("expected failed request retried, got %d calls", calls)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/realip"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IPFilterMiddleware 按命名的 IP 列表检查客户端 IP, 拒绝时返回 403.
+// 每个请求都读取最新的列表, 通过 ipfilter.Set 更新的规则立即生效, 未定义的列表允许所有请求
+func IPFilterMiddleware(list string) func(http.Handler) http.Handler {
+	return ipFilter(list, func(ip string) bool { return ipfilter.Allowed(list, ip) })
+}
+
+func ipFilter(list string, allowed func(ip string) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := realip.FromRequest(r)
+			ok := allowed(ip)
+			setIPFilterToTrace(r, list, ip, ok)
+			if !ok {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusForbidden, "ip_filter.denied", "Access denied for ip "+ip))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setIPFilterToTrace(r *http.Request, list, ip string, allowed bool) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("IPFilter", fmt.Sprintf("list: %s, ip: %s, allowed: %v", list, ip, allowed)))
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/router"
+)
+
+func TestIPFilterMiddleware(t *testing.T) {
+	defer ipfilter.Delete("admin")
+	if err := ipfilter.Set("admin", ipfilter.Rule{Allow: []string{"10.0.0.0/8"}}); err != nil {
+		t.Fatal(err)
+	}
+	handler := IPFilterMiddleware("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "10.0.0.1") // 不受信任的对端伪造的请求头不生效
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		if json.Unmarshal(rec.Body.Bytes(), &body) == nil && body.Code != 0 {
+			return body.Code
+		}
+		return rec.Code
+	}
+
+	if code := serve("10.1.2.3:1000"); code != http.StatusOK {
+		t.Errorf("expected allowed, got %d", code)
+	}
+	if code := serve("198.51.100.1:1000"); code != http.StatusForbidden {
+		t.Errorf("expected forbidden, got %d", code)
+	}
+
+	// 列表更新后立即生效
+	if err := ipfilter.Set("admin", ipfilter.Rule{Deny: []string{"10.1.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+	if code := serve("10.1.2.3:1000"); code != http.StatusForbidden {
+		t.Errorf("expected forbidden after reload, got %d", code)
+	}
+	if code := serve("198.51.100.1:1000"); code != http.StatusOK {
+		t.Errorf("expected allowed after reload, got %d", code)
+	}
+}
+
+func TestIPFilterFromParams(t *testing.T) {
+	if _, err := router.GetMiddleware("ip_filter", router.MiddlewareParams{"deny": []interface{}{"10.0.0.0/33"}}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+	mw, err := router.GetMiddleware("ip_filter", router.MiddlewareParams{"deny": []interface{}{"2001:db8::/32"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:443"
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Body.Len() == 0 {
+		t.Error("expected the IPv6 client denied")
+	}
+}
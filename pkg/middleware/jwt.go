@@ -19,90 +19,82 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
 
 	"Taurus/pkg/httpx"
-	"Taurus/pkg/redisx"
-	"Taurus/pkg/util"
+	"Taurus/pkg/jwtx"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// JwtMiddleware verifies the access token of the Authorization: Bearer header by jwtx.Default,
+// the verified claims are added to the request context, see jwtx.GetClaims
 func JwtMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 通过http header中的token解析来认证
-		token := r.Header.Get("token")
-		setJwtToTrace(r, token)
-		if token == "" {
-			httpx.SendResponse(w, http.StatusUnauthorized, "Jwt Token is empty", nil)
+		if jwtx.Default == nil {
+			// 配置错误, 不是客户端的问题
+			httpx.WriteError(w, r, httpx.NewError(http.StatusInternalServerError, "jwt.not_configured", "Jwt is not configured"))
 			return
 		}
-
-		// 解析token中包含的相关信息（有效载荷）
-		claims, err := util.ParseToken(token)
-		if err != nil {
-			httpx.SendResponse(w, http.StatusUnauthorized, "Jwt Token is parse error", nil)
+		token := jwtx.BearerToken(r.Header.Get("Authorization"))
+		if token == "" {
+			httpx.WriteError(w, r, httpx.NewError(http.StatusUnauthorized, "jwt.empty", "Jwt Token is empty"))
 			return
 		}
 
-		ua := r.Header.Get("User-Agent")
-		val, err := redisx.Redis.HGet(r.Context(), strconv.Itoa(int(claims.Uid)), ua)
-
-		if err != nil { // 说明该token是其他User-Agent的token（比如说电脑端的token的map key 是User-Agent，当然不能用来登录手机端）
-			httpx.SendResponse(w, http.StatusUnauthorized, "Jwt Token form redis error", nil)
+		claims, err := jwtx.Default.Verify(r.Context(), token)
+		setJwtToTrace(r, claims, err)
+		switch {
+		case err == nil:
+		case errors.Is(err, jwtx.ErrExpired):
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusUnauthorized, "jwt.expired", "Jwt Token is expired"))
 			return
-		}
-
-		if token != val { // 请求携带的token与redis中存储的token不一致，说明是旧的token
-			httpx.SendResponse(w, http.StatusUnauthorized, "Jwt Token is invalid", nil)
+		case errors.Is(err, jwtx.ErrRevoked):
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusUnauthorized, "jwt.revoked", "Jwt Token is revoked"))
 			return
-		}
-		// 处理过期token
-		if time.Now().Unix() > claims.ExpiresAt {
-			httpx.SendResponse(w, http.StatusUnauthorized, "Jwt Token is expired", nil)
+		case errors.Is(err, jwtx.ErrInvalidToken):
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusUnauthorized, "jwt.invalid", "Jwt Token is invalid"))
+			return
+		default:
+			// 吊销列表或远程 JWKS 不可用
+			httpx.WriteError(w, r, httpx.Wrap(err, http.StatusServiceUnavailable, "jwt.unavailable", "Jwt Token can not be verified"))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(jwtx.WithClaims(r.Context(), claims)))
 	})
 }
 
-func setJwtToTrace(r *http.Request, token string) {
+// 将 token 的 sub 和 jti 添加到trace中, 不记录 token 本身
+func setJwtToTrace(r *http.Request, claims *jwtx.Claims, err error) {
 	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
-		span.SetAttributes(attribute.String("Jwt", token))
+		if err != nil {
+			span.SetAttributes(attribute.String("jwt.error", err.Error()))
+			return
+		}
+		span.SetAttributes(attribute.String("jwt.sub", claims.Subject), attribute.String("jwt.jti", claims.ID))
 	}
 }
 
 // ------------------  例子 ------------------
 /*
-登录成功的时候需要签发JWT, 分发token（其他功能需要身份验证，给前端存储的）,
-token返回给前端以后， 前端需要存储下来，每次请求要带过来, 服务端需要将token存储到Redis, 方便后面校验
+登录成功的时候签发 token 对, access token 放在 Authorization: Bearer 请求头中, 过期后用 refresh token 换新的 token 对
 
-// -----> 登录成功，存token <-----
-token, err := util.GenerateToken(user.ID, user.UserName) // 生产token
+// -----> 登录成功，签发token <-----
+pair, err := jwtx.Default.IssuePair(r.Context(), strconv.FormatUint(uint64(user.ID), 10), map[string]interface{}{"username": user.UserName})
 if err != nil {
-	return httpx.Response{
-		Status: http.StatusInternalServerError,
-		Msg:    "token签发失败！",
-		Error:  err.Error(),
-	}
-}
-// 签发token后，存储到redis中（为了保证token唯一有效）
-ua := r.Header.Get("User-Agent") // key用户user-agent可以保证换了浏览器token失效
-m := map[string]string{ua: token}
-redisx.Redis.HSet(r.Context(), strconv.FormatUint(uint64(user.ID), 10), m)
-return response.Response{
-	Status: http.StatusOK,
-	Msg:    "登录成功！",
-	Data:   map[string]string{"token": token},
+	httpx.WriteError(w, r, httpx.Wrap(err, http.StatusInternalServerError, "jwt.issue_failed", "token签发失败！"))
+	return
 }
+httpx.SendResponse(w, http.StatusOK, pair, nil)
+
+// -----> handler中读取claims <------
+claims, _ := jwtx.GetClaims(r.Context())
+username := claims.Get("username")
 
-// -----> 中间件校验token <------
-// 判断该token是不是最新token（从redis里查）
-ua := r.Header.Get("User-Agent")
-// 存的时候  key = userid  value = map["User-Agent"]token, 取的时候  取 UID 对于的 map里面的key="User-Agent"对应的值
-val, err := redisx.Redis.HGet(r.Context(), strconv.Itoa(int(claims.Uid)), ua).Result()
+// -----> 刷新和退出登录 <------
+POST /auth/refresh {"refresh_token": "..."}   旧的 refresh token 失效, 重复使用会吊销整个会话
+POST /auth/revoke  Authorization: Bearer ...  吊销整个会话
 */
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/jwtx"
+)
+
+func TestJwtMiddleware(t *testing.T) {
+	old := jwtx.Default
+	defer func() { jwtx.Default = old }()
+	signer, _ := jwtx.GenerateSigningKey(jwtx.EdDSA)
+	jwtx.Default, _ = jwtx.NewService(signer, jwtx.Config{Issuer: "taurus"})
+	pair, err := jwtx.Default.IssuePair(context.Background(), "42", map[string]interface{}{"username": "tom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := JwtMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := jwtx.GetClaims(r.Context())
+		w.Write([]byte(claims.Subject + " " + claims.Get("username")))
+	}))
+	serve := func(authorization string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var body struct {
+			Code int `json:"code"`
+		}
+		if json.Unmarshal(rec.Body.Bytes(), &body) == nil && body.Code != 0 {
+			if rec.Code != body.Code {
+				t.Errorf("errors are sent with the real status, got %d for code %d", rec.Code, body.Code)
+			}
+			return body.Code, ""
+		}
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, body := serve("Bearer " + pair.AccessToken); code != http.StatusOK || body != "42 tom" {
+		t.Errorf("valid token: %d %s", code, body)
+	}
+	for _, authorization := range []string{"", pair.AccessToken, "Bearer " + pair.RefreshToken, "Bearer " + pair.AccessToken + "x"} {
+		if code, _ := serve(authorization); code != http.StatusUnauthorized {
+			t.Errorf("%q: %d", authorization, code)
+		}
+	}
+	jwtx.Default.Revoke(context.Background(), pair.AccessToken)
+	if code, _ := serve("Bearer " + pair.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: %d", code)
+	}
+
+	// a missing jwt service is a server error, not the client's
+	jwtx.Default = nil
+	if code, _ := serve("Bearer " + pair.AccessToken); code != http.StatusInternalServerError {
+		t.Errorf("jwt not configured: %d", code)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/metering"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QuotaMiddleware 计量调用方的请求并执行套餐的日/月配额, 超出配额时返回 429 和到配额重置的 Retry-After.
+// 必须放在 jwt 或 api_key 中间件之后, 匿名请求和未配置 metering.Default 时不计量
+func QuotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meter := metering.Default
+		if meter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		consumer, plan, ok := meter.Consumer(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.Pattern
+		if route == "" {
+			route = r.Method + " " + r.URL.Path
+		}
+		decision := meter.Allow(r.Context(), consumer, plan, route)
+		setQuotaToTrace(r, decision)
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+			httpx.WriteError(w, r, httpx.NewError(http.StatusTooManyRequests, "quota.exceeded", "Quota of plan "+plan+" exceeded"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func setQuotaToTrace(r *http.Request, decision metering.Decision) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(
+			attribute.String("quota.consumer", decision.Usage.Consumer),
+			attribute.String("quota.plan", decision.Usage.Plan),
+			attribute.Bool("quota.allowed", decision.Allowed),
+		)
+	}
+}
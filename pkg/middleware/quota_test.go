@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/db"
+	"Taurus/pkg/metering"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestQuotaMiddleware(t *testing.T) {
+	db.InitDB("middleware_quota", "sqlite", filepath.Join(t.TempDir(), "usage.db"), logger.Default.LogMode(logger.Silent), 1, 0)
+	meter, err := metering.NewMeter(metering.NewMemoryCounter(), metering.Config{
+		Plans:       map[string]metering.Plan{"free": {Daily: 1}},
+		DefaultPlan: "free",
+		DB:          "middleware_quota",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := metering.Default
+	metering.Default = meter
+	defer func() { metering.Default = old }()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /orders", QuotaMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if key != "" {
+			req = req.WithContext(contextx.WithApiKey(req.Context(), &contextx.ApiKeyIdentity{ID: key}))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("k1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first request allowed, got %d", rec.Code)
+	}
+	rec := serve("k1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	for i := 0; i < 2; i++ {
+		if rec := serve(""); rec.Code != http.StatusOK {
+			t.Fatalf("anonymous requests are not metered, got %d", rec.Code)
+		}
+	}
+
+	usage, _ := meter.Usage(context.Background(), "key:k1")
+	if usage.Daily != 1 {
+		t.Errorf("expected the rejected request not counted, got %+v", usage)
+	}
+}
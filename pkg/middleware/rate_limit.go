@@ -19,24 +19,92 @@
 package middleware
 
 import (
-	"Taurus/pkg/httpx"
-	"Taurus/pkg/util"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/jwtx"
+	"Taurus/pkg/ratelimit"
+	"Taurus/pkg/realip"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// RateLimitMiddleware 限流中间件
-func RateLimitMiddleware(limiter *util.CompositeRateLimiter) func(http.Handler) http.Handler {
+// RateLimitKeyFunc returns the rate limit key of the request
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitKey returns a key function combining the parts, parts are ip, api_key, user, route and header:<name>.
+// ip is the client IP resolved by realip, api_key and user fall back to the ip when the request has no API key or JWT, user requires the jwt middleware before the limiter.
+// No parts means ip
+func RateLimitKey(parts ...string) (RateLimitKeyFunc, error) {
+	if len(parts) == 0 {
+		parts = []string{"ip"}
+	}
+	for _, part := range parts {
+		switch kind, name, _ := strings.Cut(part, ":"); kind {
+		case "ip", "api_key", "user", "route":
+		case "header":
+			if name == "" {
+				return nil, fmt.Errorf("rate limit key %s requires a header name", part)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rate limit key %s", part)
+		}
+	}
+	return func(r *http.Request) string {
+		values := make([]string, len(parts))
+		for i, part := range parts {
+			values[i] = rateLimitKeyPart(r, part)
+		}
+		return strings.Join(values, ":")
+	}, nil
+}
+
+func rateLimitKeyPart(r *http.Request, part string) string {
+	switch kind, name, _ := strings.Cut(part, ":"); kind {
+	case "api_key":
+		if identity, ok := contextx.GetApiKey(r.Context()); ok {
+			return "key=" + identity.ID
+		}
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			// 未经校验的 key 只保存哈希, 避免密钥出现在 Redis 中
+			sum := sha256.Sum256([]byte(key))
+			return "key=" + hex.EncodeToString(sum[:8])
+		}
+	case "user":
+		if claims, ok := jwtx.GetClaims(r.Context()); ok && claims.Subject != "" {
+			return "user=" + claims.Subject
+		}
+	case "route":
+		if r.Pattern != "" {
+			return "route=" + r.Pattern
+		}
+		return "route=" + r.Method + " " + r.URL.Path
+	case "header":
+		return name + "=" + r.Header.Get(name)
+	}
+	return "ip=" + realip.FromRequest(r)
+}
+
+// RateLimitMiddleware 限流中间件, 所有响应都带有 RateLimit-* 头, 超限时返回 429 和 Retry-After, key 为 nil 时按 IP 限流
+func RateLimitMiddleware(limiter *ratelimit.Limiter, key RateLimitKeyFunc) func(http.Handler) http.Handler {
+	if key == nil {
+		key, _ = RateLimitKey()
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr // 获取请求的IP地址
-			allowed, message := limiter.Allow(ip)
-			setRateLimitToTrace(r, allowed, message)
-			if !allowed {
-				httpx.SendResponse(w, http.StatusTooManyRequests, message, nil)
+			result := limiter.Allow(r.Context(), key(r))
+			for name, value := range result.Headers() {
+				w.Header().Set(name, value)
+			}
+			setRateLimitToTrace(r, result)
+			if !result.Allowed {
+				httpx.WriteError(w, r, httpx.NewError(http.StatusTooManyRequests, "rate_limit.exceeded", "Too many requests, retry after "+w.Header().Get("Retry-After")+"s"))
 				return
 			}
 			// 如果请求被允许，继续处理下一个中间件或处理器
@@ -45,8 +113,8 @@ func RateLimitMiddleware(limiter *util.CompositeRateLimiter) func(http.Handler)
 	}
 }
 
-func setRateLimitToTrace(r *http.Request, allowed bool, message string) {
+func setRateLimitToTrace(r *http.Request, result ratelimit.Result) {
 	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
-		span.SetAttributes(attribute.String("RateLimit", fmt.Sprintf("allowed: %v, message: %v", allowed, message)))
+		span.SetAttributes(attribute.String("RateLimit", fmt.Sprintf("allowed: %v, tier: %s, remaining: %d", result.Allowed, result.Tier.Name, result.Remaining)))
 	}
 }
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Taurus/pkg/jwtx"
+	"Taurus/pkg/ratelimit"
+	"Taurus/pkg/router"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewLocalStore(), ratelimit.Tier{Limit: 2, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RateLimitMiddleware(limiter, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 同一个 IP 的不同端口共享配额
+	if rec := serve("10.0.0.1:1000"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("expected allowed with 1 remaining, got %d %v", rec.Code, rec.Header())
+	}
+	serve("10.0.0.1:1001")
+	rec := serve("10.0.0.1:1002")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Errorf("unexpected RateLimit-Policy %s", rec.Header().Get("RateLimit-Policy"))
+	}
+	if rec := serve("10.0.0.2:1000"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another ip allowed, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	key, err := RateLimitKey("route", "user", "header:X-Tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Forwarded-For", "198.51.100.1") // 不受信任的对端伪造的请求头不改变 ip
+	if got := key(req); got != "route=GET /orders:ip=10.0.0.1:X-Tenant=acme" {
+		t.Errorf("unexpected key without user: %s", got)
+	}
+	req = req.WithContext(jwtx.WithClaims(req.Context(), &jwtx.Claims{Subject: "u1"}))
+	if got := key(req); got != "route=GET /orders:user=u1:X-Tenant=acme" {
+		t.Errorf("unexpected key with user: %s", got)
+	}
+
+	if _, err := RateLimitKey("cookie"); err == nil {
+		t.Error("expected error for unsupported key")
+	}
+}
+
+func TestRateLimitFromParams(t *testing.T) {
+	mw, err := rateLimitFromParams(router.MiddlewareParams{
+		"key":          []interface{}{"ip"},
+		"tiers":        []interface{}{map[string]interface{}{"limit": 1, "window": "1m"}},
+		"global_limit": 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status codes %v", codes)
+	}
+
+	if _, err := rateLimitFromParams(router.MiddlewareParams{"limit": 0}); err == nil {
+		t.Error("expected error for zero limit")
+	}
+}
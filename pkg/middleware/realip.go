@@ -0,0 +1,42 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"Taurus/pkg/contextx"
+	"Taurus/pkg/realip"
+)
+
+// RealIPMiddleware 使用 realip.Default 解析客户端 IP 并写入 contextx.RequestContext, 之后的中间件通过 realip.FromRequest 获取.
+// 应用启动时包裹在所有路由之外, 不修改 r.RemoteAddr
+func RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := realip.Default.ClientIP(r)
+		if rc, ok := contextx.GetRequestContext(r.Context()); ok {
+			rc.ClientIP = ip
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := contextx.WithRequestContext(r.Context(), &contextx.RequestContext{AtTime: time.Now(), ClientIP: ip})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
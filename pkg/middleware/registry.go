@@ -0,0 +1,258 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package middleware
+
+import (
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/ratelimit"
+	"Taurus/pkg/router"
+	"Taurus/pkg/telemetry"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 注册内置中间件, 配置文件中的路由通过名称引用, 例如:
+//
+//	middleware:
+//	  - name: rate_limit
+//	    params: {limit: 10, window: 1s, key: [route, ip]}
+func init() {
+	// params: policy, 通过 SetCorsPolicy 注册的策略名称; 或者直接配置 allowed_origins, allowed_methods, allowed_headers,
+	// exposed_headers, allow_credentials, max_age, allow_private_network; 没有参数时使用 DefaultCorsPolicy
+	router.RegisterMiddleware("cors", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		if len(params) == 0 {
+			return CorsMiddleware, nil
+		}
+		policy, ok := GetCorsPolicy(params.String("policy", ""))
+		if name := params.String("policy", ""); name != "" && !ok {
+			return nil, fmt.Errorf("cors policy %s not found", name)
+		}
+		if !ok {
+			policy = CorsPolicy{
+				AllowedOrigins:      params.Strings("allowed_origins"),
+				AllowedMethods:      params.Strings("allowed_methods"),
+				AllowedHeaders:      params.Strings("allowed_headers"),
+				ExposedHeaders:      params.Strings("exposed_headers"),
+				AllowCredentials:    params.Bool("allow_credentials", false),
+				MaxAge:              params.Duration("max_age", 0),
+				AllowPrivateNetwork: params.Bool("allow_private_network", false),
+			}
+		}
+		cors, err := compileCorsPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		return cors.middleware, nil
+	})
+	router.RegisterMiddlewareFunc("error", ErrorHandlerMiddleware)
+
+	// params: scopes, key 需要拥有的全部 scope, 例如 [orders:read], 为空时只校验 key
+	router.RegisterMiddleware("api_key", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		return ApiKeyScopeMiddleware(params.Strings("scopes")...), nil
+	})
+	router.RegisterMiddlewareFunc("jwt", JwtMiddleware)
+	// 日/月配额, 必须放在 jwt 或 api_key 中间件之后
+	router.RegisterMiddlewareFunc("quota", QuotaMiddleware)
+
+	// params: permission, 需要的权限, 例如 orders:read; resource, 条件使用的资源属性, 例如 {tenant: "path:tenant"}.
+	// 必须放在 jwt 或 api_key 中间件之后
+	router.RegisterMiddleware("authz", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		permission := params.String("permission", "")
+		if permission == "" {
+			return nil, errors.New("authz middleware requires permission")
+		}
+		var resource ResourceFunc
+		if sources := params.StringMap("resource"); len(sources) > 0 {
+			resource = RequestResource(sources)
+		}
+		return RequirePermissionWith(permission, resource), nil
+	})
+
+	// params: tracer, 追踪器名称, 默认 http-server
+	router.RegisterMiddleware("trace", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		return TraceMiddleware(telemetry.GetTracer(params.String("tracer", "http-server"))), nil
+	})
+
+	// params: tiers, 限流层级 [{name, limit, window, burst}], 或者单个层级 limit(默认 100), window(默认 1s), burst;
+	// key, 限流 key 的组成 [ip, api_key, user, route, header:<name>], 默认 [route, ip]; global_limit, 路由所有请求共享的每窗口请求数.
+	// 旧参数 ip_capacity, global_capacity, fill_interval 分别对应 limit, global_limit, window.
+	// 使用 ratelimit.Default 存储, 配置 rate_limit.store 为 redis 时多个副本共享配额
+	router.RegisterMiddleware("rate_limit", rateLimitFromParams)
+
+	// ip_filter 中间件, list 引用 ip_filter.lists 中的命名列表(支持热更新), 或者直接配置 allow/deny
+	router.RegisterMiddleware("ip_filter", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		if list := params.String("list", ""); list != "" {
+			return IPFilterMiddleware(list), nil
+		}
+		filter, err := ipfilter.New(ipfilter.Rule{Allow: params.Strings("allow"), Deny: params.Strings("deny")})
+		if err != nil {
+			return nil, err
+		}
+		return ipFilter("", filter.Allowed), nil // 路由参数中的规则随路由加载, 不能热更新
+	})
+
+	// params: max_bytes, 请求体的最大字节数, 超过时返回 413
+	router.RegisterMiddleware("body_limit", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		maxBytes := params.Int("max_bytes", 0)
+		if maxBytes <= 0 {
+			return nil, errors.New("body_limit middleware requires max_bytes")
+		}
+		return BodyLimitMiddleware(int64(maxBytes)), nil
+	})
+
+	// params: timeout, 请求的截止时间, 例如 5s; status, 超时返回的状态码 503 或 504, 默认 504
+	router.RegisterMiddleware("timeout", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		timeout := params.Duration("timeout", 0)
+		if timeout <= 0 {
+			return nil, errors.New("timeout middleware requires timeout")
+		}
+		status := params.Int("status", http.StatusGatewayTimeout)
+		if status != http.StatusServiceUnavailable && status != http.StatusGatewayTimeout {
+			return nil, fmt.Errorf("unsupported timeout status %d", status)
+		}
+		return TimeoutMiddleware(timeout, status), nil
+	})
+
+	// params: encodings, 支持的编码 [br, zstd, gzip, deflate]; level, 压缩级别; min_size, 最小压缩字节数, 默认 1024;
+	// content_types, 压缩的 Content-Type, 例如 [text/*, application/json]
+	router.RegisterMiddleware("compress", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		c, err := compileCompress(CompressConfig{
+			Encodings:    params.Strings("encodings"),
+			Level:        params.Int("level", 0),
+			MinSize:      params.Int("min_size", 0),
+			ContentTypes: params.Strings("content_types"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.middleware, nil
+	})
+
+	// params: methods, 检查 Idempotency-Key 的方法, 默认 [POST, PATCH]; ttl, 响应的保存时间; lock_ttl, 处理中的请求保留 key 的时间;
+	// max_body_size, 请求体和保存的响应体的最大字节数, 默认 1MB. 使用 idempotency.Default 存储
+	router.RegisterMiddleware("idempotency", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		return IdempotencyMiddleware(IdempotencyConfig{
+			Methods:     params.Strings("methods"),
+			TTL:         params.Duration("ttl", 0),
+			LockTTL:     params.Duration("lock_ttl", 0),
+			MaxBodySize: int64(params.Int("max_body_size", 0)),
+		}), nil
+	})
+
+	// params: ttl, 响应没有 max-age 时的保存时间, 默认 1m; vary, 参与缓存 key 的请求头; tags, 响应的标签, 支持路径参数, 例如 order:{id};
+	// store, false 时只计算 ETag 不保存响应, 默认 true; max_body_size, 缓冲的最大响应体, 默认 1MB. 使用 httpcache.Default 存储
+	router.RegisterMiddleware("cache", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		return CacheMiddleware(CacheConfig{
+			NoStore:     !params.Bool("store", true),
+			TTL:         params.Duration("ttl", 0),
+			Vary:        params.Strings("vary"),
+			Tags:        params.Strings("tags"),
+			MaxBodySize: int64(params.Int("max_body_size", 0)),
+		}), nil
+	})
+
+	// params: request 和 response 两个转换步骤, 例如:
+	//   request:  {rename: {userName: user.name}, remove: [password], query_to_body: {page: page}, claim_headers: {X-User-ID: uid}, xml_to_json: true}
+	//   response: {rename: {...}, remove: [...], envelope: {code: errcode, message: errmsg, data: result}, json_to_xml: true}
+	router.RegisterMiddleware("transform", func(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+		request, response := params.Map("request"), params.Map("response")
+		if request == nil && response == nil {
+			return nil, errors.New("transform requires request or response params")
+		}
+		config := TransformConfig{
+			Request: RequestTransform{
+				BodyTransform: bodyTransform(request),
+				QueryToBody:   request.StringMap("query_to_body"),
+				ClaimHeaders:  request.StringMap("claim_headers"),
+			},
+			Response: ResponseTransform{BodyTransform: bodyTransform(response)},
+		}
+		if envelope := response.Map("envelope"); envelope != nil {
+			config.Response.Envelope = &Envelope{
+				Code:    envelope.String("code", ""),
+				Message: envelope.String("message", ""),
+				Data:    envelope.String("data", ""),
+			}
+		} else if response.Bool("envelope", false) {
+			config.Response.Envelope = &Envelope{}
+		}
+		return TransformMiddleware(config), nil
+	})
+}
+
+func bodyTransform(params router.MiddlewareParams) BodyTransform {
+	return BodyTransform{
+		Rename:      params.StringMap("rename"),
+		Remove:      params.Strings("remove"),
+		XMLToJSON:   params.Bool("xml_to_json", false),
+		JSONToXML:   params.Bool("json_to_xml", false),
+		XMLRoot:     params.String("xml_root", ""),
+		MaxBodySize: int64(params.Int("max_body_size", 0)),
+	}
+}
+
+func rateLimitFromParams(params router.MiddlewareParams) (router.MiddlewareFunc, error) {
+	window := params.Duration("window", params.Duration("fill_interval", time.Second))
+	var tiers []ratelimit.Tier
+	for _, tier := range params.Maps("tiers") {
+		tiers = append(tiers, ratelimit.Tier{
+			Name:   tier.String("name", ""),
+			Limit:  tier.Int("limit", 0),
+			Window: tier.Duration("window", time.Second),
+			Burst:  tier.Int("burst", 0),
+		})
+	}
+	if len(tiers) == 0 {
+		tiers = append(tiers, ratelimit.Tier{
+			Limit:  params.Int("limit", params.Int("ip_capacity", 100)),
+			Window: window,
+			Burst:  params.Int("burst", 0),
+		})
+	}
+	limiter, err := ratelimit.NewLimiter(nil, tiers...)
+	if err != nil {
+		return nil, err
+	}
+	parts := params.Strings("key")
+	if len(parts) == 0 {
+		parts = []string{"route", "ip"}
+	}
+	key, err := RateLimitKey(parts...)
+	if err != nil {
+		return nil, err
+	}
+	limit := RateLimitMiddleware(limiter, key)
+
+	globalLimit := params.Int("global_limit", params.Int("global_capacity", 0))
+	if globalLimit <= 0 {
+		return limit, nil
+	}
+	global, err := ratelimit.NewLimiter(nil, ratelimit.Tier{Name: "global", Limit: globalLimit, Window: window})
+	if err != nil {
+		return nil, err
+	}
+	globalKey, _ := RateLimitKey("route")
+	limitGlobal := RateLimitMiddleware(global, globalKey)
+	// 先检查 key 的配额, 被拒绝的请求不消耗路由的共享配额
+	return func(next http.Handler) http.Handler {
+		return limit(limitGlobal(next))
+	}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"Taurus/pkg/httpx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TimeoutMiddleware 为请求设置截止时间, 到期时取消请求的 context, 如果 handler 还没有写响应, 返回 status(503 或 504, 默认 504).
+// 响应不被缓冲, 流式响应可以正常 Flush; 超时后 handler 的写入返回 http.ErrHandlerTimeout. 不支持 websocket 升级
+func TimeoutMiddleware(timeout time.Duration, status int) func(http.Handler) http.Handler {
+	if status != http.StatusServiceUnavailable {
+		status = http.StatusGatewayTimeout
+	}
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, h: make(http.Header), ctx: ctx}
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p) // 交给外层的 error 中间件处理
+			case <-done:
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				setTimeoutToTrace(r, timeout, ctx.Err())
+				if !tw.wroteHeader && ctx.Err() == context.DeadlineExceeded {
+					httpx.WriteError(w, r, httpx.NewError(status, "request.timeout", fmt.Sprintf("Request timed out after %s", timeout)))
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter 超时后拒绝 handler 的写入, 避免在 ServeHTTP 返回后继续使用 ResponseWriter.
+// 与 http.TimeoutHandler 相同, handler 使用自己的 Header, 第一次写入时才复制到真正的响应, 超时响应和 handler 不会同时修改 Header
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	ctx         context.Context
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return
+	}
+	if code >= 100 && code < 200 {
+		// 1xx 可以发送多次, 例如 103 Early Hints
+		tw.copyHeader()
+		tw.w.WriteHeader(code)
+		return
+	}
+	tw.writeHeader(code)
+}
+
+// writeHeader 复制 handler 的 Header 并发送状态码, 调用方持有 mu
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.copyHeader()
+	tw.w.WriteHeader(code)
+}
+
+// expired 截止时间已到, handler 可能比超时响应先看到 ctx.Done, 此时的写入同样被拒绝. 调用方持有 mu
+func (tw *timeoutWriter) expired() bool {
+	if tw.ctx.Err() == context.DeadlineExceeded {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush 支持流式响应
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return
+	}
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		tw.writeHeader(http.StatusOK)
+		flusher.Flush()
+	}
+}
+
+func setTimeoutToTrace(r *http.Request, timeout time.Duration, err error) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("Timeout", fmt.Sprintf("timeout: %s, err: %v", timeout, err)))
+	}
+}
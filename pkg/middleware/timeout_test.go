@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	canceled := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Late", "1") // the handler's own header, not shared with the timeout response
+		if _, err := w.Write([]byte("late")); err != http.ErrHandlerTimeout {
+			t.Errorf("expected ErrHandlerTimeout, got %v", err)
+		}
+		close(canceled)
+	})
+	rec := httptest.NewRecorder()
+	TimeoutMiddleware(20*time.Millisecond, 0)(slow).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	<-canceled
+	var body struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusGatewayTimeout || body.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected 504 envelope, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Late") != "" {
+		t.Error("headers set after the timeout should not be sent")
+	}
+
+	// 503 is sent as it is, not mapped to 200 like SendResponse
+	canceled = make(chan struct{})
+	rec = httptest.NewRecorder()
+	TimeoutMiddleware(20*time.Millisecond, http.StatusServiceUnavailable)(slow).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	<-canceled
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "1")
+		w.Write([]byte("ok"))
+	})
+	rec = httptest.NewRecorder()
+	TimeoutMiddleware(time.Second, http.StatusServiceUnavailable)(fast).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Body.String() != "ok" || rec.Header().Get("X-Handler") != "1" {
+		t.Errorf("expected handler response, got %s %v", rec.Body.String(), rec.Header())
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	handler := BodyLimitMiddleware(8)(ValidationMiddleware(&struct {
+		Name string `json:"name"`
+	}{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })))
+	serve := func(body string, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = contentLength
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(`{"a":1}`, 7); rec.Body.String() != "ok" {
+		t.Errorf("expected small body accepted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(`{"name":"taurus"}`, 17); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 by Content-Length, got %d", rec.Code)
+	}
+	// Content-Length 不可信时, 读取请求体超过限制同样返回 413
+	if rec := serve(`{"name":"taurus"}`, 1); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 while reading, got %d %s", rec.Code, rec.Body.String())
+	}
+}
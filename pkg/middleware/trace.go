@@ -20,6 +20,7 @@ package middleware
 
 import (
 	"Taurus/pkg/contextx"
+	"Taurus/pkg/realip"
 	"crypto/md5"
 	"log"
 	"net/http"
@@ -64,8 +65,9 @@ func TraceMiddleware(tracer trace.Tracer) func(http.Handler) http.Handler {
 			copy(traceID[:], hash[:])
 
 			rc := &contextx.RequestContext{ // 生成一个唯一的RequestContext，里面记录了traceID和请求开始时间
-				TraceID: traceID.String(), // 生成一个唯一的 traceID
-				AtTime:  time.Now(),       // 记录请求开始时间
+				TraceID:  traceID.String(),      // 生成一个唯一的 traceID
+				AtTime:   time.Now(),            // 记录请求开始时间
+				ClientIP: realip.FromRequest(r), // 保留 realip 中间件解析的客户端 IP
 			}
 			// 将自定义的上下文添加到请求中
 			ctx := contextx.WithRequestContext(r.Context(), rc)
@@ -86,6 +88,7 @@ func TraceMiddleware(tracer trace.Tracer) func(http.Handler) http.Handler {
 					attribute.String("http.url", r.URL.String()),
 					attribute.String("http.path", r.URL.Path),
 					attribute.String("http.trace_id", rc.TraceID),
+					attribute.String("http.client_ip", rc.ClientIP),
 					attribute.String("http.at_time", rc.AtTime.Format(time.RFC3339)),
 				),
 			)
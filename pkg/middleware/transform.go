@@ -0,0 +1,628 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/jwtx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 请求/响应转换: 兼容合作方的旧报文格式, 字段重命名/删除, JWT claims 注入请求头, 查询参数映射到请求体,
+// 响应包装成 httpx.Response, XML 和 JSON 互转. 只有需要转换的 JSON/XML 报文会被缓冲, 其他报文直接透传
+// ---------------------------------------------------------------------------------------------------------------------
+
+// BodyTransform holds the body steps shared by requests and responses, field paths are dotted, e.g. user.name.
+// If the body is a JSON array the steps are applied to every element.
+type BodyTransform struct {
+	Rename      map[string]string // old path -> new path
+	Remove      []string          // removed paths
+	XMLToJSON   bool              // convert an XML body to JSON, XML values become strings
+	JSONToXML   bool              // convert a JSON body to XML
+	XMLRoot     string            // root element of the converted XML, default request or response
+	MaxBodySize int64             // larger bodies are passed through untouched, default 4MB
+}
+
+// RequestTransform transforms the request before it is handled or forwarded
+type RequestTransform struct {
+	BodyTransform
+	QueryToBody  map[string]string // query parameter -> body path, the parameter is removed from the URL
+	ClaimHeaders map[string]string // header -> JWT claim, headers sent by the client are always removed
+}
+
+// ResponseTransform transforms the response before it is sent to the client
+type ResponseTransform struct {
+	BodyTransform
+	Envelope *Envelope // wrap the body into httpx.Response
+}
+
+// Envelope maps the fields of a legacy response to httpx.Response, empty paths use the HTTP status and the whole body
+type Envelope struct {
+	Code    string // path of the code, e.g. errcode
+	Message string // path of the message, e.g. errmsg
+	Data    string // path of the data, e.g. result
+}
+
+// TransformConfig holds the request and response transforms of a route
+type TransformConfig struct {
+	Request  RequestTransform
+	Response ResponseTransform
+}
+
+// TransformMiddleware transforms requests and responses by the config, upgraded connections are not transformed
+func TransformMiddleware(config TransformConfig) func(http.Handler) http.Handler {
+	for _, t := range []*BodyTransform{&config.Request.BodyTransform, &config.Response.BodyTransform} {
+		if t.MaxBodySize <= 0 {
+			t.MaxBodySize = 4 << 20
+		}
+	}
+	if config.Request.XMLRoot == "" {
+		config.Request.XMLRoot = "request"
+	}
+	if config.Response.XMLRoot == "" {
+		config.Response.XMLRoot = "response"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := config.Request.apply(r); err != nil {
+				setTransformToTrace(r, "request", err)
+				httpx.WriteError(w, r, httpx.Wrap(err, http.StatusBadRequest, "transform.invalid_body", err.Error()))
+				return
+			}
+			if !config.Response.active() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// compressed responses can not be transformed
+			r.Header.Del("Accept-Encoding")
+			tw := &transformWriter{ResponseWriter: w, transform: &config.Response, request: r}
+			next.ServeHTTP(tw, r)
+			tw.finish()
+		})
+	}
+}
+
+func (t *BodyTransform) active() bool {
+	return len(t.Rename) > 0 || len(t.Remove) > 0 || t.XMLToJSON || t.JSONToXML
+}
+
+func (t *ResponseTransform) active() bool {
+	return t.BodyTransform.active() || t.Envelope != nil
+}
+
+func (t *RequestTransform) apply(r *http.Request) error {
+	for header, claim := range t.ClaimHeaders {
+		r.Header.Del(header)
+		if value := requestClaim(r, claim); value != "" {
+			r.Header.Set(header, value)
+		}
+	}
+
+	if !t.BodyTransform.active() && len(t.QueryToBody) == 0 {
+		return nil
+	}
+	format := bodyFormat(r.Header.Get("Content-Type"))
+	if format == "" && (r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0) && len(t.QueryToBody) > 0 {
+		// query parameters are moved into a new JSON body
+		format = "json"
+		r.Header.Set("Content-Type", "application/json;charset=utf-8")
+	}
+	if format == "" {
+		return nil
+	}
+
+	data, complete, err := readLimited(r.Body, t.MaxBodySize)
+	if err != nil {
+		return err
+	}
+	if !complete {
+		// too large to transform, the handler reads what has been read and the rest
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		return nil
+	}
+
+	value, root, err := decodeBody(data, format)
+	if err != nil {
+		return fmt.Errorf("invalid %s body: %v", format, err)
+	}
+	if len(t.QueryToBody) > 0 {
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+		query := r.URL.Query()
+		if object, ok := value.(map[string]interface{}); ok {
+			for param, path := range t.QueryToBody {
+				if values, ok := query[param]; ok && len(values) > 0 {
+					setPath(object, path, values[0])
+					query.Del(param)
+				}
+			}
+			r.URL.RawQuery = query.Encode()
+		}
+	}
+	if value == nil {
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		return nil
+	}
+	value = t.fields(value)
+
+	body, contentType, err := t.encode(value, format, root)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	r.Header.Set("Content-Type", contentType)
+	return nil
+}
+
+// fields renames and removes the fields of an object or of every element of an array
+func (t *BodyTransform) fields(value interface{}) interface{} {
+	if len(t.Rename) == 0 && len(t.Remove) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// rename in a stable order, a path may be renamed to the old path of another one
+		paths := make([]string, 0, len(t.Rename))
+		for path := range t.Rename {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		renamed := make(map[string]interface{}, len(paths))
+		for _, path := range paths {
+			if field, ok := deletePath(v, path); ok {
+				renamed[t.Rename[path]] = field
+			}
+		}
+		for path, field := range renamed {
+			setPath(v, path, field)
+		}
+		for _, path := range t.Remove {
+			deletePath(v, path)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = t.fields(v[i])
+		}
+	}
+	return value
+}
+
+// encode encodes the value in the format of the body, or in the converted format.
+// root is the root element of the original XML body, which is kept instead of XMLRoot
+func (t *BodyTransform) encode(value interface{}, format, root string) ([]byte, string, error) {
+	switch {
+	case format == "xml" && t.XMLToJSON, format == "json" && !t.JSONToXML:
+		body, err := json.Marshal(value)
+		return body, "application/json;charset=utf-8", err
+	default:
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		if root == "" {
+			root = t.XMLRoot
+		}
+		encoder := xml.NewEncoder(&buf)
+		if err := encodeXML(encoder, root, value); err != nil {
+			return nil, "", err
+		}
+		if err := encoder.Flush(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "application/xml;charset=utf-8", nil
+	}
+}
+
+// transformWriter buffers JSON and XML responses to transform them, other responses are streamed
+type transformWriter struct {
+	http.ResponseWriter
+	transform   *ResponseTransform
+	request     *http.Request
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (w *transformWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	header := w.Header()
+	w.buffering = status != http.StatusNoContent && status != http.StatusNotModified &&
+		bodyFormat(header.Get("Content-Type")) != "" && header.Get("Content-Encoding") == ""
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *transformWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(p)
+	}
+	if int64(w.buf.Len()+len(p)) > w.transform.MaxBodySize {
+		// too large to transform, send what has been buffered and stream the rest
+		w.buffering = false
+		w.ResponseWriter.WriteHeader(w.status)
+		if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		w.buf.Reset()
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush flushes streamed responses, buffered responses are flushed by finish
+func (w *transformWriter) Flush() {
+	if !w.buffering {
+		if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+// Unwrap returns the original writer, used by http.ResponseController
+func (w *transformWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *transformWriter) finish() {
+	if !w.buffering {
+		return
+	}
+	body, contentType, err := w.transform.apply(w.buf.Bytes(), w.Header().Get("Content-Type"), w.status)
+	if err != nil {
+		// the handler answered an invalid body, send it as it is
+		log.Printf("Response of %s %s can not be transformed: %v\n", w.request.Method, w.request.URL.Path, err)
+		setTransformToTrace(w.request, "response", err)
+		body = w.buf.Bytes()
+	} else {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+func (t *ResponseTransform) apply(data []byte, contentType string, status int) ([]byte, string, error) {
+	format := bodyFormat(contentType)
+	value, root, err := decodeBody(data, format)
+	if err != nil {
+		return nil, "", err
+	}
+	if value == nil && t.Envelope == nil {
+		return data, contentType, nil
+	}
+	value = t.fields(value)
+	if t.Envelope != nil {
+		// the envelope is a new document, it uses XMLRoot
+		value, root = t.Envelope.wrap(value, status), ""
+	}
+	return t.encode(value, format, root)
+}
+
+// wrap builds the httpx.Response of a legacy body, a body which is already an httpx.Response is not wrapped again
+func (e *Envelope) wrap(value interface{}, status int) interface{} {
+	object, _ := value.(map[string]interface{})
+	if e.Code == "" && e.Message == "" && e.Data == "" && object != nil {
+		_, hasCode := object["code"]
+		_, hasMessage := object["message"]
+		if hasCode && hasMessage {
+			return value
+		}
+	}
+
+	response := httpx.Response{Code: status, Message: http.StatusText(status), Data: value}
+	if object == nil {
+		return response
+	}
+	if code, ok := getPath(object, e.Code); ok && e.Code != "" {
+		switch c := code.(type) {
+		case json.Number:
+			if n, err := c.Int64(); err == nil {
+				response.Code = int(n)
+			}
+		case string:
+			if n, err := strconv.Atoi(c); err == nil {
+				response.Code = n
+			}
+		}
+	}
+	if message, ok := getPath(object, e.Message); ok && e.Message != "" {
+		response.Message = fmt.Sprintf("%v", message)
+	}
+	if e.Data != "" {
+		response.Data, _ = getPath(object, e.Data)
+	}
+	return response
+}
+
+// requestClaim returns the claim of the JWT verified by JwtMiddleware, or of the bearer token verified by jwtx.Default
+func requestClaim(r *http.Request, claim string) string {
+	claims, ok := jwtx.GetClaims(r.Context())
+	if !ok {
+		token := jwtx.BearerToken(r.Header.Get("Authorization"))
+		if token == "" || jwtx.Default == nil {
+			return ""
+		}
+		var err error
+		if claims, err = jwtx.Default.Verify(r.Context(), token); err != nil {
+			return ""
+		}
+	}
+	return claims.Get(claim)
+}
+
+// bodyFormat returns json or xml by the content type, empty for other content types
+func bodyFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return "xml"
+	}
+	return ""
+}
+
+// readLimited reads up to limit bytes, complete is false if the body is larger
+func readLimited(body io.ReadCloser, limit int64) (data []byte, complete bool, err error) {
+	if body == nil || body == http.NoBody {
+		return nil, true, nil
+	}
+	data, err = io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return data, false, nil
+	}
+	body.Close()
+	return data, true, nil
+}
+
+// decodeBody decodes a JSON or XML body, root is the name of the root element of an XML body
+func decodeBody(data []byte, format string) (value interface{}, root string, err error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, "", nil
+	}
+	if format == "xml" {
+		return decodeXML(data)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	// numbers are kept as they are, float64 would lose large integers such as ids
+	if err := decoder.Decode(&value); err != nil {
+		return nil, "", err
+	}
+	return value, "", nil
+}
+
+// decodeXML converts an XML document to maps and returns the name of the root element,
+// repeated elements become arrays, attributes are prefixed with @
+func decodeXML(data []byte) (interface{}, string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeXMLElement(decoder, start)
+			return value, start.Name.Local, err
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		fields["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			switch existing := fields[t.Name.Local].(type) {
+			case nil:
+				fields[t.Name.Local] = child
+			case []interface{}:
+				fields[t.Name.Local] = append(existing, child)
+			default:
+				fields[t.Name.Local] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(fields) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				fields["#text"] = content
+			}
+			return fields, nil
+		}
+	}
+}
+
+// encodeXML writes the value as an element, keys are sorted so the output is stable. It reverses decodeXML:
+// @ keys become attributes, #text the text and arrays in objects repeated elements. Keys which are not valid XML names
+// are written as <entry key="..."> and other values are encoded by httpx.EncodeXML
+func encodeXML(e *xml.Encoder, name string, value interface{}) error {
+	start := httpx.XMLElement(name)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if isXMLAttr(k) {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k[1:]}, Value: fmt.Sprint(v[k])})
+			}
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			var err error
+			switch items, isList := v[k].([]interface{}); {
+			case isXMLAttr(k):
+			case k == "#text":
+				err = e.EncodeToken(xml.CharData(fmt.Sprint(v[k])))
+			case isList:
+				for _, item := range items {
+					if err = encodeXML(e, k, item); err != nil {
+						break
+					}
+				}
+			default:
+				err = encodeXML(e, k, v[k])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case []interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := encodeXML(e, "item", item); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case httpx.Response:
+		return encodeXML(e, name, map[string]interface{}{"code": v.Code, "message": v.Message, "data": v.Data})
+	case nil:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	default:
+		return httpx.EncodeXML(e, start, v)
+	}
+}
+
+func isXMLAttr(key string) bool {
+	return strings.HasPrefix(key, "@") && httpx.IsXMLName(key[1:])
+}
+
+// getPath returns the value of a dotted path
+func getPath(object map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		value, ok := object[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return value, true
+		}
+		if object, ok = value.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// setPath sets the value of a dotted path, missing objects are created
+func setPath(object map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			object[key] = child
+		}
+		object = child
+	}
+	object[keys[len(keys)-1]] = value
+}
+
+// deletePath removes a dotted path and returns its value
+func deletePath(object map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		object = child
+	}
+	value, ok := object[keys[len(keys)-1]]
+	delete(object, keys[len(keys)-1])
+	return value, ok
+}
+
+func setTransformToTrace(r *http.Request, side string, err error) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("transform."+side+".error", err.Error()))
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransformMiddleware(t *testing.T) {
+	var received string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header.Get("Content-Type") + " " + string(body) + " " + r.URL.RawQuery
+		switch r.URL.Path {
+		case "/legacy":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"errcode":1001,"errmsg":"bad","result":{"id":12345678901234567890,"secret":"x"}}`))
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	mw := TransformMiddleware(TransformConfig{
+		Request: RequestTransform{
+			BodyTransform: BodyTransform{Rename: map[string]string{"userName": "user.name"}, Remove: []string{"password"}, XMLToJSON: true},
+			QueryToBody:   map[string]string{"page": "paging.page"},
+			ClaimHeaders:  map[string]string{"X-User-ID": "uid"},
+		},
+		Response: ResponseTransform{
+			BodyTransform: BodyTransform{Remove: []string{"result.secret"}, JSONToXML: true},
+			Envelope:      &Envelope{Code: "errcode", Message: "errmsg", Data: "result"},
+		},
+	})(handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/legacy?page=2&size=10", strings.NewReader(`<req><userName>tom</userName><password>1</password></req>`))
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-User-ID", "spoofed")
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+
+	if want := `application/json;charset=utf-8 {"paging":{"page":"2"},"user":{"name":"tom"}} size=10`; received != want {
+		t.Errorf("unexpected request:\n got %s\nwant %s", received, want)
+	}
+	if req.Header.Get("X-User-ID") != "" {
+		t.Error("client supplied claim header should be removed")
+	}
+	if want := `<response><code>1001</code><data><id>12345678901234567890</id></data><message>bad</message></response>`; !strings.HasSuffix(rec.Body.String(), want) {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/xml;charset=utf-8" {
+		t.Errorf("unexpected content type: %s", rec.Header().Get("Content-Type"))
+	}
+
+	// other content types are streamed untouched
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Body.String() != "data: 1\n\n" || !rec.Flushed {
+		t.Errorf("stream should pass through: %q flushed=%v", rec.Body.String(), rec.Flushed)
+	}
+
+	// invalid request bodies are rejected
+	req = httptest.NewRequest(http.MethodPost, "/legacy", strings.NewReader(`{"userName":`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"code":400`) {
+		t.Errorf("invalid body should be rejected: %s", rec.Body.String())
+	}
+}
+
+func TestTransformXMLNames(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Write(body)
+	})
+	mw := TransformMiddleware(TransformConfig{
+		Request: RequestTransform{BodyTransform: BodyTransform{Remove: []string{"secret"}, JSONToXML: true}},
+	})(handler)
+
+	// the root element of an XML body is kept
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<order id="1"><secret>x</secret><sku>a</sku><sku>b &amp; c</sku><note lang="en">hi</note></order>`))
+	req.Header.Set("Content-Type", "application/xml")
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+	if want := `<order id="1"><note lang="en">hi</note><sku>a</sku><sku>b &amp; c</sku></order>`; !strings.HasSuffix(rec.Body.String(), want) {
+		t.Errorf("unexpected body: %s", rec.Body.String())
+	}
+
+	// keys which are not XML names are written as entries
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a b":1,"<x>":"y","@bad\"":"z","@ok":"1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+	want := `<request ok="1"><entry key="&lt;x&gt;">y</entry><entry key="@bad&#34;">z</entry><entry key="a b">1</entry></request>`
+	if !strings.HasSuffix(rec.Body.String(), want) {
+		t.Errorf("unexpected body:\n got %s\nwant %s", rec.Body.String(), want)
+	}
+}
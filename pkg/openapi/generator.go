@@ -0,0 +1,196 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package openapi
+
+import (
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/router"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// envelopeName is the component name of the httpx.Response envelope
+const envelopeName = "Response"
+
+// pathParamRegexp matches the wildcards of Go 1.22 patterns, e.g. {id}, {path...} and {$}
+var pathParamRegexp = regexp.MustCompile(`\{([^}]*)\}`)
+
+// Generate builds the OpenAPI document of the registered routes.
+// Routes without doc are listed with the generic response only, routes which are not registered are skipped.
+func Generate(info Info, routes []router.RouteInfo) *Document {
+	registry := newSchemaRegistry()
+	registry.schemas[envelopeName] = registry.structSchema(reflect.TypeOf(httpx.Response{}))
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	for _, route := range routes {
+		if route.Status != router.StatusRegistered {
+			continue
+		}
+		path, params := convertPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		for _, method := range documentMethods(route.Method) {
+			op := item.operation(method)
+			if op == nil || *op != nil {
+				continue
+			}
+			*op = buildOperation(registry, method, path, params, route.Doc)
+		}
+	}
+
+	doc.Components.Schemas = registry.schemas
+	return doc
+}
+
+// documentMethods returns the methods of the route in the document, routes accepting all methods are listed as GET and POST
+func documentMethods(method string) []string {
+	if method == router.MethodAny {
+		return []string{http.MethodGet, http.MethodPost}
+	}
+	return []string{method}
+}
+
+// convertPath converts a Go 1.22 pattern to an OpenAPI path and returns its path parameters
+// e.g. /files/{path...} -> /files/{path}, /{$} -> /
+func convertPath(pattern string) (string, []string) {
+	params := make([]string, 0)
+	path := pathParamRegexp.ReplaceAllStringFunc(pattern, func(match string) string {
+		name := strings.TrimSuffix(match[1:len(match)-1], "...")
+		if name == "$" {
+			return ""
+		}
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+func buildOperation(registry *schemaRegistry, method string, path string, params []string, routeDoc *router.RouteDoc) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Responses:   make(map[string]*Response),
+	}
+	for _, name := range params {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+
+	var response *Schema
+	if routeDoc != nil {
+		op.Summary = routeDoc.Summary
+		op.Description = routeDoc.Description
+		op.Tags = routeDoc.Tags
+		op.Deprecated = routeDoc.Deprecated
+
+		if routeDoc.Request != nil {
+			addRequest(registry, op, method, reflect.TypeOf(routeDoc.Request), params)
+			op.Responses["400"] = envelopeResponse("Invalid request", nil)
+		}
+		if routeDoc.Response != nil {
+			response = registry.schemaOf(reflect.TypeOf(routeDoc.Response))
+		}
+	}
+	op.Responses["200"] = envelopeResponse("Successful response", response)
+	return op
+}
+
+// addRequest adds the request struct as query parameters for methods without body, otherwise as JSON or form body
+func addRequest(registry *schemaRegistry, op *Operation, method string, t reflect.Type, params []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		pathParams := make(map[string]bool, len(params))
+		for _, name := range params {
+			pathParams[name] = true
+		}
+		for _, field := range structFields(t) {
+			if pathParams[field.Name] {
+				continue
+			}
+			schema := registry.schemaOf(field.Type)
+			required := applyValidateTag(schema, field.Type, field.Tag.Get("validate"))
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:        field.Name,
+				In:          "query",
+				Required:    required,
+				Description: field.Tag.Get("description"),
+				Schema:      schema,
+			})
+		}
+	default:
+		schema := registry.schemaOf(t)
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				"application/json":                  {Schema: schema},
+				"application/x-www-form-urlencoded": {Schema: schema},
+			},
+		}
+	}
+}
+
+// envelopeResponse returns the httpx.Response envelope, data is set to the schema if given
+func envelopeResponse(description string, data *Schema) *Response {
+	schema := &Schema{Ref: "#/components/schemas/" + envelopeName}
+	if data != nil {
+		schema = &Schema{AllOf: []*Schema{
+			schema,
+			{Type: "object", Properties: map[string]*Schema{"data": data}},
+		}}
+	}
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// operationID returns a camel case id, e.g. GET /v1/users/{id} -> getV1UsersId
+func operationID(method string, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package openapi
+
+import (
+	"Taurus/pkg/router"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type testRequest struct {
+	Name  string   `json:"name" validate:"required,min=2,max=10"`
+	Age   int      `json:"age" validate:"gte=18,lt=100"`
+	Email string   `json:"email" validate:"omitempty,email"`
+	Role  string   `json:"role" validate:"oneof=admin user"`
+	Tags  []string `json:"tags" validate:"max=3,dive,min=1"`
+}
+
+type testUser struct {
+	ID     int64     `json:"id"`
+	Parent *testUser `json:"parent,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(Info{Title: "test", Version: "v1"}, []router.RouteInfo{
+		{Method: http.MethodPost, Path: "/v1/users", Status: router.StatusRegistered, Doc: &router.RouteDoc{Request: &testRequest{}, Response: testUser{}}},
+		{Method: http.MethodGet, Path: "/v1/users/{id}", Status: router.StatusRegistered},
+		{Method: http.MethodGet, Path: "/v1/dup", Status: router.StatusDuplicate},
+	})
+
+	if _, ok := doc.Paths["/v1/dup"]; ok {
+		t.Error("duplicate route should be skipped")
+	}
+	get := doc.Paths["/v1/users/{id}"].Get
+	if get == nil || len(get.Parameters) != 1 || get.Parameters[0].In != "path" || get.OperationID != "getV1UsersId" {
+		t.Fatalf("unexpected get operation: %+v", get)
+	}
+
+	request := doc.Components.Schemas["testRequest"]
+	if request == nil {
+		t.Fatal("request schema is missing")
+	}
+	if len(request.Required) != 1 || request.Required[0] != "name" {
+		t.Errorf("unexpected required fields: %v", request.Required)
+	}
+	name := request.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 10 {
+		t.Errorf("unexpected name schema: %+v", name)
+	}
+	age := request.Properties["age"]
+	if *age.Minimum != 18 || *age.Maximum != 100 || !age.ExclusiveMaximum {
+		t.Errorf("unexpected age schema: %+v", age)
+	}
+	if request.Properties["email"].Format != "email" || len(request.Properties["role"].Enum) != 2 {
+		t.Errorf("unexpected email or role schema")
+	}
+	if *request.Properties["tags"].MaxItems != 3 {
+		t.Errorf("unexpected tags schema: %+v", request.Properties["tags"])
+	}
+
+	// recursive types are referenced
+	if doc.Components.Schemas["testUser"].Properties["parent"].Ref != "#/components/schemas/testUser" {
+		t.Error("recursive type should be referenced")
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package openapi
+
+import (
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/router"
+	"net/http"
+)
+
+// Handler serves the OpenAPI document of router.DefaultManager.
+// The document is generated on every request, so routes changed at runtime are always included.
+func Handler(info Info) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc := Generate(info, router.DefaultManager.Routes())
+		httpx.CustomJSONResponse(w, doc, nil)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package ratelimit 分布式限流: GCRA 算法, 配额保存在 Redis 中由 Lua 脚本原子地检查和消耗, 多个副本共享同一份配额.
+//
+// 一个 key 可以有多个层级, 例如每秒 10 次且每小时 1000 次, 只有所有层级都允许时才消耗配额.
+// Redis 不可用时退回到进程内的限流器, 此时每个副本独立计数
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"Taurus/pkg/logx"
+)
+
+// Tier is a quota of Limit requests per Window, Burst requests can be made at once, default Limit
+type Tier struct {
+	Name   string        // 层级名称, 用于 Redis key 和 RateLimit-Policy, 默认 <limit>/<window>
+	Limit  int           // 每个窗口允许的请求数
+	Window time.Duration // 窗口大小
+	Burst  int           // 突发请求数, 默认等于 Limit
+}
+
+// interval is the emission interval of the tier, a request is replenished every interval
+func (t Tier) interval() time.Duration {
+	return t.Window / time.Duration(t.Limit)
+}
+
+// tolerance is how far the theoretical arrival time may run ahead of now
+func (t Tier) tolerance() time.Duration {
+	return t.interval() * time.Duration(t.Burst)
+}
+
+// Quota is the state of a tier after a request
+type Quota struct {
+	Tier       Tier
+	Remaining  int           // 剩余可用的请求数
+	Reset      time.Duration // 配额完全恢复的时间
+	RetryAfter time.Duration // 大于 0 表示该层级超限, 需要等待的时间
+}
+
+// Store takes a request from the quotas of the key, the request is only taken when all the tiers allow it
+type Store interface {
+	Take(ctx context.Context, key string, tiers []Tier) ([]Quota, error)
+}
+
+// Result is the decision of a request, Quota is the tier reported in the response headers:
+// the exceeded tier with the longest wait, or the tier with the fewest remaining requests
+type Result struct {
+	Allowed bool
+	Quota
+}
+
+// Default is the store used by limiters created without a store, set by the application at startup, nil means a local store
+var Default Store
+
+// fallbackPause is how long a limiter uses its local store after the store failed
+const fallbackPause = 5 * time.Second
+
+// Limiter limits the requests of keys with the tiers
+type Limiter struct {
+	tiers    []Tier
+	store    Store
+	fallback *LocalStore
+
+	mu        sync.Mutex
+	failUntil time.Time
+}
+
+// NewLimiter creates a limiter with the tiers, a nil store uses Default
+func NewLimiter(store Store, tiers ...Tier) (*Limiter, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("rate limiter requires at least one tier")
+	}
+	normalized := make([]Tier, len(tiers))
+	names := make(map[string]bool, len(tiers))
+	for i, tier := range tiers {
+		if tier.Limit <= 0 || tier.Window <= 0 {
+			return nil, fmt.Errorf("rate limit tier %d: limit and window must be positive", i)
+		}
+		if tier.Window/time.Duration(tier.Limit) <= 0 {
+			return nil, fmt.Errorf("rate limit tier %d: window %s is too small for limit %d", i, tier.Window, tier.Limit)
+		}
+		if tier.Burst <= 0 {
+			tier.Burst = tier.Limit
+		}
+		if tier.Name == "" {
+			tier.Name = strconv.Itoa(tier.Limit) + "/" + tier.Window.String()
+		}
+		if names[tier.Name] {
+			return nil, fmt.Errorf("duplicate rate limit tier %s", tier.Name)
+		}
+		names[tier.Name] = true
+		normalized[i] = tier
+	}
+	return &Limiter{tiers: normalized, store: store, fallback: NewLocalStore()}, nil
+}
+
+// Tiers returns the tiers of the limiter
+func (l *Limiter) Tiers() []Tier {
+	return l.tiers
+}
+
+// Allow takes a request of the key. When the store fails the local store is used for a while, so an outage of Redis
+// never rejects or blocks requests, the limits are then enforced per process
+func (l *Limiter) Allow(ctx context.Context, key string) Result {
+	quotas, err := l.take(ctx, key)
+	if err != nil {
+		quotas, _ = l.fallback.Take(ctx, key, l.tiers)
+	}
+	return summarize(quotas)
+}
+
+func (l *Limiter) take(ctx context.Context, key string) ([]Quota, error) {
+	store := l.store
+	if store == nil {
+		store = Default
+	}
+	if store == nil {
+		return nil, errors.New("no store")
+	}
+	if local, ok := store.(*LocalStore); ok {
+		return local.Take(ctx, key, l.tiers)
+	}
+
+	l.mu.Lock()
+	failing := time.Now().Before(l.failUntil)
+	l.mu.Unlock()
+	if failing {
+		return nil, errors.New("store is unavailable")
+	}
+
+	quotas, err := store.Take(ctx, key, l.tiers)
+	if err != nil {
+		l.mu.Lock()
+		l.failUntil = time.Now().Add(fallbackPause)
+		l.mu.Unlock()
+		logx.Logf(logx.LEVEL_WARN, "default", "rate limit store failed, using the local limiter for %s: %v", fallbackPause, err)
+		return nil, err
+	}
+	return quotas, nil
+}
+
+func summarize(quotas []Quota) Result {
+	result := Result{Allowed: true}
+	for i, quota := range quotas {
+		if quota.RetryAfter > 0 {
+			if result.Allowed || quota.RetryAfter > result.RetryAfter {
+				result = Result{Quota: quota}
+			}
+			continue
+		}
+		if result.Allowed && (i == 0 || ratio(quota) < ratio(result.Quota)) {
+			result.Quota = quota
+		}
+	}
+	return result
+}
+
+func ratio(q Quota) float64 {
+	return float64(q.Remaining) / float64(q.Tier.Limit)
+}
+
+// Headers returns the RateLimit-* headers of the result, and Retry-After when the request is rejected
+func (r Result) Headers() map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(r.Tier.Limit),
+		"RateLimit-Remaining": strconv.Itoa(r.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(seconds(r.Reset)),
+		"RateLimit-Policy":    fmt.Sprintf("%d;w=%d", r.Tier.Limit, seconds(r.Tier.Window)),
+	}
+	if !r.Allowed {
+		headers["Retry-After"] = strconv.Itoa(seconds(r.RetryAfter))
+	}
+	return headers
+}
+
+// seconds rounds up, a client waiting the advertised time must not be rejected again
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// gcra takes a request with the theoretical arrival times of the tiers, the arrival times are updated only when allowed
+func gcra(now time.Time, tats []time.Time, tiers []Tier) ([]Quota, bool) {
+	allowed := true
+	next := make([]time.Time, len(tiers))
+	quotas := make([]Quota, len(tiers))
+	for i, tier := range tiers {
+		tat := tats[i]
+		if tat.Before(now) {
+			tat = now
+		}
+		next[i] = tat.Add(tier.interval())
+		quotas[i].Tier = tier
+		if wait := next[i].Sub(now) - tier.tolerance(); wait > 0 {
+			allowed = false
+			quotas[i].RetryAfter = wait
+		}
+		tats[i] = tat
+	}
+	for i, tier := range tiers {
+		if allowed {
+			tats[i] = next[i]
+		}
+		ahead := tats[i].Sub(now)
+		quotas[i].Reset = ahead
+		quotas[i].Remaining = max(int((tier.tolerance()-ahead)/tier.interval()), 0)
+	}
+	return quotas, allowed
+}
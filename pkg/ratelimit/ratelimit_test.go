@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestStore(now *time.Time) *LocalStore {
+	s := NewLocalStore()
+	s.now = func() time.Time { return *now }
+	s.lastSweep = *now
+	return s
+}
+
+func TestLimiterBurstAndReplenish(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter, err := NewLimiter(newTestStore(&now), Tier{Limit: 3, Window: 3 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow(ctx, "a")
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("expected allowed with %d remaining, got %+v", i, result)
+		}
+	}
+	result := limiter.Allow(ctx, "a")
+	if result.Allowed || result.RetryAfter != time.Second {
+		t.Fatalf("expected rejected with retry after 1s, got %+v", result)
+	}
+	if !limiter.Allow(ctx, "b").Allowed {
+		t.Fatal("keys must have separate quotas")
+	}
+
+	now = now.Add(time.Second)
+	if result := limiter.Allow(ctx, "a"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one request replenished, got %+v", result)
+	}
+}
+
+func TestLimiterTiersAreAtomic(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter, err := NewLimiter(newTestStore(&now),
+		Tier{Name: "second", Limit: 2, Window: time.Second},
+		Tier{Name: "minute", Limit: 3, Window: time.Minute},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "a")
+	limiter.Allow(ctx, "a")
+	result := limiter.Allow(ctx, "a")
+	if result.Allowed || result.Tier.Name != "second" {
+		t.Fatalf("expected rejected by the second tier, got %+v", result)
+	}
+
+	// 被拒绝的请求不消耗其他层级的配额
+	now = now.Add(time.Second)
+	result = limiter.Allow(ctx, "a")
+	if !result.Allowed || result.Tier.Name != "minute" || result.Remaining != 0 {
+		t.Fatalf("expected the last request of the minute tier, got %+v", result)
+	}
+	now = now.Add(time.Second)
+	if result := limiter.Allow(ctx, "a"); result.Allowed || result.Tier.Name != "minute" {
+		t.Fatalf("expected rejected by the minute tier, got %+v", result)
+	}
+}
+
+func TestLocalStoreEvictsReplenishedKeys(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := newTestStore(&now)
+	limiter, _ := NewLimiter(store, Tier{Limit: 10, Window: time.Second})
+	for _, key := range []string{"a", "b", "c"} {
+		limiter.Allow(context.Background(), key)
+	}
+	if store.Len() != 3 {
+		t.Fatalf("expected 3 keys, got %d", store.Len())
+	}
+	now = now.Add(sweepInterval)
+	limiter.Allow(context.Background(), "d")
+	if store.Len() != 1 {
+		t.Fatalf("expected replenished keys evicted, got %d", store.Len())
+	}
+}
+
+type failingStore struct {
+	calls int
+}
+
+func (s *failingStore) Take(context.Context, string, []Tier) ([]Quota, error) {
+	s.calls++
+	return nil, errors.New("connection refused")
+}
+
+func TestLimiterFallsBackToLocalStore(t *testing.T) {
+	store := &failingStore{}
+	limiter, _ := NewLimiter(store, Tier{Limit: 1, Window: time.Minute})
+
+	if !limiter.Allow(context.Background(), "a").Allowed {
+		t.Fatal("expected allowed by the local store")
+	}
+	if limiter.Allow(context.Background(), "a").Allowed {
+		t.Fatal("expected the local store to enforce the limit")
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected the failing store skipped for a while, got %d calls", store.calls)
+	}
+}
+
+func TestResultHeaders(t *testing.T) {
+	result := Result{Quota: Quota{
+		Tier:       Tier{Limit: 100, Window: time.Minute},
+		Remaining:  0,
+		Reset:      1500 * time.Millisecond,
+		RetryAfter: 200 * time.Millisecond,
+	}}
+	headers := result.Headers()
+	expected := map[string]string{
+		"RateLimit-Limit":     "100",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "2",
+		"RateLimit-Policy":    "100;w=60",
+		"Retry-After":         "1",
+	}
+	for name, value := range expected {
+		if headers[name] != value {
+			t.Errorf("%s: expected %s, got %s", name, value, headers[name])
+		}
+	}
+}
+
+func TestNewLimiterValidatesTiers(t *testing.T) {
+	if _, err := NewLimiter(nil); err == nil {
+		t.Error("expected error without tiers")
+	}
+	if _, err := NewLimiter(nil, Tier{Limit: 0, Window: time.Second}); err == nil {
+		t.Error("expected error with zero limit")
+	}
+	if _, err := NewLimiter(nil, Tier{Limit: 1, Window: time.Second}, Tier{Limit: 1, Window: time.Second}); err == nil {
+		t.Error("expected error with duplicate tiers")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"Taurus/pkg/redisx"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LocalStore keeps the quotas in the process, keys whose quotas are fully replenished are evicted
+type LocalStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepInterval is the minimum interval between two evictions of the local store
+const sweepInterval = time.Minute
+
+// NewLocalStore creates a local store
+func NewLocalStore() *LocalStore {
+	return &LocalStore{tats: make(map[string]time.Time), lastSweep: time.Now(), now: time.Now}
+}
+
+// Take implements Store
+func (s *LocalStore) Take(_ context.Context, key string, tiers []Tier) ([]Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, tat := range s.tats {
+			if !tat.After(now) {
+				delete(s.tats, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	tats := make([]time.Time, len(tiers))
+	for i, tier := range tiers {
+		tats[i] = s.tats[key+"\x00"+tier.Name]
+	}
+	quotas, allowed := gcra(now, tats, tiers)
+	if allowed {
+		for i, tier := range tiers {
+			s.tats[key+"\x00"+tier.Name] = tats[i]
+		}
+	}
+	return quotas, nil
+}
+
+// Len returns the number of keys of the store
+func (s *LocalStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tats)
+}
+
+// gcraScript is the GCRA of all the tiers of a key, KEYS are the tiers and ARGV are the interval and tolerance of each tier in microseconds.
+// The time of the Redis server is used so the replicas need no synchronized clocks, replicate_commands allows writes after TIME before Redis 5.
+// Returns allowed, then remaining, reset and retry after of each tier
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local allowed = 1
+local tats = {}
+for i = 1, #KEYS do
+  local interval = tonumber(ARGV[i * 2 - 1])
+  local tolerance = tonumber(ARGV[i * 2])
+  local tat = tonumber(redis.call('GET', KEYS[i]) or now)
+  if tat < now then
+    tat = now
+  end
+  tats[i] = tat
+  if tat + interval - now > tolerance then
+    allowed = 0
+  end
+end
+local result = {allowed}
+for i = 1, #KEYS do
+  local interval = tonumber(ARGV[i * 2 - 1])
+  local tolerance = tonumber(ARGV[i * 2])
+  local tat = tats[i]
+  local retry = tat + interval - now - tolerance
+  if allowed == 1 then
+    tat = tat + interval
+    redis.call('SET', KEYS[i], string.format('%d', tat), 'PX', math.ceil((tat - now) / 1000))
+  end
+  local remaining = math.floor((tolerance - (tat - now)) / interval)
+  if remaining < 0 then
+    remaining = 0
+  end
+  if retry < 0 then
+    retry = 0
+  end
+  table.insert(result, remaining)
+  table.insert(result, tat - now)
+  table.insert(result, retry)
+end
+return result
+`)
+
+// RedisStore keeps the quotas in Redis, shared by all the replicas
+type RedisStore struct {
+	client *redisx.RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a Redis store, the keys are <prefix>{<key>}:<tier>, default prefix ratelimit:
+func NewRedisStore(client *redisx.RedisClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "ratelimit:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, tiers []Tier) ([]Quota, error) {
+	// hash tag 保证同一个 key 的所有层级在集群模式下位于同一个 slot
+	keys := make([]string, len(tiers))
+	args := make([]interface{}, 0, len(tiers)*2)
+	for i, tier := range tiers {
+		keys[i] = s.prefix + "{" + key + "}:" + tier.Name
+		args = append(args, tier.interval().Microseconds(), tier.tolerance().Microseconds())
+	}
+	reply, err := s.client.RunScript(ctx, gcraScript, keys, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 1+len(tiers)*3 {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+	quotas := make([]Quota, len(tiers))
+	for i, tier := range tiers {
+		quotas[i] = Quota{
+			Tier:       tier,
+			Remaining:  int(integer(values[1+i*3])),
+			Reset:      time.Duration(integer(values[2+i*3])) * time.Microsecond,
+			RetryAfter: time.Duration(integer(values[3+i*3])) * time.Microsecond,
+		}
+	}
+	return quotas, nil
+}
+
+func integer(value interface{}) int64 {
+	v, _ := value.(int64)
+	return v
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+// Package realip 解析请求的真实客户端 IP.
+//
+// 只有直接连接的对端是受信任的代理时才读取 Forwarded、X-Forwarded-For、X-Real-IP 等请求头,
+// 并从右向左跳过受信任的代理, 第一个不受信任的地址即为客户端 IP, 客户端伪造的请求头不会生效
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"Taurus/pkg/contextx"
+)
+
+// DefaultHeaders are the headers read from trusted proxies, the first present header is used
+var DefaultHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// Resolver resolves the client IP with the trusted proxies
+type Resolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// Default is the resolver used by the middleware, it trusts no proxy until the application configures it
+var Default = &Resolver{headers: DefaultHeaders}
+
+// named groups of trusted proxies
+var namedPrefixes = map[string][]string{
+	"loopback": {"127.0.0.0/8", "::1/128"},
+	"private":  {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+}
+
+// NewResolver creates a resolver, trusted proxies are CIDRs, IPs, loopback or private.
+// headers are the headers read from trusted proxies in order of preference, default DefaultHeaders
+func NewResolver(trustedProxies []string, headers ...string) (*Resolver, error) {
+	r := &Resolver{headers: headers}
+	if len(r.headers) == 0 {
+		r.headers = DefaultHeaders
+	}
+	for _, proxy := range trustedProxies {
+		values, ok := namedPrefixes[proxy]
+		if !ok {
+			values = []string{proxy}
+		}
+		for _, value := range values {
+			prefix, err := parsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %s: %w", value, err)
+			}
+			r.trusted = append(r.trusted, prefix)
+		}
+	}
+	return r, nil
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// Trusted reports whether the ip is a trusted proxy
+func (r *Resolver) Trusted(ip string) bool {
+	addr, ok := parseAddr(ip)
+	return ok && r.trustedAddr(addr)
+}
+
+func (r *Resolver) trustedAddr(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client IP of the request. The headers are only read when the peer is a trusted proxy,
+// the hops are walked from right to left and the first untrusted address is the client
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote, ok := parseAddr(req.RemoteAddr)
+	if !ok {
+		return hostOf(req.RemoteAddr)
+	}
+	if !r.trustedAddr(remote) {
+		return remote.String()
+	}
+
+	for _, header := range r.headers {
+		values := req.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+		var hops []string
+		if http.CanonicalHeaderKey(header) == "Forwarded" {
+			hops = forwardedFor(values)
+		} else {
+			for _, value := range values {
+				hops = append(hops, strings.Split(value, ",")...)
+			}
+		}
+
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, ok := parseAddr(hops[i])
+			if !ok {
+				// unknown 或混淆的地址, 使用最近一个可信的地址
+				break
+			}
+			client = addr
+			if !r.trustedAddr(addr) {
+				break
+			}
+		}
+		return client.String()
+	}
+	return remote.String()
+}
+
+// forwardedFor returns the for parameters of the Forwarded header (RFC 7239), e.g. for=192.0.2.60;proto=https, for="[2001:db8::17]:4711"
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, param, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					hops = append(hops, param)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseAddr parses an IP with an optional port, brackets and quotes
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// FromRequest returns the client IP stored in the request context by the real ip middleware, or resolves it with Default
+func FromRequest(r *http.Request) string {
+	if rc, ok := contextx.GetRequestContext(r.Context()); ok && rc.ClientIP != "" {
+		return rc.ClientIP
+	}
+	return Default.ClientIP(r)
+}
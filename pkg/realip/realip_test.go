@@ -0,0 +1,68 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Taurus/pkg/contextx"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "203.0.113.7", "loopback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		remote   string
+		headers  map[string]string
+		expected string
+	}{
+		{"no proxy", "198.51.100.1:1234", nil, "198.51.100.1"},
+		{"spoofed by untrusted peer", "198.51.100.1:1234", map[string]string{"X-Forwarded-For": "127.0.0.1", "X-Real-IP": "10.0.0.1"}, "198.51.100.1"},
+		{"single proxy", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"spoofed hop before client", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 203.0.113.7"}, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.4"}, "10.0.0.3"},
+		{"invalid hop", "10.0.0.2:80", map[string]string{"X-Forwarded-For": "198.51.100.1, unknown, 10.0.0.3"}, "10.0.0.3"},
+		{"forwarded header", "10.0.0.2:80", map[string]string{"Forwarded": `for=198.51.100.1;proto=https, for="[2001:db8::17]:4711", for=10.0.0.3`}, "2001:db8::17"},
+		{"forwarded preferred", "10.0.0.2:80", map[string]string{"Forwarded": "for=198.51.100.2", "X-Forwarded-For": "198.51.100.1"}, "198.51.100.2"},
+		{"x-real-ip", "127.0.0.1:80", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"ipv6 peer", "[2001:db8::1]:443", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "2001:db8::1"},
+		{"ipv6 loopback proxy", "[::1]:443", map[string]string{"X-Forwarded-For": "2001:db8::2"}, "2001:db8::2"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = c.remote
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		if ip := resolver.ClientIP(req); ip != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, ip)
+		}
+	}
+}
+
+func TestDefaultTrustsNoProxy(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if ip := Default.ClientIP(req); ip != "127.0.0.1" {
+		t.Errorf("expected the peer address, got %s", ip)
+	}
+
+	req = req.WithContext(contextx.WithRequestContext(req.Context(), &contextx.RequestContext{ClientIP: "198.51.100.9"}))
+	if ip := FromRequest(req); ip != "198.51.100.9" {
+		t.Errorf("expected the ip of the request context, got %s", ip)
+	}
+}
+
+func TestNewResolverRejectsInvalidProxy(t *testing.T) {
+	if _, err := NewResolver([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+	if _, err := NewResolver([]string{"proxy.local"}); err == nil {
+		t.Error("expected error for host name")
+	}
+}
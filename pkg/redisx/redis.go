@@ -203,3 +203,13 @@ func (r *RedisClient) Unlock(ctx context.Context, lockKey string, currentProcess
 func (r *RedisClient) AddHook(hook redis.Hook) {
 	r.client.AddHook(hook)
 }
+
+// RunScript 执行 Lua 脚本, 优先使用 EVALSHA, 脚本未缓存时回退到 EVAL
+func (r *RedisClient) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.Run(ctx, r.client, keys, args...).Result()
+}
+
+// Del 删除键
+func (r *RedisClient) Del(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 声明式路由: 路由在配置文件中定义, handler 和 middleware 通过名称在注册表中查找
+// ---------------------------------------------------------------------------------------------------------------------
+
+// MiddlewareParams holds the parameters of a middleware from the configuration
+type MiddlewareParams map[string]interface{}
+
+// MiddlewareFactory creates a middleware with the parameters from the configuration
+type MiddlewareFactory func(params MiddlewareParams) (MiddlewareFunc, error)
+
+// MiddlewareConfig is a named middleware with parameters
+type MiddlewareConfig struct {
+	Name   string
+	Params MiddlewareParams
+}
+
+// RouteConfig is a route defined in the configuration
+type RouteConfig struct {
+	Path       string
+	Methods    []string
+	Handler    string    // name registered by RegisterHandler
+	Upstream   *Upstream // proxies the request to the upstream instead of Handler
+	Middleware []MiddlewareConfig
+}
+
+// GroupConfig is a route group defined in the configuration
+type GroupConfig struct {
+	Prefix     string
+	Middleware []MiddlewareConfig
+	Routes     []RouteConfig
+	Groups     []GroupConfig
+}
+
+var (
+	registryMu         sync.RWMutex
+	handlerRegistry    = make(map[string]http.Handler)
+	middlewareRegistry = make(map[string]MiddlewareFactory)
+)
+
+// RegisterHandler registers a handler by name for the routes in the configuration
+func RegisterHandler(name string, handler http.Handler) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := handlerRegistry[name]; ok {
+		log.Printf("Handler %s already registered", name)
+		return
+	}
+	handlerRegistry[name] = handler
+}
+
+// RegisterMiddleware registers a middleware factory by name for the routes in the configuration
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := middlewareRegistry[name]; ok {
+		log.Printf("Middleware %s already registered", name)
+		return
+	}
+	middlewareRegistry[name] = factory
+}
+
+// RegisterMiddlewareFunc registers a middleware without parameters by name
+func RegisterMiddlewareFunc(name string, middleware MiddlewareFunc) {
+	RegisterMiddleware(name, func(MiddlewareParams) (MiddlewareFunc, error) {
+		return middleware, nil
+	})
+}
+
+// GetHandler returns the handler registered by name
+func GetHandler(name string) (http.Handler, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	handler, ok := handlerRegistry[name]
+	return handler, ok
+}
+
+// GetMiddleware creates the middleware registered by name with the parameters
+func GetMiddleware(name string, params MiddlewareParams) (MiddlewareFunc, error) {
+	registryMu.RLock()
+	factory, ok := middlewareRegistry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown middleware %s", name)
+	}
+	if params == nil {
+		params = MiddlewareParams{}
+	}
+	return factory(params)
+}
+
+// LoadConfig sets the routes and groups from the configuration, it replaces the routes of a previous LoadConfig.
+// Names are resolved when the route table is built, so handlers can be registered after LoadConfig.
+func LoadConfig(routes []RouteConfig, groups []GroupConfig) {
+	DefaultManager.SetConfig(routes, groups)
+}
+
+// SetConfig sets the declarative routes and groups, the route table is rebuilt if it is already loaded
+func (m *RouterManager) SetConfig(routes []RouteConfig, groups []GroupConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configRoutes = routes
+	m.configGroups = groups
+	m.configResolved = false
+	m.reload()
+}
+
+// resolveConfig converts the declarative routes to routes and groups once, middleware factories are not called again on reload.
+// Routes which can not be resolved are skipped and reported by Check
+func (m *RouterManager) resolveConfig() {
+	if m.configResolved {
+		return
+	}
+	// before LoadRoutes the result is not kept, handlers may still be registered
+	m.configResolved = m.loaded
+	m.configErrors = nil
+	m.resolvedRoutes = m.resolvedRoutes[:0]
+	m.resolvedGroups = m.resolvedGroups[:0]
+
+	for _, rc := range m.configRoutes {
+		if route, ok := m.resolveRoute(rc, rc.Path); ok {
+			m.resolvedRoutes = append(m.resolvedRoutes, route)
+		}
+	}
+	for _, gc := range m.configGroups {
+		if group, ok := m.resolveGroup(gc, gc.Prefix); ok {
+			m.resolvedGroups = append(m.resolvedGroups, group)
+		}
+	}
+}
+
+// resolveGroup skips the whole group if one of its middleware can not be created, routes are never served without it
+func (m *RouterManager) resolveGroup(gc GroupConfig, prefix string) (RouteGroup, bool) {
+	middleware, ok := m.resolveMiddleware(gc.Middleware, prefix)
+	if !ok {
+		return RouteGroup{}, false
+	}
+	group := RouteGroup{Prefix: gc.Prefix, Middleware: middleware}
+	for _, rc := range gc.Routes {
+		if route, ok := m.resolveRoute(rc, prefix+rc.Path); ok {
+			group.Routes = append(group.Routes, route)
+		}
+	}
+	for _, sub := range gc.Groups {
+		if subGroup, ok := m.resolveGroup(sub, prefix+sub.Prefix); ok {
+			group.Groups = append(group.Groups, subGroup)
+		}
+	}
+	return group, true
+}
+
+func (m *RouterManager) resolveRoute(rc RouteConfig, fullPath string) (Router, bool) {
+	var handler http.Handler = rc.Upstream
+	if rc.Upstream == nil {
+		var ok bool
+		if handler, ok = GetHandler(rc.Handler); !ok {
+			m.configError(fullPath, fmt.Errorf("unknown handler %s", rc.Handler))
+			return Router{}, false
+		}
+	}
+	middleware, ok := m.resolveMiddleware(rc.Middleware, fullPath)
+	if !ok {
+		return Router{}, false
+	}
+	return Router{Path: rc.Path, Methods: rc.Methods, Handler: handler, Middleware: middleware}, true
+}
+
+func (m *RouterManager) resolveMiddleware(configs []MiddlewareConfig, path string) ([]MiddlewareFunc, bool) {
+	middleware := make([]MiddlewareFunc, 0, len(configs))
+	for _, mc := range configs {
+		mw, err := GetMiddleware(mc.Name, mc.Params)
+		if err != nil {
+			m.configError(path, err)
+			return nil, false
+		}
+		middleware = append(middleware, mw)
+	}
+	return middleware, true
+}
+
+func (m *RouterManager) configError(path string, err error) {
+	log.Printf("Warning: Route %s in configuration can not be loaded: %v\n", path, err)
+	m.configErrors = append(m.configErrors, fmt.Sprintf("%s (%v)", path, err))
+}
+
+// String returns the parameter as string
+func (p MiddlewareParams) String(key string, def string) string {
+	if v, ok := p[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return def
+}
+
+// Int returns the parameter as int, numbers and numeric strings are supported
+func (p MiddlewareParams) Int(key string, def int) int {
+	switch v := p[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// Bool returns the parameter as bool
+func (p MiddlewareParams) Bool(key string, def bool) bool {
+	switch v := p[key].(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// Duration returns the parameter as duration, e.g. "1s", numbers are seconds
+func (p MiddlewareParams) Duration(key string, def time.Duration) time.Duration {
+	switch v := p[key].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int, int64, float64:
+		return time.Duration(p.Int(key, 0)) * time.Second
+	}
+	return def
+}
+
+// Strings returns the parameter as string slice, a comma separated string is split
+func (p MiddlewareParams) Strings(key string) []string {
+	switch v := p[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values
+	case string:
+		values := make([]string, 0)
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Map returns the nested parameters, e.g. params of a transform step, nil if not found
+func (p MiddlewareParams) Map(key string) MiddlewareParams {
+	switch v := p[key].(type) {
+	case MiddlewareParams:
+		return v
+	case map[string]interface{}:
+		return v
+	case map[interface{}]interface{}:
+		params := make(MiddlewareParams, len(v))
+		for k, item := range v {
+			params[fmt.Sprintf("%v", k)] = item
+		}
+		return params
+	}
+	return nil
+}
+
+// Maps returns a list of nested parameters, e.g. the tiers of a rate limit, items which are not maps are skipped
+func (p MiddlewareParams) Maps(key string) []MiddlewareParams {
+	items, ok := p[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	list := make([]MiddlewareParams, 0, len(items))
+	for _, item := range items {
+		if nested := (MiddlewareParams{key: item}).Map(key); nested != nil {
+			list = append(list, nested)
+		}
+	}
+	return list
+}
+
+// StringMap returns the nested parameters as strings, e.g. header names to values
+func (p MiddlewareParams) StringMap(key string) map[string]string {
+	nested := p.Map(key)
+	if nested == nil {
+		return nil
+	}
+	values := make(map[string]string, len(nested))
+	for k := range nested {
+		values[k] = nested.String(k, "")
+	}
+	return values
+}
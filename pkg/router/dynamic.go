@@ -0,0 +1,200 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"strings"
+)
+
+// AddRoute adds a single route, the route table is rebuilt if it is already loaded
+func (m *RouterManager) AddRoute(route Router) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = append(m.routes, route)
+	m.reload()
+}
+
+// AddGroup adds a route group, the route table is rebuilt if it is already loaded
+func (m *RouterManager) AddGroup(group RouteGroup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routeGroups = append(m.routeGroups, group)
+	m.reload()
+}
+
+// ReplaceRoute replaces the handler, middleware and doc of the route with the same full path and methods.
+// The replaced route keeps its group, so the group middleware still applies.
+// If no such route exists, the overlapping methods are removed and the route is added as a single route.
+func (m *RouterManager) ReplaceRoute(route Router) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	methods := routeMethods(route)
+	if replaceRoutes(m.routes, "", route, methods) {
+		m.reload()
+		return
+	}
+	for i := range m.routeGroups {
+		if replaceInGroup(&m.routeGroups[i], "", route, methods) {
+			m.reload()
+			return
+		}
+	}
+
+	remove := methods
+	if methods[0] == MethodAny {
+		remove = nil
+	}
+	m.removeRoute(route.Path, remove)
+	m.routes = append(m.routes, route)
+	m.reload()
+}
+
+// RemoveRoute removes the given methods from the routes with the full path, all methods if none is given.
+// It returns the number of routes changed.
+func (m *RouterManager) RemoveRoute(path string, methods ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := m.removeRoute(path, methods)
+	if removed > 0 {
+		m.reload()
+	}
+	return removed
+}
+
+// SetDisabled replaces the set of disabled routes, e.g. "GET /v1/users/{id}" disables one method,
+// "/v1/users/{id}" disables all methods. Disabled routes are kept and can be enabled again.
+func (m *RouterManager) SetDisabled(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disabled = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if method, path, ok := strings.Cut(key, " "); ok {
+			key = strings.ToUpper(method) + " " + strings.TrimSpace(path)
+		}
+		if key != "" {
+			m.disabled[key] = true
+		}
+	}
+	m.reload()
+}
+
+// removeRoute must be called with m.mu held
+func (m *RouterManager) removeRoute(path string, methods []string) int {
+	var removed int
+	m.routes, removed = removeRoutes(m.routes, "", path, methods)
+	for i := range m.routeGroups {
+		removed += removeFromGroup(&m.routeGroups[i], "", path, methods)
+	}
+	return removed
+}
+
+func replaceRoutes(routes []Router, prefix string, route Router, methods []string) bool {
+	for i := range routes {
+		if prefix+routes[i].Path == route.Path && sameMethods(routeMethods(routes[i]), methods) {
+			routes[i].Handler = route.Handler
+			routes[i].Middleware = route.Middleware
+			routes[i].Doc = route.Doc
+			return true
+		}
+	}
+	return false
+}
+
+func replaceInGroup(group *RouteGroup, prefix string, route Router, methods []string) bool {
+	prefix += group.Prefix
+	if replaceRoutes(group.Routes, prefix, route, methods) {
+		return true
+	}
+	for i := range group.Groups {
+		if replaceInGroup(&group.Groups[i], prefix, route, methods) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeRoutes(routes []Router, prefix string, path string, methods []string) ([]Router, int) {
+	kept := make([]Router, 0, len(routes))
+	removed := 0
+	for _, route := range routes {
+		current := routeMethods(route)
+		if prefix+route.Path != path {
+			kept = append(kept, route)
+			continue
+		}
+		rest := subtractMethods(current, methods)
+		if len(rest) == len(current) {
+			kept = append(kept, route)
+			continue
+		}
+		removed++
+		if len(rest) > 0 {
+			route.Method = ""
+			route.Methods = rest
+			kept = append(kept, route)
+		}
+	}
+	return kept, removed
+}
+
+func removeFromGroup(group *RouteGroup, prefix string, path string, methods []string) int {
+	var removed int
+	prefix += group.Prefix
+	group.Routes, removed = removeRoutes(group.Routes, prefix, path, methods)
+	for i := range group.Groups {
+		removed += removeFromGroup(&group.Groups[i], prefix, path, methods)
+	}
+	return removed
+}
+
+// subtractMethods returns the methods which are not removed, nothing is left if remove is empty
+func subtractMethods(methods []string, remove []string) []string {
+	if len(remove) == 0 {
+		return nil
+	}
+	removeSet := make(map[string]bool, len(remove))
+	for _, method := range remove {
+		removeSet[strings.ToUpper(method)] = true
+	}
+	rest := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if !removeSet[method] {
+			rest = append(rest, method)
+		}
+	}
+	return rest
+}
+
+func sameMethods(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, method := range a {
+		set[method] = true
+	}
+	for _, method := range b {
+		if !set[method] {
+			return false
+		}
+	}
+	return true
+}
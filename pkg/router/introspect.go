@@ -0,0 +1,135 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"Taurus/pkg/httpx"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Status of a route after loading
+const (
+	StatusRegistered = "registered" // 注册成功
+	StatusDuplicate  = "duplicate"  // 相同的方法和路径已经注册, 被跳过
+	StatusConflict   = "conflict"   // 路径与其他路径冲突, ServeMux 拒绝注册
+	StatusDisabled   = "disabled"   // 被 SetDisabled 禁用
+)
+
+// RouteInfo describes a loaded route, one RouteInfo per method
+type RouteInfo struct {
+	Method     string    `json:"method"`     // HTTP method, MethodAny means all methods
+	Path       string    `json:"path"`       // full path, including the group prefix
+	Prefix     string    `json:"prefix"`     // combined prefix of the route groups
+	Middleware []string  `json:"middleware"` // middleware names in calling order
+	Handler    string    `json:"handler"`    // handler name
+	Status     string    `json:"status"`     // registered, duplicate, conflict or disabled
+	Doc        *RouteDoc `json:"-"`          // document of the route, may be nil
+}
+
+func newRouteInfo(method string, fullPath string, prefix string, route Router, middleware []MiddlewareFunc) RouteInfo {
+	names := make([]string, 0, len(middleware))
+	for _, mw := range middleware {
+		names = append(names, funcName(mw))
+	}
+	return RouteInfo{
+		Method:     method,
+		Path:       fullPath,
+		Prefix:     prefix,
+		Middleware: names,
+		Handler:    handlerName(route.Handler),
+		Status:     StatusRegistered,
+		Doc:        route.Doc,
+	}
+}
+
+// Routes returns every route with its group prefix, methods, middleware and status
+func (m *RouterManager) Routes() []RouteInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// before LoadRoutes the table is built only for introspection
+	if !m.loaded {
+		m.build()
+	}
+	infos := make([]RouteInfo, len(m.infos))
+	copy(infos, m.infos)
+	return infos
+}
+
+// Check returns an error if any route is duplicate, conflicts with another route or can not be loaded from the configuration, used by strict mode
+func (m *RouterManager) Check() error {
+	var problems []string
+	for _, info := range m.Routes() {
+		if info.Status == StatusDuplicate || info.Status == StatusConflict {
+			problems = append(problems, fmt.Sprintf("%s %s (%s)", info.Method, info.Path, info.Status))
+		}
+	}
+	m.mu.Lock()
+	problems = append(problems, m.configErrors...)
+	m.mu.Unlock()
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid routes: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// RoutesHandler serves the routes of DefaultManager as JSON, mount it on an admin route
+func RoutesHandler(w http.ResponseWriter, r *http.Request) {
+	httpx.SendResponse(w, http.StatusOK, DefaultManager.Routes(), nil)
+}
+
+// handlerName returns the name of the handler, the function name for http.HandlerFunc
+func handlerName(handler http.Handler) string {
+	if handler == nil {
+		return ""
+	}
+	if fn, ok := handler.(http.HandlerFunc); ok {
+		return funcName(fn)
+	}
+	if upstream, ok := handler.(*Upstream); ok {
+		return upstream.String()
+	}
+	return reflect.TypeOf(handler).String()
+}
+
+// funcName returns the short name of a function, e.g. middleware.TraceMiddleware
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	// Taurus/pkg/middleware.TraceMiddleware.func1 -> middleware.TraceMiddleware
+	name := path.Base(f.Name())
+	for {
+		idx := strings.LastIndex(name, ".func")
+		if idx <= 0 {
+			break
+		}
+		name = name[:idx]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"Taurus/pkg/httpx"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MethodAny marks a route which accepts all HTTP methods
+const MethodAny = "*"
+
+// methodHandler dispatches the requests of one path to the handler registered for the request method
+// 如果路径存在但方法不匹配，返回405并设置Allow头
+type methodHandler struct {
+	handlers   map[string]http.Handler     // method -> handler with middleware
+	any        http.Handler                // handler registered without method
+	middleware map[string][]MiddlewareFunc // method -> middleware, used by the automatic OPTIONS response of a CORS preflight
+	first      []MiddlewareFunc            // middleware of the first route, used by the automatic OPTIONS response otherwise
+}
+
+func newMethodHandler() *methodHandler {
+	return &methodHandler{handlers: make(map[string]http.Handler), middleware: make(map[string][]MiddlewareFunc)}
+}
+
+// add registers the handler with its middleware for the method
+func (h *methodHandler) add(method string, handler http.Handler, middleware []MiddlewareFunc) {
+	if h.empty() {
+		h.first = middleware
+	}
+	chained := ChainMiddleware(handler, middleware...)
+	if method == MethodAny {
+		h.any = chained
+		return
+	}
+	h.handlers[method] = chained
+	h.middleware[method] = middleware
+}
+
+// empty reports whether no handler is registered
+func (h *methodHandler) empty() bool {
+	return h.any == nil && len(h.handlers) == 0
+}
+
+func (h *methodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := h.handlers[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	// HEAD is served by GET handler, net/http discards the body
+	if r.Method == http.MethodHead {
+		if handler, ok := h.handlers[http.MethodGet]; ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	if h.any != nil {
+		h.any.ServeHTTP(w, r)
+		return
+	}
+
+	allow := strings.Join(h.allowed(), ", ")
+	// OPTIONS 请求依然经过路由的中间件(例如CORS预检)，最终返回Allow
+	if r.Method == http.MethodOptions {
+		ChainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		}), h.optionsMiddleware(r)...).ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", allow)
+	httpx.SendResponse(w, http.StatusMethodNotAllowed, "Method Not Allowed", nil)
+}
+
+// optionsMiddleware returns the middleware of the route of the preflighted method (Access-Control-Request-Method),
+// the middleware of the first route if the request is not a preflight or the method is not registered
+func (h *methodHandler) optionsMiddleware(r *http.Request) []MiddlewareFunc {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if middleware, ok := h.middleware[method]; ok {
+		return middleware
+	}
+	if method == http.MethodHead {
+		if middleware, ok := h.middleware[http.MethodGet]; ok {
+			return middleware
+		}
+	}
+	return h.first
+}
+
+// allowed returns the sorted methods which can be served by this path
+func (h *methodHandler) allowed() []string {
+	methods := make([]string, 0, len(h.handlers)+2)
+	for method := range h.handlers {
+		methods = append(methods, method)
+	}
+	if _, ok := h.handlers[http.MethodGet]; ok {
+		if _, ok := h.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	if _, ok := h.handlers[http.MethodOptions]; !ok {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// routeMethods returns the upper case methods of the route, MethodAny if none is set
+func routeMethods(route Router) []string {
+	methods := make([]string, 0, len(route.Methods)+1)
+	seen := make(map[string]bool)
+	for _, method := range append([]string{route.Method}, route.Methods...) {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || seen[method] {
+			continue
+		}
+		seen[method] = true
+		methods = append(methods, method)
+	}
+	if len(methods) == 0 || seen[MethodAny] {
+		return []string{MethodAny}
+	}
+	return methods
+}
+
+// Param returns the value of the path parameter, e.g. Param(r, "id") for /v1/users/{id}
+func Param(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
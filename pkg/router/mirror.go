@@ -0,0 +1,220 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"Taurus/pkg/logx"
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 流量镜像: 将部分请求异步复制到影子上游, 丢弃影子的响应, 记录主请求和影子请求的状态码和耗时差异
+// ---------------------------------------------------------------------------------------------------------------------
+
+// Mirror duplicates requests of an Upstream to a shadow upstream.
+// The body is captured while the primary request reads it and the shadow request is sent after the primary
+// response, so the primary path never waits for the shadow and never sees its errors.
+type Mirror struct {
+	Targets       []string      // static targets of the shadow upstream
+	Service       string        // consul service of the shadow upstream, used when Targets is empty
+	Tags          []string      // consul service tags of the shadow upstream
+	Percent       float64       // percentage of mirrored requests, 0 - 100
+	MaxBodySize   int64         // requests with a larger body are not mirrored, default 1MB
+	Timeout       time.Duration // timeout of the shadow request, default 5s
+	MaxConcurrent int           // shadow requests in flight, more are dropped, default 100
+	Logger        string        // name of the logx logger of the diffs, default "default"
+
+	once   sync.Once
+	shadow *Upstream
+	slots  chan struct{}
+}
+
+// mirrorKey is the context key of the mirrorRecord of a primary request
+type mirrorKey struct{}
+
+// mirrorRecord holds the primary result and the captured body of a mirrored request
+type mirrorRecord struct {
+	mu       sync.Mutex // the body may be read by the transport goroutine
+	start    time.Time
+	status   int
+	body     bytes.Buffer
+	overflow bool // the body is larger than MaxBodySize
+	eof      bool // the body has been read completely by the primary request
+}
+
+// captureBody copies what the primary request reads from the body, up to the limit
+type captureBody struct {
+	io.ReadCloser
+	record *mirrorRecord
+	limit  int64
+}
+
+func (c *captureBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.record.mu.Lock()
+	defer c.record.mu.Unlock()
+	if n > 0 && !c.record.overflow {
+		if int64(c.record.body.Len()+n) > c.limit {
+			c.record.overflow = true
+			c.record.body.Reset()
+		} else {
+			c.record.body.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		c.record.eof = true
+	}
+	return n, err
+}
+
+func (m *Mirror) init(u *Upstream) {
+	if m.MaxBodySize <= 0 {
+		m.MaxBodySize = 1 << 20
+	}
+	if m.Timeout <= 0 {
+		m.Timeout = 5 * time.Second
+	}
+	if m.MaxConcurrent <= 0 {
+		m.MaxConcurrent = 100
+	}
+	if m.Logger == "" {
+		m.Logger = "default"
+	}
+	m.slots = make(chan struct{}, m.MaxConcurrent)
+	// the shadow rewrites the request like the primary upstream, but never retries
+	m.shadow = &Upstream{
+		Targets:       m.Targets,
+		Service:       m.Service,
+		Tags:          m.Tags,
+		StripPrefix:   u.StripPrefix,
+		AddPrefix:     u.AddPrefix,
+		SetHeaders:    u.SetHeaders,
+		RemoveHeaders: u.RemoveHeaders,
+		DialTimeout:   u.DialTimeout,
+		Timeout:       m.Timeout,
+	}
+}
+
+// sample decides whether the request is mirrored, upgraded connections are never mirrored
+func (m *Mirror) sample(r *http.Request) bool {
+	return m.Percent > 0 && !isUpgrade(r) && rand.Float64()*100 < m.Percent
+}
+
+// start prepares the request for mirroring, the body is captured while the primary request reads it
+func (m *Mirror) start(u *Upstream, r *http.Request) (*http.Request, *mirrorRecord) {
+	m.once.Do(func() { m.init(u) })
+	record := &mirrorRecord{start: time.Now()}
+	if r.Body == nil || r.Body == http.NoBody {
+		record.eof = true
+	} else if r.ContentLength > m.MaxBodySize {
+		return r, nil
+	} else {
+		r.Body = &captureBody{ReadCloser: r.Body, record: record, limit: m.MaxBodySize}
+	}
+	return r.WithContext(context.WithValue(r.Context(), mirrorKey{}, record)), record
+}
+
+// finish sends the shadow request in the background after the primary response
+func (m *Mirror) finish(r *http.Request, record *mirrorRecord) {
+	latency := time.Since(record.start)
+	record.mu.Lock()
+	complete := !record.overflow && record.eof
+	body := bytes.Clone(record.body.Bytes())
+	status := record.status
+	record.mu.Unlock()
+	if !complete {
+		// the body is too large or has not been read completely, the shadow would receive a different request
+		return
+	}
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		logx.Logf(logx.LEVEL_WARN, m.Logger, "mirror %s %s is dropped, too many shadow requests in flight", r.Method, r.URL.Path)
+		return
+	}
+
+	// the shadow request must not be canceled with the primary request
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(r.Context()))
+	shadowReq := r.Clone(ctx)
+	shadowReq.Body = io.NopCloser(bytes.NewReader(body))
+	shadowReq.ContentLength = int64(len(body))
+	shadowReq.Header.Set("X-Mirror", "1")
+
+	go func() {
+		defer func() { <-m.slots }()
+		writer := &discardWriter{header: make(http.Header), status: http.StatusOK}
+		start := time.Now()
+		m.shadow.ServeHTTP(writer, shadowReq)
+		m.record(ctx, shadowReq, status, latency, writer.status, time.Since(start))
+	}()
+}
+
+// record logs the status and latency diff and records it as a span
+func (m *Mirror) record(ctx context.Context, r *http.Request, status int, latency time.Duration, shadowStatus int, shadowLatency time.Duration) {
+	// the span name is fixed, the route pattern keeps the cardinality low unlike the request path
+	_, span := otel.Tracer("router.mirror").Start(ctx, "mirror",
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", r.Pattern),
+			attribute.Int("mirror.primary.status", status),
+			attribute.Int64("mirror.primary.latency_ms", latency.Milliseconds()),
+			attribute.Int("mirror.shadow.status", shadowStatus),
+			attribute.Int64("mirror.shadow.latency_ms", shadowLatency.Milliseconds()),
+			attribute.Bool("mirror.status_diff", status != shadowStatus),
+			attribute.Int64("mirror.latency_diff_ms", (shadowLatency-latency).Milliseconds()),
+		),
+	)
+	span.End()
+
+	format := "mirror %s %s primary: %d %v, shadow: %d %v"
+	level := logx.LEVEL_INFO
+	if status != shadowStatus {
+		level = logx.LEVEL_WARN
+	}
+	logx.Logf(level, m.Logger, format, r.Method, r.URL.Path, status, latency, shadowStatus, shadowLatency)
+}
+
+// recordStatus records the status of the primary response, called by the upstream
+func recordStatus(r *http.Request, status int) {
+	if record, ok := r.Context().Value(mirrorKey{}).(*mirrorRecord); ok {
+		record.mu.Lock()
+		record.status = status
+		record.mu.Unlock()
+	}
+}
+
+// discardWriter discards the shadow response and keeps the status
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(status int)      { w.status = status }
@@ -19,29 +19,63 @@
 package router
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 )
 
 // Router holds the configuration for a route, including its handler and middleware
+// Path supports the Go 1.22 ServeMux wildcard syntax, e.g. /v1/users/{id} or /files/{path...}
 type Router struct {
 	Path       string
+	Method     string   // HTTP method, e.g. http.MethodGet, empty means all methods
+	Methods    []string // multiple HTTP methods, merged with Method
 	Handler    http.Handler
+	Upstream   *Upstream // optional, proxies the request to the upstream when Handler is nil
 	Middleware []MiddlewareFunc
+	Doc        *RouteDoc // optional, describes the route for the OpenAPI document
+}
+
+// RouteDoc describes the request and response of a route, used by the OpenAPI document generator
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{} // request struct with json and validate tags, e.g. &controller.ValidateRequest{}
+	Response    interface{} // data of the httpx.Response envelope
+	Deprecated  bool
 }
 
 // RouteGroup holds a group of routes with a common prefix and middleware
+// Sub groups inherit the prefix and middleware of their parent group
 type RouteGroup struct {
 	Prefix     string
 	Middleware []MiddlewareFunc
 	Routes     []Router
+	Groups     []RouteGroup
 }
 
 // RouterManager manages all routes and route groups
+// After LoadRoutes, every change rebuilds the route table and swaps it atomically, in-flight requests keep the old one
 type RouterManager struct {
+	mu              sync.Mutex
 	routes          []Router
 	routeGroups     []RouteGroup
-	registeredPaths map[string]bool // Track registered paths
+	registeredPaths map[string]bool // Track registered method + path
+	disabled        map[string]bool // Disabled method + path or path, see SetDisabled
+	loaded          bool            // Whether LoadRoutes has been called
+	infos           []RouteInfo     // Result of the last build, see Routes
+	mux             atomic.Pointer[http.ServeMux]
+
+	// Declarative routes from the configuration, see SetConfig
+	configRoutes   []RouteConfig
+	configGroups   []GroupConfig
+	configResolved bool
+	configErrors   []string
+	resolvedRoutes []Router
+	resolvedGroups []RouteGroup
 }
 
 // DefaultManager is the default instance of RouterManager
@@ -53,51 +87,172 @@ func NewRouterManager() *RouterManager {
 		routes:          []Router{},
 		routeGroups:     []RouteGroup{},
 		registeredPaths: make(map[string]bool),
+		disabled:        make(map[string]bool),
 	}
 }
 
-// AddRouter adds a single route to the manager
+// AddRouter adds a single route to the manager, it takes effect immediately if the server is running
 func AddRouter(route Router) {
-	DefaultManager.routes = append(DefaultManager.routes, route)
+	DefaultManager.AddRoute(route)
 }
 
-// AddRouterGroup adds a route group to the manager
+// AddRouterGroup adds a route group to the manager, it takes effect immediately if the server is running
 func AddRouterGroup(group RouteGroup) {
-	DefaultManager.routeGroups = append(DefaultManager.routeGroups, group)
+	DefaultManager.AddGroup(group)
 }
 
-// LoadRoutes loads all routes and route groups into a ServeMux
-func LoadRoutes() *http.ServeMux {
+// ReplaceRouter replaces the route with the same path and methods, or adds it if not found
+func ReplaceRouter(route Router) {
+	DefaultManager.ReplaceRoute(route)
+}
+
+// RemoveRouter removes the methods of the route with the full path, all methods if none is given
+func RemoveRouter(path string, methods ...string) int {
+	return DefaultManager.RemoveRoute(path, methods...)
+}
+
+// LoadRoutes loads all routes and route groups, the returned handler always serves the latest route table
+func LoadRoutes() http.Handler {
+	DefaultManager.Reload()
+	return DefaultManager
+}
+
+// ServeHTTP serves the request with the current route table
+func (m *RouterManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux := m.mux.Load()
+	if mux == nil {
+		http.NotFound(w, r)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
+
+// Reload rebuilds the route table and swaps it atomically
+func (m *RouterManager) Reload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaded = true
+	m.reload()
+}
+
+// reload must be called with m.mu held, it does nothing before LoadRoutes
+func (m *RouterManager) reload() {
+	if !m.loaded {
+		return
+	}
+	m.mux.Store(m.build())
+}
+
+// build flattens all routes and groups, then registers one method dispatcher per path on a new ServeMux.
+// The result of every route is recorded in m.infos, see Routes
+func (m *RouterManager) build() *http.ServeMux {
 	mux := http.NewServeMux()
+	m.registeredPaths = make(map[string]bool)
+	m.infos = make([]RouteInfo, 0, len(m.infos))
+	dispatchers := make(map[string]*methodHandler)
+	paths := make([]string, 0)
+	// index of the first registered info of each path, used to mark conflicting patterns
+	pathInfos := make(map[string][]int)
+
+	register := func(prefix string, route Router, middleware []MiddlewareFunc) {
+		fullPath := prefix + route.Path
+		if fullPath == "" {
+			log.Printf("Warning: Path of route is empty, skipping.\n")
+			return
+		}
+		if route.Handler == nil && route.Upstream != nil {
+			route.Handler = route.Upstream
+		}
+		dispatcher, ok := dispatchers[fullPath]
+		if !ok {
+			dispatcher = newMethodHandler()
+			dispatchers[fullPath] = dispatcher
+			paths = append(paths, fullPath)
+		}
+		methods := routeMethods(route)
+		for _, method := range methods {
+			key := method + " " + fullPath
+			info := newRouteInfo(method, fullPath, prefix, route, middleware)
+			switch {
+			case m.disabled[key] || m.disabled[fullPath]:
+				info.Status = StatusDisabled
+			case m.registeredPaths[key]:
+				log.Printf("Warning: Path %s is already registered, skipping.\n", key)
+				info.Status = StatusDuplicate
+			default:
+				dispatcher.add(method, route.Handler, middleware)
+				m.registeredPaths[key] = true
+				pathInfos[fullPath] = append(pathInfos[fullPath], len(m.infos))
+			}
+			m.infos = append(m.infos, info)
+		}
+	}
+
 	// Load individual routes
-	for _, route := range DefaultManager.routes {
-		if DefaultManager.registeredPaths[route.Path] {
-			log.Printf("Warning: Path %s is already registered, skipping.\n", route.Path)
+	for _, route := range m.routes {
+		register("", route, route.Middleware)
+	}
+
+	// Load route groups, sub groups are loaded recursively
+	for _, group := range m.routeGroups {
+		walkGroup(group, "", nil, register)
+	}
+
+	// Load routes from the configuration after the routes in code, so code wins on duplicates
+	m.resolveConfig()
+	for _, route := range m.resolvedRoutes {
+		register("", route, route.Middleware)
+	}
+	for _, group := range m.resolvedGroups {
+		walkGroup(group, "", nil, register)
+	}
+
+	for _, path := range paths {
+		// all methods of the path are disabled, let the mux answer 404
+		if dispatchers[path].empty() {
 			continue
 		}
-		handler := ChainMiddleware(route.Handler, route.Middleware...)
-		mux.Handle(route.Path, handler)
-		DefaultManager.registeredPaths[route.Path] = true
-	}
-	// Load route groups
-	for _, group := range DefaultManager.routeGroups {
-		for _, route := range group.Routes {
-			// Combine group and route middleware, maintaining order
-			allMiddleware := append(group.Middleware, route.Middleware...)
-			handler := ChainMiddleware(route.Handler, allMiddleware...)
-			// Ensure the path is correctly formatted
-			fullPath := group.Prefix + route.Path
-			if fullPath == "" || DefaultManager.registeredPaths[fullPath] {
-				log.Printf("Warning: Path %s is already registered, skipping.\n", fullPath)
-				continue // Skip if the full path is empty or already registered
+		if err := handle(mux, path, dispatchers[path]); err != nil {
+			for _, i := range pathInfos[path] {
+				m.infos[i].Status = StatusConflict
 			}
-			mux.Handle(fullPath, handler)
-			DefaultManager.registeredPaths[fullPath] = true
 		}
 	}
 	return mux
 }
 
+// walkGroup visits every route of the group and its sub groups with the combined prefix and middleware
+func walkGroup(group RouteGroup, prefix string, middleware []MiddlewareFunc, visit func(string, Router, []MiddlewareFunc)) {
+	prefix += group.Prefix
+	middleware = combineMiddleware(middleware, group.Middleware)
+	for _, route := range group.Routes {
+		// Combine group and route middleware, maintaining order
+		visit(prefix, route, combineMiddleware(middleware, route.Middleware))
+	}
+	for _, sub := range group.Groups {
+		walkGroup(sub, prefix, middleware, visit)
+	}
+}
+
+// combineMiddleware returns a new slice, so the parent's backing array is never shared between routes
+func combineMiddleware(parent []MiddlewareFunc, child []MiddlewareFunc) []MiddlewareFunc {
+	all := make([]MiddlewareFunc, 0, len(parent)+len(child))
+	all = append(all, parent...)
+	return append(all, child...)
+}
+
+// handle registers a handler on the mux, conflicting patterns are logged and returned instead of panicking
+func handle(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Warning: Path %s can not be registered: %v\n", pattern, r)
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
+}
+
 // MiddlewareFunc defines a function to process middleware
 // It takes an http.Handler and returns an http.Handler
 // This allows chaining of middleware functions
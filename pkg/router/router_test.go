@@ -0,0 +1,247 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMethodAndParams(t *testing.T) {
+	m := NewRouterManager()
+	m.routes = append(m.routes, Router{
+		Path:   "/v1/users/{id}",
+		Method: http.MethodGet,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("get " + Param(r, "id")))
+		}),
+	}, Router{
+		Path:    "/v1/users/{id}",
+		Methods: []string{http.MethodPut, "delete"},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Method + " " + Param(r, "id")))
+		}),
+	})
+	mux := m.build()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users/42", nil))
+	if rec.Body.String() != "get 42" {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1/users/7", nil))
+	if rec.Body.String() != "DELETE 7" {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/users/42", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("unexpected Allow header: %q", allow)
+	}
+}
+
+func TestNestedGroups(t *testing.T) {
+	var order []string
+	mark := func(name string) MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	m := NewRouterManager()
+	m.routeGroups = append(m.routeGroups, RouteGroup{
+		Prefix:     "/v1",
+		Middleware: []MiddlewareFunc{mark("v1")},
+		Groups: []RouteGroup{{
+			Prefix:     "/admin",
+			Middleware: []MiddlewareFunc{mark("admin")},
+			Routes: []Router{{
+				Path:       "/ping",
+				Method:     http.MethodGet,
+				Handler:    http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+				Middleware: []MiddlewareFunc{mark("route")},
+			}},
+		}},
+	})
+	mux := m.build()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/ping", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(order) != 3 || order[0] != "v1" || order[1] != "admin" || order[2] != "route" {
+		t.Errorf("unexpected middleware order: %v", order)
+	}
+
+	// OPTIONS without handler still runs the middleware chain
+	order = nil
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/v1/admin/ping", nil))
+	if rec.Code != http.StatusNoContent || len(order) != 3 {
+		t.Errorf("unexpected OPTIONS response: %d %v", rec.Code, order)
+	}
+}
+
+func TestPreflightMiddleware(t *testing.T) {
+	cors := func(origin string) MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	m := NewRouterManager()
+	m.routes = append(m.routes,
+		Router{Path: "/items", Method: http.MethodGet, Handler: noop, Middleware: []MiddlewareFunc{cors("*")}},
+		Router{Path: "/items", Method: http.MethodPost, Handler: noop, Middleware: []MiddlewareFunc{cors("https://admin.example.com")}},
+	)
+	mux := m.build()
+
+	// the preflight runs the middleware of the requested method, other OPTIONS requests the one of the first route
+	for method, origin := range map[string]string{"POST": "https://admin.example.com", "GET": "*", "HEAD": "*", "": "*", "PATCH": "*"} {
+		req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+		if method != "" {
+			req.Header.Set("Access-Control-Request-Method", method)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); rec.Code != http.StatusNoContent || got != origin {
+			t.Errorf("preflight %q: %d %q", method, rec.Code, got)
+		}
+	}
+}
+
+func TestRuntimeChanges(t *testing.T) {
+	text := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) })
+	}
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	m := NewRouterManager()
+	m.AddGroup(RouteGroup{Prefix: "/v1", Routes: []Router{{Path: "/a", Method: http.MethodGet, Handler: text("a1")}}})
+	m.Reload()
+
+	// routes added after loading take effect immediately
+	m.AddRoute(Router{Path: "/b", Handler: text("b1")})
+	if rec := get(m, "/b"); rec.Body.String() != "b1" {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+
+	m.ReplaceRoute(Router{Path: "/v1/a", Method: http.MethodGet, Handler: text("a2")})
+	if rec := get(m, "/v1/a"); rec.Body.String() != "a2" {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+
+	m.SetDisabled("GET /v1/a")
+	if rec := get(m, "/v1/a"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for disabled route, got %d", rec.Code)
+	}
+	m.SetDisabled()
+	if rec := get(m, "/v1/a"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for enabled route, got %d", rec.Code)
+	}
+
+	if n := m.RemoveRoute("/b"); n != 1 {
+		t.Errorf("expected 1 removed route, got %d", n)
+	}
+	if rec := get(m, "/b"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for removed route, got %d", rec.Code)
+	}
+}
+
+func TestRoutesIntrospection(t *testing.T) {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	m := NewRouterManager()
+	m.AddRoute(Router{Path: "/a", Method: http.MethodGet, Handler: noop, Middleware: []MiddlewareFunc{testMiddleware}})
+	m.AddGroup(RouteGroup{Prefix: "/", Routes: []Router{{Path: "a", Method: http.MethodGet, Handler: noop}}})
+
+	routes := m.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	if routes[0].Status != StatusRegistered || routes[1].Status != StatusDuplicate || routes[1].Prefix != "/" {
+		t.Errorf("unexpected routes: %+v", routes)
+	}
+	if len(routes[0].Middleware) != 1 || routes[0].Middleware[0] != "router.testMiddleware" {
+		t.Errorf("unexpected middleware names: %v", routes[0].Middleware)
+	}
+	if err := m.Check(); err == nil {
+		t.Error("expected duplicate route error")
+	}
+}
+
+func TestConfigRoutes(t *testing.T) {
+	RegisterHandler("test.echo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Tag") + Param(r, "id")))
+	}))
+	RegisterMiddleware("test.tag", func(params MiddlewareParams) (MiddlewareFunc, error) {
+		tag := params.String("tag", "none")
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Header.Set("X-Tag", r.Header.Get("X-Tag")+tag+":")
+				next.ServeHTTP(w, r)
+			})
+		}, nil
+	})
+
+	m := NewRouterManager()
+	m.SetConfig([]RouteConfig{
+		{Path: "/missing", Handler: "test.missing"},
+	}, []GroupConfig{
+		{
+			Prefix:     "/cfg",
+			Middleware: []MiddlewareConfig{{Name: "test.tag", Params: MiddlewareParams{"tag": "group"}}},
+			Routes: []RouteConfig{
+				{Path: "/items/{id}", Methods: []string{"get"}, Handler: "test.echo", Middleware: []MiddlewareConfig{{Name: "test.tag"}}},
+			},
+		},
+		{Prefix: "/bad", Middleware: []MiddlewareConfig{{Name: "test.unknown"}}, Routes: []RouteConfig{{Path: "/x", Handler: "test.echo"}}},
+	})
+	m.Reload()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cfg/items/1", nil))
+	if rec.Body.String() != "group:none:1" {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cfg/items/1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	// group with unknown middleware is skipped entirely
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bad/x", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if err := m.Check(); err == nil || !strings.Contains(err.Error(), "test.missing") || !strings.Contains(err.Error(), "test.unknown") {
+		t.Errorf("unexpected check error: %v", err)
+	}
+
+	params := MiddlewareParams{"n": "5", "d": "2s", "s": []interface{}{"a", "b"}, "b": true}
+	if params.Int("n", 0) != 5 || params.Duration("d", 0) != 2*time.Second || len(params.Strings("s")) != 2 || !params.Bool("b", false) {
+		t.Errorf("unexpected params conversion")
+	}
+}
+
+func testMiddleware(next http.Handler) http.Handler {
+	return next
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"Taurus/pkg/jwtx"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 流量拆分: 灰度发布时按规则或权重将请求分配到不同的上游池, 规则可以在运行时更新
+// ---------------------------------------------------------------------------------------------------------------------
+
+// SplitConfig holds the pools and rules of a traffic split, it can be decoded from the consul KV
+type SplitConfig struct {
+	Pools  []PoolConfig `json:"pools"`
+	Rules  []SplitRule  `json:"rules"`  // evaluated in order, the first matched rule selects its pool
+	Sticky string       `json:"sticky"` // key of sticky weighted assignment, e.g. header:X-User-ID, cookie:uid, query:uid, claim:uid
+}
+
+// PoolConfig is a group of upstream targets, static targets or consul instances with the tags
+type PoolConfig struct {
+	Name    string   `json:"name"`
+	Weight  int      `json:"weight"` // share of the requests not matched by any rule, 0 means only selected by rules
+	Targets []string `json:"targets"`
+	Service string   `json:"service"`
+	Scheme  string   `json:"scheme"`
+	Tags    []string `json:"tags"` // consul service tags, e.g. [v2]
+}
+
+// SplitRule selects the pool if all matchers match, a value of "*" only requires the key to be present
+type SplitRule struct {
+	Pool   string            `json:"pool"`
+	Header map[string]string `json:"header"`
+	Cookie map[string]string `json:"cookie"`
+	Query  map[string]string `json:"query"`
+	Claim  map[string]string `json:"claim"` // verified claims of the JWT in the Authorization: Bearer header
+}
+
+// TrafficSplit is a TargetResolver which selects a pool for every request, use it by Upstream.Split
+type TrafficSplit struct {
+	name  string
+	state atomic.Pointer[splitState]
+}
+
+type splitState struct {
+	pools     map[string]TargetResolver
+	weighted  []weightedPool
+	total     int
+	rules     []SplitRule
+	sticky    string
+	firstPool string
+}
+
+type weightedPool struct {
+	name   string
+	weight int
+}
+
+var (
+	splitsMu sync.Mutex
+	splits   = make(map[string]*TrafficSplit)
+)
+
+// GetTrafficSplit returns the traffic split with the name, it is created without pools if not found.
+// Requests fail with 502 until SetTrafficSplit configures it
+func GetTrafficSplit(name string) *TrafficSplit {
+	splitsMu.Lock()
+	defer splitsMu.Unlock()
+	split, ok := splits[name]
+	if !ok {
+		split = &TrafficSplit{name: name}
+		splits[name] = split
+	}
+	return split
+}
+
+// SetTrafficSplit creates or updates the traffic split with the name, in-flight requests keep the old rules
+func SetTrafficSplit(name string, config SplitConfig) error {
+	return GetTrafficSplit(name).Update(config)
+}
+
+// Update validates the config and replaces the pools and rules atomically
+func (s *TrafficSplit) Update(config SplitConfig) error {
+	if len(config.Pools) == 0 {
+		return fmt.Errorf("traffic split %s has no pools", s.name)
+	}
+	state := &splitState{
+		pools:     make(map[string]TargetResolver, len(config.Pools)),
+		rules:     config.Rules,
+		sticky:    config.Sticky,
+		firstPool: config.Pools[0].Name,
+	}
+	for _, pool := range config.Pools {
+		if _, ok := state.pools[pool.Name]; ok || pool.Name == "" {
+			return fmt.Errorf("traffic split %s: invalid or duplicate pool name %q", s.name, pool.Name)
+		}
+		var resolver TargetResolver
+		switch {
+		case len(pool.Targets) > 0:
+			var err error
+			if resolver, err = StaticResolver(pool.Targets...); err != nil {
+				return fmt.Errorf("traffic split %s pool %s: %v", s.name, pool.Name, err)
+			}
+		case pool.Service != "":
+			resolver = ConsulResolver(pool.Service, pool.Scheme, pool.Tags...)
+		default:
+			return fmt.Errorf("traffic split %s pool %s has no targets or service", s.name, pool.Name)
+		}
+		state.pools[pool.Name] = resolver
+		if pool.Weight > 0 {
+			state.weighted = append(state.weighted, weightedPool{name: pool.Name, weight: pool.Weight})
+			state.total += pool.Weight
+		}
+	}
+	for _, rule := range config.Rules {
+		if _, ok := state.pools[rule.Pool]; !ok {
+			return fmt.Errorf("traffic split %s: rule selects unknown pool %s", s.name, rule.Pool)
+		}
+		// a rule without matchers would match every request
+		if len(rule.Header)+len(rule.Cookie)+len(rule.Query)+len(rule.Claim) == 0 {
+			return fmt.Errorf("traffic split %s: rule of pool %s has no header, cookie, query or claim", s.name, rule.Pool)
+		}
+	}
+	s.state.Store(state)
+	return nil
+}
+
+// Resolve selects the pool by the rules, then by the weights, and resolves the target in the pool
+func (s *TrafficSplit) Resolve(r *http.Request) (*url.URL, error) {
+	state := s.state.Load()
+	if state == nil {
+		return nil, fmt.Errorf("traffic split %s is not configured", s.name)
+	}
+	pool := state.selectPool(r)
+	setSplitToTrace(r, s.name, pool)
+	return state.pools[pool].Resolve(r)
+}
+
+// String returns the name of the traffic split, used by route introspection
+func (s *TrafficSplit) String() string {
+	return "split:" + s.name
+}
+
+func (state *splitState) selectPool(r *http.Request) string {
+	var claims requestClaims
+	for _, rule := range state.rules {
+		if rule.match(r, &claims) {
+			return rule.Pool
+		}
+	}
+	if state.total == 0 {
+		return state.firstPool
+	}
+
+	var n int
+	if key := requestValue(r, state.sticky, &claims); key != "" {
+		// the same user always gets the same pool as long as the weights do not change
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		n = int(h.Sum32() % uint32(state.total))
+	} else {
+		n = rand.Intn(state.total)
+	}
+	for _, pool := range state.weighted {
+		if n < pool.weight {
+			return pool.name
+		}
+		n -= pool.weight
+	}
+	return state.firstPool
+}
+
+func (rule *SplitRule) match(r *http.Request, claims *requestClaims) bool {
+	matchers := []struct {
+		source string
+		values map[string]string
+	}{
+		{"header", rule.Header},
+		{"cookie", rule.Cookie},
+		{"query", rule.Query},
+		{"claim", rule.Claim},
+	}
+	for _, matcher := range matchers {
+		for key, expected := range matcher.values {
+			value := requestValue(r, matcher.source+":"+key, claims)
+			if value == "" || (expected != "*" && value != expected) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// requestValue returns the value of the source, e.g. header:X-User-ID, claims are parsed once per request
+func requestValue(r *http.Request, source string, claims *requestClaims) string {
+	kind, key, ok := strings.Cut(source, ":")
+	if !ok {
+		return ""
+	}
+	switch kind {
+	case "header":
+		return r.Header.Get(key)
+	case "cookie":
+		if cookie, err := r.Cookie(key); err == nil {
+			return cookie.Value
+		}
+	case "query":
+		return r.URL.Query().Get(key)
+	case "claim":
+		// numbers are kept as json.Number, e.g. uid 1000000 does not become 1e+06
+		if verified := claims.get(r); verified != nil {
+			return verified.Get(key)
+		}
+	}
+	return ""
+}
+
+// requestClaims are the verified claims of a request, loaded once when a rule or the sticky key needs them
+type requestClaims struct {
+	loaded bool
+	claims *jwtx.Claims
+}
+
+func (c *requestClaims) get(r *http.Request) *jwtx.Claims {
+	if !c.loaded {
+		c.loaded = true
+		c.claims = verifiedClaims(r)
+	}
+	return c.claims
+}
+
+// verifiedClaims returns the claims verified by the jwt middleware, routes without the middleware verify the bearer token
+// by jwtx.Default. Unverified tokens are never used, otherwise a forged token could select any pool
+func verifiedClaims(r *http.Request) *jwtx.Claims {
+	if claims, ok := jwtx.GetClaims(r.Context()); ok {
+		return claims
+	}
+	token := jwtx.BearerToken(r.Header.Get("Authorization"))
+	if token == "" || jwtx.Default == nil {
+		return nil
+	}
+	claims, err := jwtx.Default.Verify(r.Context(), token)
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
+func setSplitToTrace(r *http.Request, split string, pool string) {
+	if span := trace.SpanFromContext(r.Context()); span.SpanContext().IsValid() {
+		span.SetAttributes(attribute.String("upstream.split", split), attribute.String("upstream.pool", pool))
+	}
+}
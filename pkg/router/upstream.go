@@ -0,0 +1,582 @@
+// Copyright (c) 2025 Taurus Team. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Author: yelei
+// Email: 61647649@qq.com
+// Date: 2025-06-13
+
+package router
+
+import (
+	"Taurus/pkg/breaker"
+	"Taurus/pkg/consul"
+	"Taurus/pkg/httpx"
+	"Taurus/pkg/realip"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"golang.org/x/net/http2"
+)
+
+// ---------------------------------------------------------------------------------------------------------------------
+// 上游代理: 将请求转发到静态地址或 consul 发现的服务, 支持 HTTP/1.1, HTTP/2, WebSocket 和流式请求/响应
+// ---------------------------------------------------------------------------------------------------------------------
+
+// Upstream is a route handler which proxies the request to the upstream targets.
+// Use it as Router.Upstream, the middleware of the route runs before the request is forwarded.
+//
+//	router.AddRouter(router.Router{
+//		Path:       "/api/users/{path...}",
+//		Upstream:   &router.Upstream{Service: "user-service", StripPrefix: "/api/users", Retries: 2},
+//		Middleware: []router.MiddlewareFunc{middleware.ApiKeyAuthMiddleware},
+//	})
+type Upstream struct {
+	Targets  []string       // static targets, e.g. http://127.0.0.1:8081, use h2c:// for HTTP/2 without TLS
+	Service  string         // consul service name, used when Targets is empty
+	Scheme   string         // scheme of the consul service instances, default http
+	Tags     []string       // only consul service instances with all the tags are selected
+	Split    string         // name of the traffic split, see SetTrafficSplit, overrides Targets and Service
+	Resolver TargetResolver // custom resolver, overrides all above
+
+	StripPrefix  string // removed from the request path, e.g. /api/users/1 -> /1
+	AddPrefix    string // added to the request path after StripPrefix
+	PreserveHost bool   // forward the Host header of the client instead of the target host
+
+	SetHeaders            map[string]string // request headers set before forwarding
+	RemoveHeaders         []string          // request headers removed before forwarding
+	SetResponseHeaders    map[string]string // response headers set before responding
+	RemoveResponseHeaders []string          // response headers removed before responding
+
+	DialTimeout           time.Duration // timeout of connecting to the target, default 5s
+	ResponseHeaderTimeout time.Duration // timeout of waiting for the response header, 0 means no timeout
+	Timeout               time.Duration // timeout of the whole request, not applied to upgraded connections
+	Retries               int           // retries of idempotent requests on connection errors, 502, 503 and 504, bodies up to 1MB are buffered for replay
+	RetryBackoff          time.Duration // wait between retries, default 100ms
+	FlushInterval         time.Duration // flush interval of the response body, -1 flushes after every write
+
+	Breaker *breaker.Config // optional, fails fast with 503 while the whole upstream keeps failing
+	Outlier *breaker.Config // optional, ejects a failing target, requests go to the other targets until it recovers
+
+	Mirror    *Mirror           // optional, duplicates a percentage of the requests to a shadow upstream
+	Transport http.RoundTripper // custom transport, default is created from the timeouts
+
+	once     sync.Once
+	proxy    *httputil.ReverseProxy
+	resolver TargetResolver
+	breaker  *breaker.Breaker
+	outliers *breaker.Group
+	err      error
+}
+
+// TargetResolver returns the target of a request, it is called for every attempt
+type TargetResolver interface {
+	Resolve(r *http.Request) (*url.URL, error)
+}
+
+// maxEjectedSkips is the number of ejected targets skipped by an attempt before sending to an ejected target anyway
+const maxEjectedSkips = 5
+
+// maxRetryBodySize is the largest request body buffered so that a retry can send it again
+const maxRetryBodySize = 1 << 20
+
+// idempotentMethods can be retried safely
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodTrace:   true,
+}
+
+// ServeHTTP forwards the request to the upstream
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.once.Do(u.init)
+	if u.err != nil {
+		log.Printf("Upstream %s is invalid: %v\n", u, u.err)
+		gatewayError(w, http.StatusBadGateway)
+		return
+	}
+
+	var record *mirrorRecord
+	if u.Mirror != nil && u.Mirror.sample(r) {
+		r, record = u.Mirror.start(u, r)
+	}
+	if u.Retries > 0 && idempotentMethods[r.Method] && r.GetBody == nil && r.ContentLength > 0 && r.ContentLength <= maxRetryBodySize {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			gatewayError(w, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+
+	// upgraded connections, e.g. WebSocket, live longer than a request
+	if u.Timeout > 0 && !isUpgrade(r) {
+		ctx, cancel := context.WithTimeout(r.Context(), u.Timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+	u.proxy.ServeHTTP(w, r)
+
+	if record != nil {
+		u.Mirror.finish(r, record)
+	}
+}
+
+// String returns the targets of the upstream, used by route introspection
+func (u *Upstream) String() string {
+	switch {
+	case u.Resolver != nil:
+		return fmt.Sprintf("upstream(%T)", u.Resolver)
+	case u.Split != "":
+		return "upstream(split:" + u.Split + ")"
+	case len(u.Targets) > 0:
+		return "upstream(" + strings.Join(u.Targets, ", ") + ")"
+	default:
+		return "upstream(consul:" + u.Service + ")"
+	}
+}
+
+func (u *Upstream) init() {
+	u.resolver = u.Resolver
+	if u.resolver == nil {
+		switch {
+		case u.Split != "":
+			u.resolver = GetTrafficSplit(u.Split)
+		case len(u.Targets) > 0:
+			u.resolver, u.err = StaticResolver(u.Targets...)
+		case u.Service != "":
+			u.resolver = ConsulResolver(u.Service, u.Scheme, u.Tags...)
+		default:
+			u.err = errors.New("no targets or service")
+		}
+	}
+
+	if u.Breaker != nil {
+		u.breaker = breaker.New(u.String(), *u.Breaker)
+	}
+	if u.Outlier != nil {
+		u.outliers = breaker.NewGroup(u.String(), *u.Outlier)
+	}
+
+	transport := u.Transport
+	if transport == nil {
+		transport = newUpstreamTransport(u)
+	}
+	u.proxy = &httputil.ReverseProxy{
+		Rewrite:        u.rewrite,
+		Transport:      &retryTransport{upstream: u, base: transport},
+		FlushInterval:  u.FlushInterval,
+		ModifyResponse: u.modifyResponse,
+		ErrorHandler:   u.errorHandler,
+	}
+}
+
+// rewrite rewrites the path and headers, the target is set by retryTransport for every attempt
+func (u *Upstream) rewrite(pr *httputil.ProxyRequest) {
+	// keep the X-Forwarded-For set by trusted proxies, SetXForwarded appends the peer IP.
+	// The X-Forwarded-For of an untrusted client is dropped, so upstreams can not be spoofed either
+	if xff := pr.In.Header.Values("X-Forwarded-For"); len(xff) > 0 && realip.Default.Trusted(pr.In.RemoteAddr) {
+		pr.Out.Header["X-Forwarded-For"] = xff
+	}
+	pr.SetXForwarded()
+
+	// the escaped path is rewritten, so an encoded slash (/a%2Fb) is not forwarded as /a/b
+	path := pr.Out.URL.EscapedPath()
+	if u.StripPrefix != "" {
+		path = strings.TrimPrefix(path, escapePath(u.StripPrefix))
+	}
+	path = escapePath(u.AddPrefix) + path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	setEscapedPath(pr.Out.URL, path)
+
+	for _, name := range u.RemoveHeaders {
+		pr.Out.Header.Del(name)
+	}
+	for name, value := range u.SetHeaders {
+		pr.Out.Header.Set(name, value)
+	}
+
+	if u.PreserveHost {
+		pr.Out.Host = pr.In.Host
+	} else {
+		pr.Out.Host = ""
+	}
+}
+
+func (u *Upstream) modifyResponse(resp *http.Response) error {
+	recordStatus(resp.Request, resp.StatusCode)
+	for _, name := range u.RemoveResponseHeaders {
+		resp.Header.Del(name)
+	}
+	for name, value := range u.SetResponseHeaders {
+		resp.Header.Set(name, value)
+	}
+	return nil
+}
+
+func (u *Upstream) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the client has gone away, nothing to respond
+		return
+	case errors.Is(err, breaker.ErrOpen), errors.Is(err, breaker.ErrTooManyCalls):
+		recordStatus(r, http.StatusServiceUnavailable)
+		gatewayError(w, http.StatusServiceUnavailable)
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Upstream %s timeout: %s %s\n", u, r.Method, r.URL.Path)
+		recordStatus(r, http.StatusGatewayTimeout)
+		gatewayError(w, http.StatusGatewayTimeout)
+	default:
+		log.Printf("Upstream %s error: %s %s: %v\n", u, r.Method, r.URL.Path, err)
+		recordStatus(r, http.StatusBadGateway)
+		gatewayError(w, http.StatusBadGateway)
+	}
+}
+
+// gatewayError writes the httpx.Response envelope with the real HTTP status,
+// httpx.SendResponse answers 200 for 502 which hides upstream failures from clients and load balancers
+func gatewayError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(httpx.Response{Code: status, Message: http.StatusText(status)})
+}
+
+// retryTransport resolves the target of every attempt, so a retry may go to another instance
+type retryTransport struct {
+	upstream *Upstream
+	base     http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.upstream.breaker == nil {
+		return t.roundTrip(req)
+	}
+	done, err := t.upstream.breaker.Allow(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.roundTrip(req)
+	done(breaker.HTTPOutcome(resp, err))
+	return resp, err
+}
+
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	retries := 0
+	if idempotentMethods[req.Method] && (!hasBody || req.GetBody != nil) {
+		retries = t.upstream.Retries
+	}
+	backoff := t.upstream.RetryBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		body := req.Body
+		if attempt > 0 && hasBody {
+			// the body has been consumed by the previous attempt
+			var err error
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		target, done, err := t.resolve(req)
+		if err != nil {
+			return nil, err
+		}
+		out := withTarget(req, target)
+		out.Body = body
+		resp, err := t.base.RoundTrip(out)
+		if done != nil {
+			done(breaker.HTTPOutcome(resp, err))
+		}
+		retryable := err != nil || resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+		if !retryable || attempt >= retries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		log.Printf("Upstream %s attempt %d failed, retrying: %s %s\n", t.upstream, attempt+1, req.Method, req.URL.Path)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// resolve returns the target of an attempt, ejected targets are skipped if outlier ejection is enabled.
+// done reports the result of the attempt to the outlier breaker of the target, it is nil if nothing is recorded.
+func (t *retryTransport) resolve(req *http.Request) (*url.URL, func(breaker.Outcome), error) {
+	var target *url.URL
+	for i := 0; i < maxEjectedSkips; i++ {
+		var err error
+		if target, err = t.upstream.resolver.Resolve(req); err != nil {
+			return nil, nil, err
+		}
+		if t.upstream.outliers == nil {
+			return target, nil, nil
+		}
+		if done, err := t.upstream.outliers.Get(target.Host).Allow(req.Context()); err == nil {
+			return target, done, nil
+		}
+	}
+	// every resolved target is ejected, sending to one of them is better than failing the request
+	return target, nil, nil
+}
+
+// withTarget returns a copy of the request sent to the target, the target path is prepended to the request path
+func withTarget(req *http.Request, target *url.URL) *http.Request {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	if target.Path != "" && target.Path != "/" {
+		setEscapedPath(out.URL, strings.TrimSuffix(target.EscapedPath(), "/")+out.URL.EscapedPath())
+	}
+	if target.RawQuery != "" {
+		if out.URL.RawQuery == "" {
+			out.URL.RawQuery = target.RawQuery
+		} else {
+			out.URL.RawQuery = target.RawQuery + "&" + out.URL.RawQuery
+		}
+	}
+	return out
+}
+
+// escapePath escapes a configured path, e.g. a prefix, as it appears in a request URL
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// setEscapedPath sets Path and RawPath of u from the escaped path, RawPath keeps the original escaping
+func setEscapedPath(u *url.URL, escaped string) {
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return
+	}
+	u.Path = path
+	if escapePath(path) == escaped {
+		u.RawPath = ""
+	} else {
+		u.RawPath = escaped
+	}
+}
+
+// upstreamTransport uses HTTP/2 without TLS for h2c targets, otherwise HTTP/1.1 or HTTP/2 negotiated by TLS
+type upstreamTransport struct {
+	http1 *http.Transport
+	h2c   *http2.Transport
+}
+
+func newUpstreamTransport(u *Upstream) *upstreamTransport {
+	dialTimeout := u.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	return &upstreamTransport{
+		http1: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+			ResponseHeaderTimeout: u.ResponseHeaderTimeout,
+		},
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "h2c" {
+		req.URL.Scheme = "http"
+		return t.h2c.RoundTrip(req)
+	}
+	return t.http1.RoundTrip(req)
+}
+
+// staticResolver selects the targets in turn
+type staticResolver struct {
+	targets []*url.URL
+	next    atomic.Uint64
+}
+
+// StaticResolver returns a resolver which selects the targets in turn
+func StaticResolver(targets ...string) (TargetResolver, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no targets")
+	}
+	resolver := &staticResolver{}
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid target %q", target)
+		}
+		resolver.targets = append(resolver.targets, u)
+	}
+	return resolver, nil
+}
+
+func (s *staticResolver) Resolve(*http.Request) (*url.URL, error) {
+	n := s.next.Add(1) - 1
+	return s.targets[n%uint64(len(s.targets))], nil
+}
+
+// consulResolver selects the healthy instances of the service in turn, the instances are cached by serviceWatcher
+type consulResolver struct {
+	scheme  string
+	watcher *serviceWatcher
+	next    atomic.Uint64
+}
+
+// ConsulResolver returns a resolver which selects a healthy instance of the service, only instances registered with all the tags are selected.
+// The instances are cached and refreshed by consul blocking queries in the background, requests never query consul directly
+func ConsulResolver(service string, scheme string, tags ...string) TargetResolver {
+	if scheme == "" {
+		scheme = "http"
+	}
+	return &consulResolver{scheme: scheme, watcher: watchService(service, tags)}
+}
+
+func (c *consulResolver) Resolve(*http.Request) (*url.URL, error) {
+	entries, err := c.watcher.instances()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no healthy instance of service %s, tags: %v", c.watcher.service, c.watcher.tags)
+	}
+	entry := entries[(c.next.Add(1)-1)%uint64(len(entries))]
+	address := entry.Service.Address
+	if address == "" {
+		address = entry.Node.Address
+	}
+	return &url.URL{Scheme: c.scheme, Host: net.JoinHostPort(address, strconv.Itoa(entry.Service.Port))}, nil
+}
+
+// serviceWatcher 缓存服务的健康实例, 第一次使用时查询 consul, 之后通过阻塞查询在后台更新.
+// 相同服务和标签的 resolver 共享一个 watcher, 路由重新加载时不会创建新的查询
+type serviceWatcher struct {
+	service string
+	tags    []string
+
+	startMu sync.Mutex
+	started atomic.Bool
+	mu      sync.RWMutex
+	entries []*api.ServiceEntry
+}
+
+var serviceWatchers sync.Map // service + tags -> *serviceWatcher
+
+func watchService(service string, tags []string) *serviceWatcher {
+	key := service + "|" + strings.Join(tags, ",")
+	watcher, _ := serviceWatchers.LoadOrStore(key, &serviceWatcher{service: service, tags: tags})
+	return watcher.(*serviceWatcher)
+}
+
+// instances returns the cached instances, the first call queries consul and starts the background watch
+func (w *serviceWatcher) instances() ([]*api.ServiceEntry, error) {
+	if !w.started.Load() {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.entries, nil
+}
+
+func (w *serviceWatcher) start() error {
+	w.startMu.Lock()
+	defer w.startMu.Unlock()
+	if w.started.Load() {
+		return nil
+	}
+	client := consul.Client
+	if client == nil {
+		return errors.New("consul client is not initialized")
+	}
+	entries, meta, err := client.HealthyServices(w.service, w.tags, 0)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.entries = entries
+	w.mu.Unlock()
+	w.started.Store(true)
+	go w.watch(client, meta.LastIndex)
+	return nil
+}
+
+// watch 阻塞查询实例的变化, 查询失败时保留上一次的实例并退避重试
+func (w *serviceWatcher) watch(client *consul.ConsulClient, lastIndex uint64) {
+	retryCount := 0
+	for {
+		entries, meta, err := client.HealthyServices(w.service, w.tags, lastIndex)
+		if err != nil {
+			log.Printf("Failed to watch service %s: %v\n", w.service, err)
+			time.Sleep(min(time.Second<<min(retryCount, 6), time.Minute))
+			retryCount++
+			continue
+		}
+		retryCount = 0
+		if meta.LastIndex == lastIndex {
+			continue
+		}
+		// 索引变小说明 consul 重置了索引, 重新从头开始查询
+		if meta.LastIndex < lastIndex {
+			lastIndex = 0
+			continue
+		}
+		lastIndex = meta.LastIndex
+		w.mu.Lock()
+		w.entries = entries
+		w.mu.Unlock()
+	}
+}
+
+func isUpgrade(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" && strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
@@ -0,0 +1,390 @@
+package router
+
+import (
+	"Taurus/pkg/breaker"
+	"Taurus/pkg/consul"
+	"Taurus/pkg/jwtx"
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpstreamProxy(t *testing.T) {
+	var calls, bodyCalls atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/flaky-body" {
+			body, _ := io.ReadAll(r.Body)
+			if bodyCalls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(body)
+			return
+		}
+		w.Header().Set("X-Internal", "secret")
+		w.Write([]byte(r.Method + " " + r.URL.EscapedPath() + " " + r.Header.Get("X-Gateway") + " " + r.Header.Get("Cookie")))
+	}))
+	defer backend.Close()
+
+	m := NewRouterManager()
+	m.AddRoute(Router{
+		Path: "/api/{path...}",
+		Upstream: &Upstream{
+			Targets:               []string{backend.URL},
+			StripPrefix:           "/api",
+			AddPrefix:             "/v1",
+			SetHeaders:            map[string]string{"X-Gateway": "taurus"},
+			RemoveHeaders:         []string{"Cookie"},
+			RemoveResponseHeaders: []string{"X-Internal"},
+		},
+		Middleware: []MiddlewareFunc{func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Key") == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}},
+	})
+	m.AddRoute(Router{Path: "/flaky", Upstream: &Upstream{Targets: []string{backend.URL}, Retries: 1, RetryBackoff: time.Millisecond}})
+	m.AddRoute(Router{Path: "/flaky-body", Upstream: &Upstream{Targets: []string{backend.URL}, Retries: 1, RetryBackoff: time.Millisecond}})
+	m.AddRoute(Router{Path: "/down", Upstream: &Upstream{Targets: []string{"http://127.0.0.1:1"}, DialTimeout: 100 * time.Millisecond}})
+	m.Reload()
+
+	// middleware runs before forwarding
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("X-Key", "1")
+	req.Header.Set("Cookie", "a=b")
+	m.ServeHTTP(rec, req)
+	if body := strings.TrimSpace(rec.Body.String()); body != "GET /v1/users/1 taurus" {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if rec.Header().Get("X-Internal") != "" {
+		t.Error("response header should be removed")
+	}
+
+	// escaped characters of the path are forwarded unchanged
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/users/a%2Fb", nil)
+	req.Header.Set("X-Key", "1")
+	m.ServeHTTP(rec, req)
+	if body := strings.TrimSpace(rec.Body.String()); body != "GET /v1/users/a%2Fb taurus" {
+		t.Errorf("unexpected escaped path: %q", body)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flaky", nil))
+	if rec.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("expected retry, got %d after %d calls", rec.Code, calls.Load())
+	}
+
+	// idempotent requests with a body are retried with the same body
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/flaky-body", strings.NewReader("payload")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "payload" || bodyCalls.Load() != 2 {
+		t.Errorf("expected retry with body, got %d %q after %d calls", rec.Code, rec.Body.String(), bodyCalls.Load())
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/down", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", rec.Code)
+	}
+
+	if info := m.Routes()[0]; info.Handler != "upstream("+backend.URL+")" {
+		t.Errorf("unexpected handler name: %s", info.Handler)
+	}
+}
+
+func TestUpstreamUpgrade(t *testing.T) {
+	// a backend which switches protocols and echoes one line
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo " + line)
+		rw.Flush()
+	}))
+	defer backend.Close()
+
+	m := NewRouterManager()
+	m.AddRoute(Router{Path: "/ws", Upstream: &Upstream{Targets: []string{backend.URL}, Timeout: time.Second}})
+	m.Reload()
+	gateway := httptest.NewServer(m)
+	defer gateway.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected upgrade response: %v %v", resp, err)
+	}
+	io.WriteString(conn, "hello\n")
+	line, _ := reader.ReadString('\n')
+	if line != "echo hello\n" {
+		t.Errorf("unexpected echo: %q", line)
+	}
+}
+
+func TestTrafficSplit(t *testing.T) {
+	split := GetTrafficSplit("test")
+	err := split.Update(SplitConfig{
+		Pools: []PoolConfig{
+			{Name: "stable", Weight: 95, Targets: []string{"http://stable:80"}},
+			{Name: "canary", Weight: 5, Targets: []string{"http://canary:80"}},
+		},
+		Rules: []SplitRule{
+			{Pool: "canary", Header: map[string]string{"X-Canary": "1"}},
+			{Pool: "canary", Claim: map[string]string{"uid": "1000000"}},
+		},
+		Sticky: "header:X-User-ID",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := func(r *http.Request) string {
+		target, err := split.Resolve(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return target.Host
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Canary", "1")
+	if host(req) != "canary:80" {
+		t.Error("header rule should select canary")
+	}
+
+	// only verified claims are used, numeric claims are compared without exponent
+	old := jwtx.Default
+	defer func() { jwtx.Default = old }()
+	signer, _ := jwtx.GenerateSigningKey(jwtx.EdDSA)
+	jwtx.Default, _ = jwtx.NewService(signer, jwtx.Config{Issuer: "taurus"})
+	pair, err := jwtx.Default.IssuePair(context.Background(), "42", map[string]interface{}{"uid": 1000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	if host(req) != "canary:80" {
+		t.Error("claim rule should select canary")
+	}
+	for _, header := range []string{"Authorization", "token"} {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(header, "Bearer eyJhbGciOiJub25lIn0.eyJ1aWQiOjEwMDAwMDB9.")
+		// without a sticky key the weights are random, check the rule itself
+		var claims requestClaims
+		if (&SplitRule{Pool: "canary", Claim: map[string]string{"uid": "1000000"}}).match(req, &claims) {
+			t.Errorf("forged token in %s should not match claim rules", header)
+		}
+	}
+
+	// sticky assignment is stable and roughly follows the weights
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User-ID", strconv.Itoa(i))
+		first := host(req)
+		if host(req) != first {
+			t.Fatal("sticky assignment changed")
+		}
+		if first == "canary:80" {
+			canary++
+		}
+	}
+	if canary == 0 || canary > 150 {
+		t.Errorf("unexpected canary share: %d/1000", canary)
+	}
+
+	// rules are reloadable, invalid updates keep the old rules
+	if err := SetTrafficSplit("test", SplitConfig{Pools: []PoolConfig{{Name: "a"}}}); err == nil {
+		t.Error("pool without targets should be rejected")
+	}
+	if err := SetTrafficSplit("test", SplitConfig{Pools: []PoolConfig{{Name: "a", Targets: []string{"http://a:80"}}}, Rules: []SplitRule{{Pool: "a"}}}); err == nil {
+		t.Error("rule without matchers should be rejected")
+	}
+	SetTrafficSplit("test", SplitConfig{Pools: []PoolConfig{{Name: "v2", Targets: []string{"http://v2:80"}}}})
+	if host(httptest.NewRequest(http.MethodGet, "/", nil)) != "v2:80" {
+		t.Error("updated rules should be used")
+	}
+}
+
+func TestUpstreamMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	shadowBodies := make(chan string, 2)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		shadowBodies <- r.URL.Path + " " + string(body) + " " + r.Header.Get("X-Mirror")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	upstream := &Upstream{
+		Targets:     []string{primary.URL},
+		StripPrefix: "/api",
+		Mirror:      &Mirror{Targets: []string{shadow.URL}, Percent: 100, MaxBodySize: 8},
+	}
+
+	rec := httptest.NewRecorder()
+	upstream.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader("small")))
+	if rec.Body.String() != "primary" {
+		t.Fatalf("unexpected primary body: %q", rec.Body.String())
+	}
+	select {
+	case got := <-shadowBodies:
+		if got != "/orders small 1" {
+			t.Errorf("unexpected shadow request: %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shadow request not sent")
+	}
+
+	// bodies larger than the limit are not mirrored, the primary still gets the whole body
+	rec = httptest.NewRecorder()
+	upstream.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader("a larger body")))
+	if rec.Body.String() != "primary" {
+		t.Fatalf("unexpected primary body: %q", rec.Body.String())
+	}
+	select {
+	case got := <-shadowBodies:
+		t.Errorf("large body should not be mirrored: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestUpstreamBreaker(t *testing.T) {
+	var good, bad atomic.Int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		good.Add(1)
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bad.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	quiet := func(string, breaker.State, breaker.State) {}
+
+	// the failing instance is ejected after 2 failures, later requests only go to the healthy one
+	upstream := &Upstream{
+		Targets: []string{healthy.URL, failing.URL},
+		Outlier: &breaker.Config{ConsecutiveFailures: 2, OpenTimeout: time.Minute, OnStateChange: quiet},
+	}
+	for i := 0; i < 20; i++ {
+		upstream.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if bad.Load() != 2 || good.Load() != 18 {
+		t.Errorf("expected the failing instance to be ejected, healthy: %d, failing: %d", good.Load(), bad.Load())
+	}
+
+	// the whole upstream fails fast with 503 when its breaker is open
+	upstream = &Upstream{
+		Targets: []string{failing.URL},
+		Breaker: &breaker.Config{ConsecutiveFailures: 1, OpenTimeout: time.Minute, OnStateChange: quiet},
+	}
+	bad.Store(0)
+	for i := 0; i < 3; i++ {
+		upstream.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	rec := httptest.NewRecorder()
+	upstream.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || bad.Load() != 1 {
+		t.Errorf("expected 503 without calling the upstream, got %d after %d calls", rec.Code, bad.Load())
+	}
+}
+
+func TestConsulResolverCachesInstances(t *testing.T) {
+	var queries, blocking atomic.Int32
+	release := make(chan struct{})
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/catalog/services" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		queries.Add(1)
+		index := "1"
+		body := `[{"Node":{"Address":"10.0.0.1"},"Service":{"Address":"","Port":8080}}]`
+		if r.URL.Query().Get("index") != "" {
+			// 阻塞查询, 实例变化后返回新的实例
+			index = "2"
+			if blocking.Add(1) == 1 {
+				<-release
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+			body = `[{"Node":{"Address":"10.0.0.1"},"Service":{"Address":"10.0.0.2","Port":9090}}]`
+		}
+		w.Header().Set("X-Consul-Index", index)
+		w.Write([]byte(body))
+	}))
+	defer fake.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(fake.URL, "http://"))
+	portNumber, _ := strconv.Atoi(port)
+	old := consul.Client
+	if _, err := consul.NewConsulClient(&consul.ServerConfig{Address: host, Port: portNumber}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { consul.Client = old }()
+
+	resolver := ConsulResolver("orders", "", "v2")
+	for i := 0; i < 5; i++ {
+		target, err := resolver.Resolve(nil)
+		if err != nil || target.String() != "http://10.0.0.1:8080" {
+			t.Fatalf("unexpected target %v %v", target, err)
+		}
+	}
+	if n := queries.Load() - blocking.Load(); n != 1 {
+		t.Errorf("expected a single non-blocking query, got %d", n)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		target, _ := ConsulResolver("orders", "", "v2").Resolve(nil)
+		if target.String() == "http://10.0.0.2:9090" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the watch to refresh the instances, got %v", target)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -19,6 +19,7 @@
 package tcp
 
 import (
+	"Taurus/pkg/ratelimit"
 	"Taurus/pkg/tcp/errors"
 	"Taurus/pkg/tcp/protocol"
 	"context"
@@ -57,6 +58,15 @@ func WithRateLimit(messagesPerSecond int) ConnectionOption {
 	}
 }
 
+// WithSharedRateLimit 使用共享的限流器限制接收的消息速率, 同一个 key 的所有连接(包括其他副本上的连接)共享配额,
+// 接收时替代 WithRateLimit, 发送仍然使用本地的 rateLimiter, 服务端的推送不消耗客户端的配额
+func WithSharedRateLimit(limiter *ratelimit.Limiter, key string) ConnectionOption {
+	return func(c *Connection) {
+		c.sharedLimiter = limiter
+		c.sharedLimitKey = key
+	}
+}
+
 // WithMaxMessageSize 设置连接允许传输的最大消息大小
 func WithMaxMessageSize(bytes uint32) ConnectionOption {
 	return func(c *Connection) {
@@ -93,6 +103,9 @@ type Connection struct {
 
 	idleTimeout time.Duration // 连接最大空闲超时时间
 	rateLimiter *rate.Limiter // 消息频率限制器
+
+	sharedLimiter  *ratelimit.Limiter // 共享的接收消息频率限制器, 设置后接收时代替 rateLimiter
+	sharedLimitKey string             // 共享限流器的 key
 }
 
 var globalConnectionID uint64 // 生成唯一连接 ID 的全局计数器
@@ -172,7 +185,7 @@ func (c *Connection) readLoop() {
 			return
 		default:
 			// 判断是否达到发送速率限制
-			if !c.rateLimiter.Allow() {
+			if !c.allowInbound() {
 				// 如果达到发送速率限制，等待100ms
 				c.metrics.AddError()
 				c.handler.OnError(c, errors.ErrRateLimitExceeded)
@@ -319,6 +332,14 @@ func (c *Connection) readLoop() {
 	}
 }
 
+// allowInbound 判断接收的消息是否达到速率限制
+func (c *Connection) allowInbound() bool {
+	if c.sharedLimiter != nil {
+		return c.sharedLimiter.Allow(c.ctx, c.sharedLimitKey).Allowed
+	}
+	return c.rateLimiter.Allow()
+}
+
 // writeLoop 处理发送消息。
 func (c *Connection) writeLoop() {
 	defer func() {
@@ -84,6 +84,7 @@ var (
 	ErrConnectionClosed   = NewError(ErrorTypeConnection, "connection closed", nil)       // 连接已关闭
 	ErrConnectionIdle     = NewError(ErrorTypeConnection, "connection idle timeout", nil) // 连接空闲超时
 	ErrTooManyConnections = NewError(ErrorTypeConnection, "too many connections", nil)    // 连接数超过限制
+	ErrConnectionDenied   = NewError(ErrorTypeConnection, "connection denied", nil)       // 客户端 IP 被拒绝
 	ErrSendChannelFull    = NewError(ErrorTypeConnection, "send channel full", nil)       // 发送通道已满
 
 	// 协议相关错误 (2xx)
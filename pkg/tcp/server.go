@@ -19,6 +19,8 @@
 package tcp
 
 import (
+	"Taurus/pkg/ipfilter"
+	"Taurus/pkg/ratelimit"
 	"Taurus/pkg/tcp/errors"
 	"Taurus/pkg/tcp/protocol"
 	"context"
@@ -56,6 +58,9 @@ type Server struct {
 	maxMessageSize uint32        // 连接允许单条传输的消息大小, 默认1MB
 	idleTimeout    time.Duration // 连接最大空闲超时时间
 	rateLimiter    int           // 消息频率限制器, 每秒100条消息
+
+	sharedLimiter *ratelimit.Limiter // 共享的消息频率限制器, 按客户端 IP 限流
+	ipFilter      string             // 接受连接时检查的 ipfilter 列表名称, 为空时不检查
 }
 
 // ServerOption 定义了配置服务器的函数类型。
@@ -90,6 +95,20 @@ func WithConnectionRateLimiter(messagesPerSecond int) ServerOption {
 	}
 }
 
+// WithSharedRateLimiter 设置共享的接收消息频率限制器, 同一个客户端 IP 的所有连接共享配额, 设置后 WithConnectionRateLimiter 只限制发送
+func WithSharedRateLimiter(limiter *ratelimit.Limiter) ServerOption {
+	return func(s *Server) {
+		s.sharedLimiter = limiter
+	}
+}
+
+// WithIPFilter 接受连接时按命名的 ipfilter 列表检查客户端 IP, 被拒绝的连接在创建 Connection 之前关闭, 列表可以热更新
+func WithIPFilter(list string) ServerOption {
+	return func(s *Server) {
+		s.ipFilter = list
+	}
+}
+
 // WithProtocol 设置服务器的协议实现。
 // 协议定义了消息如何编码和解码。
 func WithProtocol(protocol protocol.Protocol) ServerOption {
@@ -246,12 +265,26 @@ func (s *Server) acceptLoop() {
 			retries = 0
 			delay = s.baseDelay
 
+			// 拒绝 IP 过滤列表中不允许的客户端
+			if s.ipFilter != "" && !ipfilter.Allowed(s.ipFilter, remoteHost(conn.RemoteAddr())) {
+				conn.Close()
+				<-s.connChan // 释放槽位
+				s.metrics.AddConnectionRefused()
+				s.handler.OnError(nil, errors.ErrConnectionDenied)
+				continue
+			}
+
 			// 创建并存储新连接
-			c := NewConnection(conn, s.protocol, s.handler,
+			opts := []ConnectionOption{
 				WithSendChanSize(s.bufferSize),
 				WithMaxMessageSize(s.maxMessageSize),
 				WithIdleTimeout(s.idleTimeout),
-				WithRateLimit(s.rateLimiter))
+				WithRateLimit(s.rateLimiter),
+			}
+			if s.sharedLimiter != nil {
+				opts = append(opts, WithSharedRateLimit(s.sharedLimiter, "tcp:ip="+remoteHost(conn.RemoteAddr())))
+			}
+			c := NewConnection(conn, s.protocol, s.handler, opts...)
 			s.conns.Store(c.ID(), c)
 			s.metrics.AddConnection()
 
@@ -335,3 +368,11 @@ func (s *Server) ConnectionCount() int32 {
 func (s *Server) GetMetrics() map[string]interface{} {
 	return s.metrics.GetStats()
 }
+
+// remoteHost returns the ip of the address without the port
+func remoteHost(addr net.Addr) string {
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
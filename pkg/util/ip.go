@@ -85,6 +85,8 @@ func IsIPAllowed(ip string, allowedHosts []string) bool {
 }
 
 // GetRemoteIP 获取远程IP
+//
+// Deprecated: 返回的请求头地址可以被客户端伪造, 使用 realip.FromRequest 获取经过可信代理解析的客户端 IP
 func GetRemoteIP(r *http.Request) []string {
 	var ips []string
 	// 提取X-Forwarded-For
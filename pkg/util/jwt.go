@@ -25,7 +25,10 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-var JwtSecret = []byte("61647649@qq.com") // 声明签名信息
+// JwtSecret 声明签名信息
+//
+// Deprecated: 硬编码的 HS256 密钥, 请使用 jwtx.Service 的非对称签名
+var JwtSecret = []byte("61647649@qq.com")
 
 // Claims 自定义有效载荷
 type Claims struct {
@@ -35,6 +38,8 @@ type Claims struct {
 }
 
 // GenerateToken 签发token（调用jwt-go库生成token）, 传入用户名和ID 返回一个token字符串. 用户登录成功签发token
+//
+// Deprecated: 请使用 jwtx.Service.IssuePair, JwtMiddleware 不再接受该 token
 func GenerateToken(uid uint, username string) (string, error) {
 	nowTime := time.Now()
 	expireTime := nowTime.Add(time.Hour * 24)
@@ -55,6 +60,8 @@ func GenerateToken(uid uint, username string) (string, error) {
 }
 
 // ParseToken token解码, 传入token字符串， 解析出Claims结构体. 用户请求携带token， 解析出Claims结构体
+//
+// Deprecated: 请使用 jwtx.Service.Verify
 func ParseToken(tokenString string) (*Claims, error) {
 	// 输入用户token字符串,自定义的Claims结构体对象,以及自定义函数来解析token字符串为jwt的Token结构体指针
 	//Keyfunc是匿名函数类型: type Keyfunc func(*Token) (interface{}, error)
@@ -81,6 +81,8 @@ func min(a, b int) int {
 
 // CompositeRateLimiter 组合限流器
 // 同时实现了基于 IP 的限流和全局限流，并支持请求排队
+//
+// Deprecated: 只在单个进程内计数且 IP 限流器不会淘汰, 使用 ratelimit.Limiter
 type CompositeRateLimiter struct {
 	ipLimiters     map[string]*RateLimiter // IP限流器映射表，每个IP一个限流器
 	globalLimiter  *RateLimiter            // 全局限流器，控制总体流量
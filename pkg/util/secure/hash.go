@@ -21,6 +21,7 @@ package secure
 import (
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 
@@ -62,6 +63,17 @@ func SHA1String(s string) string {
 	return SHA1([]byte(s))
 }
 
+// SHA256 计算字节流的SHA256值
+func SHA256(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// SHA256String 计算字符串的SHA256值
+func SHA256String(s string) string {
+	return SHA256([]byte(s))
+}
+
 // Byte2Hex 将字节流转换为十六进制字符串
 func Byte2Hex(b []byte) string {
 	return hex.EncodeToString(b)